@@ -0,0 +1,251 @@
+package eventt
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrForbidden returned when the request source is not in AccessControl.Allow.
+	ErrForbidden = errors.New("source not allowed")
+	// ErrRateLimited returned when the request source exceeded AccessControl.RateLimit.
+	ErrRateLimited = errors.New("rate limit exceeded")
+)
+
+// AccessControl optional source filtering and rate limiting for SonarrTriggers.Monitor,
+// rejected requests are answered with 403 or 429 and reported to SonarrTriggers.OnError.
+// requests received on a Unix socket come from the local host, their source is 127.0.0.1.
+type AccessControl struct {
+	// Allow list of CIDRs or IPs allowed to send events, e.g. "192.168.1.0/24" or "10.0.0.5",
+	// if empty all sources are allowed.
+	Allow []string
+	// TrustedProxies list of CIDRs or IPs of reverse proxies in front of eventt, the
+	// X-Forwarded-For header is used to find the client IP only when the request
+	// comes from one of them, otherwise the header is ignored.
+	TrustedProxies []string
+	// RateLimit maximum number of requests per second accepted from a single IP using
+	// a token bucket, zero disable rate limiting.
+	RateLimit float64
+	// Burst size of the token bucket for each IP, if zero it will be the ceil of RateLimit.
+	Burst int
+
+	once    sync.Once
+	err     error
+	allow   []netip.Prefix
+	proxies []netip.Prefix
+
+	mu      sync.Mutex
+	buckets map[netip.Addr]*bucket
+}
+
+// bucket token bucket for a single source.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// accessError carry the http status and Retry-After for a rejected request.
+type accessError struct {
+	status     int
+	retryAfter time.Duration
+	err        error
+}
+
+func (e *accessError) Error() string { return e.err.Error() }
+func (e *accessError) Unwrap() error { return e.err }
+
+// reject write the rejection response for the request.
+func (e *accessError) reject(w http.ResponseWriter) {
+	if e.retryAfter > 0 {
+		seconds := int(math.Ceil(e.retryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	}
+	w.WriteHeader(e.status)
+}
+
+// Validate check Allow and TrustedProxies are valid CIDRs or IPs, invalid entries
+// are otherwise reported to OnError on the first request.
+func (a *AccessControl) Validate() error {
+	if _, err := parsePrefixes(a.Allow); err != nil {
+		return fmt.Errorf("allow: %w", err)
+	}
+	if _, err := parsePrefixes(a.TrustedProxies); err != nil {
+		return fmt.Errorf("trusted proxies: %w", err)
+	}
+	return nil
+}
+
+// check validate the request against the allow list and rate limit.
+func (a *AccessControl) check(r *http.Request) *accessError {
+	a.once.Do(a.parse)
+	if a.err != nil {
+		return &accessError{
+			status: http.StatusInternalServerError,
+			err:    fmt.Errorf("invalid access control: %w", a.err),
+		}
+	}
+
+	ip, err := a.clientIP(r)
+	if err != nil {
+		return &accessError{
+			status: http.StatusForbidden,
+			err:    fmt.Errorf("%w: %v", ErrForbidden, err),
+		}
+	}
+
+	if len(a.allow) > 0 && !contains(a.allow, ip) {
+		return &accessError{
+			status: http.StatusForbidden,
+			err:    fmt.Errorf("%w: %s", ErrForbidden, ip),
+		}
+	}
+
+	if wait := a.take(ip, time.Now()); wait > 0 {
+		return &accessError{
+			status:     http.StatusTooManyRequests,
+			retryAfter: wait,
+			err:        fmt.Errorf("%w: %s", ErrRateLimited, ip),
+		}
+	}
+	return nil
+}
+
+func (a *AccessControl) parse() {
+	if a.allow, a.err = parsePrefixes(a.Allow); a.err != nil {
+		return
+	}
+	a.proxies, a.err = parsePrefixes(a.TrustedProxies)
+}
+
+// clientIP return the remote address of the request, or the last untrusted address
+// from X-Forwarded-For when the request is sent by a trusted proxy.
+func (a *AccessControl) clientIP(r *http.Request) (netip.Addr, error) {
+	ip, err := remoteIP(r)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	if len(a.proxies) == 0 || !contains(a.proxies, ip) {
+		return ip, nil
+	}
+
+	// walk the chain from the nearest hop, the first address not belonging
+	// to a trusted proxy is the client.
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		addr, err := netip.ParseAddr(hop)
+		if err != nil {
+			return netip.Addr{}, fmt.Errorf("invalid X-Forwarded-For address '%s'", hop)
+		}
+		ip = addr.Unmap()
+		if !contains(a.proxies, ip) {
+			break
+		}
+	}
+	return ip, nil
+}
+
+// remoteIP return the address of the peer which sent r, peers connected to a Unix
+// socket have no address, e.g. "@", they are local so loopback is returned.
+func remoteIP(r *http.Request) (netip.Addr, error) {
+	if local, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok && local.Network() == "unix" {
+		return netip.AddrFrom4([4]byte{127, 0, 0, 1}), nil
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("invalid remote address '%s'", r.RemoteAddr)
+	}
+	return ip.Unmap(), nil
+}
+
+// take consume a token for ip, it returns how long the caller should wait
+// if no token available.
+func (a *AccessControl) take(ip netip.Addr, now time.Time) time.Duration {
+	if a.RateLimit <= 0 {
+		return 0
+	}
+	burst := float64(a.Burst)
+	if burst <= 0 {
+		burst = math.Ceil(a.RateLimit)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.buckets == nil {
+		a.buckets = make(map[netip.Addr]*bucket)
+	}
+	b, ok := a.buckets[ip]
+	if !ok {
+		a.prune(now, burst)
+		b = &bucket{tokens: burst, last: now}
+		a.buckets[ip] = b
+	}
+
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*a.RateLimit)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / a.RateLimit * float64(time.Second))
+}
+
+// prune remove buckets which are already refilled, they are identical to a new one.
+func (a *AccessControl) prune(now time.Time, burst float64) {
+	if len(a.buckets) < 1024 {
+		return
+	}
+	for ip, b := range a.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*a.RateLimit >= burst {
+			delete(a.buckets, ip)
+		}
+	}
+}
+
+func parsePrefixes(list []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(list))
+	for _, s := range list {
+		s = strings.TrimSpace(s)
+		if !strings.Contains(s, "/") {
+			addr, err := netip.ParseAddr(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address '%s': %w", s, err)
+			}
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR '%s': %w", s, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+func contains(prefixes []netip.Prefix, ip netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
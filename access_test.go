@@ -0,0 +1,209 @@
+package eventt
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func accessRequest(remote string, forwarded ...string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.RemoteAddr = remote
+	for _, f := range forwarded {
+		r.Header.Add("X-Forwarded-For", f)
+	}
+	return r
+}
+
+func TestAccessControlAllow(t *testing.T) {
+	a := &AccessControl{Allow: []string{"192.168.1.0/24", "10.0.0.5", "fd00::/8"}}
+	tests := []struct {
+		remote string
+		status int
+	}{
+		{"192.168.1.20:4000", 0},
+		{"10.0.0.5:4000", 0},
+		{"[::ffff:10.0.0.5]:4000", 0},
+		{"[fd00::1]:4000", 0},
+		{"10.0.0.6:4000", http.StatusForbidden},
+		{"192.168.2.1:4000", http.StatusForbidden},
+		{"not-an-ip", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		err := a.check(accessRequest(tt.remote))
+		switch {
+		case tt.status == 0 && err != nil:
+			t.Errorf("%s: unexpected error %v", tt.remote, err)
+		case tt.status != 0 && (err == nil || err.status != tt.status || !errors.Is(err, ErrForbidden)):
+			t.Errorf("%s: got %v, want %d forbidden", tt.remote, err, tt.status)
+		}
+	}
+}
+
+func TestAccessControlForwardedFor(t *testing.T) {
+	a := &AccessControl{TrustedProxies: []string{"10.0.0.0/8"}}
+	a.once.Do(a.parse)
+	tests := []struct {
+		name      string
+		remote    string
+		forwarded []string
+		want      string
+		err       bool
+	}{
+		{"direct client", "192.168.1.2:1", nil, "192.168.1.2", false},
+		{"untrusted proxy ignored", "192.168.1.2:1", []string{"1.2.3.4"}, "192.168.1.2", false},
+		{"trusted proxy", "10.0.0.1:1", []string{"1.2.3.4"}, "1.2.3.4", false},
+		{"spoofed hop before client", "10.0.0.1:1", []string{"6.6.6.6, 1.2.3.4, 10.0.0.2"}, "1.2.3.4", false},
+		{"several headers", "10.0.0.1:1", []string{"6.6.6.6", "1.2.3.4"}, "1.2.3.4", false},
+		{"only proxies", "10.0.0.1:1", []string{"10.0.0.3"}, "10.0.0.3", false},
+		{"no header", "10.0.0.1:1", nil, "10.0.0.1", false},
+		{"invalid hop", "10.0.0.1:1", []string{"bogus"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ip, err := a.clientIP(accessRequest(tt.remote, tt.forwarded...))
+			if tt.err {
+				if err == nil {
+					t.Fatalf("got %v, want error", ip)
+				}
+				return
+			}
+			if err != nil || ip != netip.MustParseAddr(tt.want) {
+				t.Errorf("got %v, %v, want %s", ip, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccessControlRateLimit(t *testing.T) {
+	a := &AccessControl{RateLimit: 1, Burst: 2}
+	ip := netip.MustParseAddr("192.168.1.2")
+	other := netip.MustParseAddr("192.168.1.3")
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if wait := a.take(ip, now); wait != 0 {
+			t.Fatalf("request %d: wait %v within burst", i, wait)
+		}
+	}
+	if wait := a.take(ip, now); wait != time.Second {
+		t.Errorf("wait = %v, want 1s", wait)
+	}
+	if wait := a.take(other, now); wait != 0 {
+		t.Errorf("other source limited: wait %v", wait)
+	}
+	if wait := a.take(ip, now.Add(500*time.Millisecond)); wait != 500*time.Millisecond {
+		t.Errorf("wait = %v, want 500ms", wait)
+	}
+	if wait := a.take(ip, now.Add(1500*time.Millisecond)); wait != 0 {
+		t.Errorf("wait = %v after refill", wait)
+	}
+
+	w := httptest.NewRecorder()
+	err := a.check(accessRequest("192.168.1.2:1"))
+	if err == nil || err.status != http.StatusTooManyRequests || !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("got %v, want rate limited", err)
+	}
+	err.reject(w)
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("response %d, Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+	}
+}
+
+func TestAccessControlPrune(t *testing.T) {
+	a := &AccessControl{RateLimit: 1, Burst: 1}
+	now := time.Now()
+	for i := 0; i < 1024; i++ {
+		a.take(netip.AddrFrom4([4]byte{10, 0, byte(i >> 8), byte(i)}), now)
+	}
+	busy := netip.MustParseAddr("192.168.1.2")
+	a.take(busy, now.Add(2*time.Second))
+
+	// a new source once the buckets are refilled remove them, except the busy one.
+	a.take(netip.MustParseAddr("192.168.1.3"), now.Add(2*time.Second))
+	if len(a.buckets) != 2 {
+		t.Errorf("got %d buckets after prune, want 2", len(a.buckets))
+	}
+	if _, ok := a.buckets[busy]; !ok {
+		t.Error("bucket with consumed tokens was pruned")
+	}
+}
+
+func TestAccessControlInvalid(t *testing.T) {
+	a := &AccessControl{Allow: []string{"10.0.0.0/33"}}
+	if err := a.Validate(); err == nil {
+		t.Error("invalid CIDR accepted")
+	}
+	if err := (&AccessControl{TrustedProxies: []string{"proxy"}}).Validate(); err == nil {
+		t.Error("invalid proxy accepted")
+	}
+	if err := a.check(accessRequest("10.0.0.1:1")); err == nil || err.status != http.StatusInternalServerError {
+		t.Errorf("got %v, want internal error", err)
+	}
+}
+
+func TestAccessControlUnixSocket(t *testing.T) {
+	// socket paths are limited to about 100 bytes, t.TempDir can be longer.
+	dir, err := os.MkdirTemp("", "eventt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "eventt.sock")
+	l, err := listenUnix(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	s := &SonarrTriggers{
+		Access: &AccessControl{Allow: []string{"127.0.0.1"}, TrustedProxies: []string{"127.0.0.1"}},
+		OnTest: func(e TestEvent) { got = append(got, "test") },
+		OnError: func(payload []byte, err error) int {
+			got = append(got, err.Error())
+			return 0
+		},
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(s.Monitor)}
+	go srv.Serve(l)
+	defer srv.Close()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	post := func(forwarded string) int {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, "http://eventt/", strings.NewReader(`{"eventType":"Test"}`))
+		req.Header.Set("Content-Type", "application/json")
+		if forwarded != "" {
+			req.Header.Set("X-Forwarded-For", forwarded)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if status := post(""); status != http.StatusOK {
+		t.Errorf("local peer got %d, errors %v", status, got)
+	}
+	// the proxy in front of the socket is trusted, the forwarded client is checked.
+	if status := post("192.168.1.2"); status != http.StatusForbidden {
+		t.Errorf("forwarded client got %d, want 403", status)
+	}
+	if len(got) != 2 || got[0] != "test" || !strings.Contains(got[1], "192.168.1.2") {
+		t.Errorf("got %v", got)
+	}
+}
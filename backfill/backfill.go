@@ -0,0 +1,487 @@
+// Package backfill recover Sonarr events missed while the webhook listener was down,
+// by reading Sonarr activity history and dispatching the records as typed events.
+package backfill
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/k-x7/eventt"
+	"github.com/k-x7/eventt/sonarr"
+)
+
+// Default settings used when Backfill fields are not set.
+const (
+	DefaultMaxAge      = 24 * time.Hour
+	DefaultOverlap     = 5 * time.Minute
+	DefaultMaxAttempts = 3
+)
+
+// Backfill read Sonarr history since the last checkpoint and dispatch the records
+// through Triggers as the same typed events sent by webhooks:
+//
+//	grabbed                 GrabEvent
+//	downloadFolderImported  DownloadEvent
+//	episodeFileDeleted      EpisodeFileDeleteEvent
+//	episodeFileRenamed      RenameEvent, one per renamed file
+//
+// series deletions are not recorded in Sonarr history, so SeriesDeleteEvent can not
+// be recovered.
+//
+// Backfill is also an eventt.Sink, add it to Triggers.Sinks so events delivered by
+// webhook are recorded and not dispatched again:
+//
+//	bf := &backfill.Backfill{Client: client, Triggers: events, StatePath: "eventt.state"}
+//	events.Sinks = append(events.Sinks, bf)
+//	go bf.Run(ctx)
+//
+// history records do not have all the webhook fields, e.g. DownloadEvent.IsUpgrade is
+// always false and EpisodeFile ids are taken from the episodes.
+type Backfill struct {
+	Client   *sonarr.Client
+	Triggers *eventt.SonarrTriggers
+	// StatePath file where the checkpoint and delivered events are persisted, empty
+	// keep them in memory only.
+	StatePath string
+	// MaxAge how far back history is read when there is no checkpoint, default DefaultMaxAge.
+	MaxAge time.Duration
+	// Overlap history is read from checkpoint minus Overlap to tolerate clock skew
+	// between Sonarr and eventt, records already delivered are skipped.
+	// default DefaultOverlap.
+	Overlap time.Duration
+	// MaxAttempts number of Runs an event which fail to be dispatched, e.g. a sink
+	// error, is tried before it is skipped so later events are not blocked by it,
+	// default DefaultMaxAttempts.
+	MaxAttempts int
+
+	mu      sync.Mutex
+	state   *state
+	running bool
+	// synced true after a successful Run, from then live events advance the checkpoint.
+	synced bool
+}
+
+// state persisted between restarts.
+type state struct {
+	// Checkpoint time of the last delivered event.
+	Checkpoint time.Time `json:"checkpoint"`
+	// Delivered keys of delivered and skipped events and when they were delivered.
+	Delivered map[string]time.Time `json:"delivered"`
+	// Attempts number of failed dispatches by event key, see Backfill.MaxAttempts.
+	Attempts map[string]int `json:"attempts,omitempty"`
+}
+
+// Run dispatch history records since the last checkpoint, it returns the number of
+// dispatched events. it stops at the first event which fail to be dispatched and the
+// next Run starts from it, after MaxAttempts failed Runs the event is skipped and Run
+// continues with the next events, the skipped events are reported in the returned error.
+func (b *Backfill) Run(ctx context.Context) (int, error) {
+	b.mu.Lock()
+	if b.running {
+		b.mu.Unlock()
+		return 0, errors.New("backfill: already running")
+	}
+	if err := b.load(); err != nil {
+		b.mu.Unlock()
+		return 0, err
+	}
+	b.running = true
+	since := b.since()
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		b.running = false
+		b.mu.Unlock()
+	}()
+
+	started := time.Now()
+	records, err := b.Client.HistorySince(ctx, since)
+	if err != nil {
+		return 0, fmt.Errorf("backfill: %w", err)
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Date.Before(records[j].Date) })
+
+	dispatched := 0
+	var skipped []error
+	for _, group := range groupRecords(records) {
+		last := group[len(group)-1]
+		e, ok := toEvent(group)
+		if !ok {
+			continue
+		}
+		if b.delivered(e) {
+			b.advance(last.Date)
+			continue
+		}
+		payload, err := json.Marshal(e)
+		if err != nil {
+			return dispatched, fmt.Errorf("backfill: error encoding history record %d: %w", last.ID, err)
+		}
+		if err := b.Triggers.Dispatch(ctx, payload); err != nil {
+			err = fmt.Errorf("history record %d: %w", last.ID, err)
+			if !b.failed(e) {
+				b.save()
+				return dispatched, fmt.Errorf("backfill: %w", err)
+			}
+			// recorded like delivered events, so it is not read again in the overlap.
+			skipped = append(skipped, err)
+			b.record(e, time.Now())
+			b.advance(last.Date)
+			continue
+		}
+		// Triggers sinks may not include b, so the event is recorded here too.
+		b.record(e, time.Now())
+		b.advance(last.Date)
+		dispatched++
+	}
+
+	b.mu.Lock()
+	b.synced = true
+	// all the history until the request was read.
+	if b.state.Checkpoint.Before(started) {
+		b.state.Checkpoint = started
+	}
+	b.mu.Unlock()
+	if err := b.save(); err != nil {
+		return dispatched, err
+	}
+	if len(skipped) > 0 {
+		return dispatched, fmt.Errorf("backfill: skipped %d event(s) after %d attempts, last %w",
+			len(skipped), b.maxAttempts(), skipped[len(skipped)-1])
+	}
+	return dispatched, nil
+}
+
+// Send implements eventt.Sink, it records events delivered by webhook.
+func (b *Backfill) Send(ctx context.Context, event eventt.Event, payload []byte) error {
+	now := time.Now()
+	b.mu.Lock()
+	if err := b.load(); err != nil {
+		b.mu.Unlock()
+		return err
+	}
+	b.mu.Unlock()
+
+	if len(keys(event)) == 0 {
+		return nil
+	}
+	b.record(event, now)
+	b.mu.Lock()
+	if b.synced && !b.running && now.After(b.state.Checkpoint) {
+		b.state.Checkpoint = now
+	}
+	b.mu.Unlock()
+	return b.save()
+}
+
+// since return the time to read history from, b.mu must be held.
+func (b *Backfill) since() time.Time {
+	if b.state.Checkpoint.IsZero() {
+		maxAge := b.MaxAge
+		if maxAge <= 0 {
+			maxAge = DefaultMaxAge
+		}
+		return time.Now().Add(-maxAge)
+	}
+	overlap := b.Overlap
+	if overlap <= 0 {
+		overlap = DefaultOverlap
+	}
+	return b.state.Checkpoint.Add(-overlap)
+}
+
+func (b *Backfill) delivered(e eventt.Event) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ks := keys(e)
+	for _, k := range ks {
+		if _, ok := b.state.Delivered[k]; !ok {
+			return false
+		}
+	}
+	return len(ks) > 0
+}
+
+func (b *Backfill) record(e eventt.Event, at time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, k := range keys(e) {
+		b.state.Delivered[k] = at
+		delete(b.state.Attempts, k)
+	}
+}
+
+// failed count a failed dispatch of e, it report whether e reached MaxAttempts and
+// should be skipped.
+func (b *Backfill) failed(e eventt.Event) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state.Attempts == nil {
+		b.state.Attempts = make(map[string]int)
+	}
+	ks := keys(e)
+	attempts := 0
+	for _, k := range ks {
+		b.state.Attempts[k]++
+		if b.state.Attempts[k] > attempts {
+			attempts = b.state.Attempts[k]
+		}
+	}
+	return attempts >= b.maxAttempts()
+}
+
+func (b *Backfill) maxAttempts() int {
+	if b.MaxAttempts <= 0 {
+		return DefaultMaxAttempts
+	}
+	return b.MaxAttempts
+}
+
+func (b *Backfill) advance(t time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if t.After(b.state.Checkpoint) {
+		b.state.Checkpoint = t
+	}
+}
+
+// load read the state file once, b.mu must be held.
+func (b *Backfill) load() error {
+	if b.state != nil {
+		return nil
+	}
+	st := &state{Delivered: make(map[string]time.Time)}
+	if b.StatePath != "" {
+		data, err := os.ReadFile(b.StatePath)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			return fmt.Errorf("backfill: %w", err)
+		default:
+			if err := json.Unmarshal(data, st); err != nil {
+				return fmt.Errorf("backfill: error reading state '%s': %w", b.StatePath, err)
+			}
+			if st.Delivered == nil {
+				st.Delivered = make(map[string]time.Time)
+			}
+		}
+	}
+	b.state = st
+	return nil
+}
+
+// save prune old delivered keys and write the state file atomically.
+func (b *Backfill) save() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// keys older than the history window of the next Run are not needed anymore.
+	horizon := b.since().Add(-time.Hour)
+	for k, at := range b.state.Delivered {
+		if at.Before(horizon) {
+			delete(b.state.Delivered, k)
+		}
+	}
+	if b.StatePath == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(b.state)
+	if err != nil {
+		return fmt.Errorf("backfill: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(b.StatePath), filepath.Base(b.StatePath)+".*")
+	if err != nil {
+		return fmt.Errorf("backfill: error saving state: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("backfill: error saving state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("backfill: error saving state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), b.StatePath); err != nil {
+		return fmt.Errorf("backfill: error saving state: %w", err)
+	}
+	return nil
+}
+
+// keys identify an event in both webhook payloads and history records, events which
+// are not recorded in history have no keys. renames have a key per file.
+func keys(e eventt.Event) []string {
+	switch e := e.(type) {
+	case eventt.GrabEvent:
+		return []string{fmt.Sprintf("Grab/%d/%s", e.Series.ID, e.Release.ReleaseTitle)}
+	case eventt.DownloadEvent:
+		return []string{fmt.Sprintf("Download/%d/%s", e.Series.ID, baseName(e.EpisodeFile.Path))}
+	case eventt.EpisodeFileDeleteEvent:
+		return []string{fmt.Sprintf("EpisodeFileDelete/%d/%s", e.Series.ID, baseName(e.EpisodeFile.RelativePath))}
+	case eventt.RenameEvent:
+		ks := make([]string, 0, len(e.RenamedEpisodeFiles))
+		for _, f := range e.RenamedEpisodeFiles {
+			ks = append(ks, fmt.Sprintf("Rename/%d/%s", e.Series.ID, baseName(f.RelativePath)))
+		}
+		return ks
+	}
+	return nil
+}
+
+// baseName last element of a Sonarr path, which can be a Windows path.
+func baseName(path string) string {
+	if i := strings.LastIndexAny(path, `/\`); i >= 0 {
+		return path[i+1:]
+	}
+	return path
+}
+
+// groupRecords group consecutive records of the same event, e.g. multi episodes grab.
+func groupRecords(records []sonarr.HistoryRecord) [][]sonarr.HistoryRecord {
+	var groups [][]sonarr.HistoryRecord
+	for _, r := range records {
+		if n := len(groups); n > 0 && sameEvent(groups[n-1][0], r) {
+			groups[n-1] = append(groups[n-1], r)
+			continue
+		}
+		groups = append(groups, []sonarr.HistoryRecord{r})
+	}
+	return groups
+}
+
+func sameEvent(a, b sonarr.HistoryRecord) bool {
+	if a.EventType != b.EventType || a.SeriesID != b.SeriesID {
+		return false
+	}
+	if a.DownloadID != "" {
+		return a.DownloadID == b.DownloadID
+	}
+	return a.SourceTitle == b.SourceTitle && a.EventType != sonarr.HistoryEpisodeFileRenamed
+}
+
+// toEvent map history records of the same event to the typed event.
+func toEvent(group []sonarr.HistoryRecord) (eventt.Event, bool) {
+	r := group[0]
+	if r.Series == nil {
+		return nil, false
+	}
+	series := eventt.Series{
+		ID:       r.Series.ID,
+		Title:    r.Series.Title,
+		Path:     r.Series.Path,
+		TvdbID:   r.Series.TvdbID,
+		TvMazeID: r.Series.TvMazeID,
+		ImdbID:   r.Series.ImdbID,
+		Type:     r.Series.SeriesType,
+	}
+	var episodes []eventt.Episode
+	for _, g := range group {
+		if g.Episode == nil {
+			continue
+		}
+		episodes = append(episodes, eventt.Episode{
+			ID:            g.Episode.ID,
+			EpisodeNumber: g.Episode.EpisodeNumber,
+			SeasonNumber:  g.Episode.SeasonNumber,
+			Title:         g.Episode.Title,
+			AirDate:       g.Episode.AirDate,
+			AirDateUtc:    g.Episode.AirDateUtc,
+		})
+	}
+
+	switch r.EventType {
+	case sonarr.HistoryGrabbed:
+		e := eventt.GrabEvent{
+			Series:             series,
+			Episodes:           episodes,
+			DownloadClient:     firstOf(r.Data["downloadClientName"], r.Data["downloadClient"]),
+			DownloadClientType: r.Data["downloadClient"],
+			DownloadID:         r.DownloadID,
+			EventType:          string(eventt.Grab),
+		}
+		e.Release.Quality = r.Quality.Quality.Name
+		e.Release.QualityVersion = r.Quality.Revision.Version
+		e.Release.ReleaseGroup = r.Data["releaseGroup"]
+		e.Release.ReleaseTitle = r.SourceTitle
+		e.Release.Indexer = r.Data["indexer"]
+		e.Release.Size, _ = strconv.Atoi(r.Data["size"])
+		return e, true
+	case sonarr.HistoryDownloadFolderImported:
+		e := eventt.DownloadEvent{Series: series, Episodes: episodes, EventType: string(eventt.Download)}
+		e.EpisodeFile.Path = r.Data["importedPath"]
+		e.EpisodeFile.RelativePath = relativePath(series.Path, e.EpisodeFile.Path)
+		e.EpisodeFile.Quality = r.Quality.Quality.Name
+		e.EpisodeFile.QualityVersion = r.Quality.Revision.Version
+		e.EpisodeFile.ReleaseGroup = r.Data["releaseGroup"]
+		if r.Episode != nil {
+			e.EpisodeFile.ID = r.Episode.EpisodeFileID
+		}
+		return e, true
+	case sonarr.HistoryEpisodeFileDeleted:
+		e := eventt.EpisodeFileDeleteEvent{
+			Series:       series,
+			Episodes:     episodes,
+			DeleteReason: r.Data["reason"],
+			EventType:    string(eventt.EpisodeFileDelete),
+		}
+		e.EpisodeFile.RelativePath = r.SourceTitle
+		e.EpisodeFile.Path = joinPath(series.Path, r.SourceTitle)
+		e.EpisodeFile.ReleaseGroup = r.Data["releaseGroup"]
+		e.EpisodeFile.Quality = r.Quality.Quality.Name
+		e.EpisodeFile.QualityVersion = r.Quality.Revision.Version
+		return e, true
+	case sonarr.HistoryEpisodeFileRenamed:
+		f := eventt.RenamedEpisodeFile{
+			PreviousRelativePath: r.Data["sourceRelativePath"],
+			PreviousPath:         r.Data["sourcePath"],
+			RelativePath:         r.Data["relativePath"],
+			Quality:              r.Quality.Quality.Name,
+			QualityVersion:       r.Quality.Revision.Version,
+			ReleaseGroup:         r.Data["releaseGroup"],
+		}
+		if r.Episode != nil {
+			f.ID = r.Episode.EpisodeFileID
+		}
+		return eventt.RenameEvent{
+			Series:              series,
+			RenamedEpisodeFiles: []eventt.RenamedEpisodeFile{f},
+			EventType:           string(eventt.Rename),
+		}, true
+	}
+	return nil, false
+}
+
+func firstOf(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func relativePath(seriesPath, path string) string {
+	rel := strings.TrimPrefix(path, seriesPath)
+	return strings.TrimLeft(rel, `/\`)
+}
+
+func joinPath(dir, rel string) string {
+	if dir == "" {
+		return rel
+	}
+	sep := "/"
+	if strings.Contains(dir, `\`) {
+		sep = `\`
+	}
+	return strings.TrimRight(dir, `/\`) + sep + rel
+}
@@ -0,0 +1,79 @@
+package backfill
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/k-x7/eventt"
+	"github.com/k-x7/eventt/sonarr"
+)
+
+func grabRecord(id int, title string, date time.Time) sonarr.HistoryRecord {
+	r := sonarr.HistoryRecord{
+		ID:          id,
+		SeriesID:    1,
+		SourceTitle: title,
+		Date:        date,
+		DownloadID:  title,
+		EventType:   sonarr.HistoryGrabbed,
+		Data:        map[string]string{"indexer": "nyaa"},
+		Series:      &sonarr.Series{ID: 1, Title: "Mob Psycho 100"},
+		Episode:     &sonarr.Episode{ID: 10 + id, SeasonNumber: 1, EpisodeNumber: id},
+	}
+	r.Quality.Quality.Name = "WEBDL-1080p"
+	return r
+}
+
+func TestRunSkipsFailingEvent(t *testing.T) {
+	now := time.Now().Add(-time.Hour)
+	records := []sonarr.HistoryRecord{
+		grabRecord(1, "Mob.Psycho.100.S01E01", now),
+		grabRecord(2, "Mob.Psycho.100.S01E02", now.Add(time.Minute)),
+		// series deletions are not in history, unknown records are ignored.
+		{ID: 3, SeriesID: 1, EventType: "seriesDeleted", Date: now.Add(2 * time.Minute), Series: &sonarr.Series{ID: 1}},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(records)
+	}))
+	defer srv.Close()
+
+	var grabbed []string
+	errSink := errors.New("sink down")
+	events := &eventt.SonarrTriggers{
+		OnGrab: func(e eventt.GrabEvent) { grabbed = append(grabbed, e.Release.ReleaseTitle) },
+		Sinks: []eventt.Sink{eventt.SinkFunc(func(ctx context.Context, e eventt.Event, payload []byte) error {
+			if g, ok := e.(eventt.GrabEvent); ok && strings.HasSuffix(g.Release.ReleaseTitle, "E01") {
+				return errSink
+			}
+			return nil
+		})},
+	}
+	bf := &Backfill{Client: &sonarr.Client{BaseURL: srv.URL}, Triggers: events, MaxAttempts: 2}
+
+	n, err := bf.Run(context.Background())
+	if n != 0 || !errors.Is(err, errSink) {
+		t.Fatalf("first run: got %d, %v, want 0 and sink error", n, err)
+	}
+	n, err = bf.Run(context.Background())
+	if n != 1 || !errors.Is(err, errSink) || !strings.Contains(err.Error(), "skipped 1 event") {
+		t.Fatalf("second run: got %d, %v, want 1 and skipped event", n, err)
+	}
+	n, err = bf.Run(context.Background())
+	if n != 0 || err != nil {
+		t.Fatalf("third run: got %d, %v, want nothing left", n, err)
+	}
+
+	want := []string{"Mob.Psycho.100.S01E01", "Mob.Psycho.100.S01E01", "Mob.Psycho.100.S01E02"}
+	if strings.Join(grabbed, ",") != strings.Join(want, ",") {
+		t.Errorf("grabbed %v, want %v", grabbed, want)
+	}
+	if len(bf.state.Attempts) != 0 {
+		t.Errorf("attempts not cleared: %v", bf.state.Attempts)
+	}
+}
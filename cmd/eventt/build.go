@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/exp/slog"
+
+	"github.com/k-x7/eventt"
+	"github.com/k-x7/eventt/journal"
+	"github.com/k-x7/eventt/notify"
+	"github.com/k-x7/eventt/relay"
+	"github.com/k-x7/eventt/script"
+)
+
+// instance webhook handler built from a Config, with the sinks and triggers.
+type instance struct {
+	cfg      *Config
+	triggers *eventt.SonarrTriggers
+	handler  http.Handler
+	closers  []func(ctx context.Context) error
+
+	mu      sync.Mutex
+	active  int
+	retired bool
+	idle    chan struct{}
+}
+
+// build create the handler and sinks described by cfg, metrics is shared between
+// reloads so counters are not reset, nil create a new one.
+func build(cfg *Config, metrics *eventt.Metrics) (*instance, error) {
+	if metrics == nil {
+		metrics = &eventt.Metrics{}
+	}
+	inst := &instance{cfg: cfg, idle: make(chan struct{})}
+
+	var templates *eventt.Templates
+	if cfg.Templates != "" {
+		templates = &eventt.Templates{}
+		if err := templates.ParseDir(cfg.Templates); err != nil {
+			return nil, fmt.Errorf("templates: %w", err)
+		}
+	}
+
+	triggers := &eventt.SonarrTriggers{
+		LogOnError:     true,
+		MaxPayloadSize: cfg.Limits.MaxPayloadSize,
+		ReadTimeout:    cfg.Limits.ReadTimeout,
+		RequireJSON:    cfg.Limits.RequireJSON,
+		Metrics:        metrics,
+	}
+	if cfg.Access != nil {
+		triggers.Access = &eventt.AccessControl{
+			Allow:          cfg.Access.Allow,
+			TrustedProxies: cfg.Access.TrustedProxies,
+			RateLimit:      cfg.Access.RateLimit,
+			Burst:          cfg.Access.Burst,
+		}
+	}
+	for _, sc := range cfg.Sinks {
+		sink, closer, err := buildSink(sc, templates)
+		if err != nil {
+			return nil, fmt.Errorf("sink '%s': %w", sc.Name, err)
+		}
+		triggers.Sinks = append(triggers.Sinks, &filtered{
+			name:    sc.Name,
+			filters: cfg.Filters,
+			types:   eventt.Types(sc.Types...),
+			sink:    sink,
+		})
+		if closer != nil {
+			inst.closers = append(inst.closers, closer)
+		}
+		if hc, ok := sink.(eventt.HealthChecker); ok {
+			triggers.RegisterCheck("sink "+sc.Name, hc.HealthCheck)
+		}
+	}
+	inst.triggers = triggers
+
+	mux := http.NewServeMux()
+	mux.Handle(cfg.Path, basicAuth(cfg.Auth, http.HandlerFunc(triggers.Monitor)))
+	if cfg.Metrics != "" {
+		mux.Handle(cfg.Metrics, triggers.Metrics)
+	}
+	if cfg.Healthz != "" {
+		mux.HandleFunc(cfg.Healthz, triggers.Healthz)
+	}
+	if cfg.Readyz != "" {
+		mux.HandleFunc(cfg.Readyz, triggers.Readyz)
+	}
+	inst.handler = mux
+	return inst, nil
+}
+
+// acquire register an in-flight request, it returns false if the instance is retired.
+func (inst *instance) acquire() bool {
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	if inst.retired {
+		return false
+	}
+	inst.active++
+	return true
+}
+
+func (inst *instance) release() {
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	inst.active--
+	if inst.retired && inst.active == 0 {
+		close(inst.idle)
+	}
+}
+
+// retire stop accepting new requests, the returned channel is closed when all
+// in-flight requests are done.
+func (inst *instance) retire() <-chan struct{} {
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	if !inst.retired {
+		inst.retired = true
+		if inst.active == 0 {
+			close(inst.idle)
+		}
+	}
+	return inst.idle
+}
+
+// close release the sinks, waiting for pending deliveries until ctx is done.
+func (inst *instance) close(ctx context.Context) {
+	inst.triggers.Close()
+	for _, c := range inst.closers {
+		if err := c(ctx); err != nil {
+			slog.Error("error closing sink", err)
+		}
+	}
+}
+
+func buildSink(sc SinkConfig, templates *eventt.Templates) (eventt.Sink, func(context.Context) error, error) {
+	options := notify.Options{}
+	if templates != nil {
+		options.Format = notify.TemplateFormat(templates, sc.Name)
+	}
+	if sc.GroupWindow > 0 {
+		options.Group = &notify.SeriesGroup{
+			Window: sc.GroupWindow,
+			OnError: func(err error) {
+				slog.Error("error sending grouped notification", err, "sink", sc.Name)
+			},
+		}
+	}
+
+	switch sc.Type {
+	case "exec":
+		return &script.Exec{
+			Command:       sc.Command,
+			Args:          sc.Args,
+			Dir:           sc.Dir,
+			Env:           sc.Env,
+			Stdin:         sc.Stdin,
+			Timeout:       sc.Timeout,
+			MaxConcurrent: sc.MaxConcurrent,
+			OnResult: func(r script.Result) {
+				slog.Debug("script finished", "sink", sc.Name, "event", r.Event,
+					"exit_code", r.ExitCode, "duration", r.Duration, "stdout", string(r.Stdout))
+			},
+		}, nil, nil
+	case "relay":
+		r := &relay.Relay{
+			MaxAttempts: sc.MaxAttempts,
+			MinBackoff:  sc.MinBackoff,
+			MaxBackoff:  sc.MaxBackoff,
+			OnDelivery: func(d relay.Delivery) {
+				if d.Err == nil {
+					slog.Debug("relay delivered", "sink", sc.Name, "target", d.Target, "event", d.Event, "attempt", d.Attempt)
+					return
+				}
+				slog.Warn("relay delivery failed", "sink", sc.Name, "target", d.Target, "event", d.Event,
+					"attempt", d.Attempt, "final", d.Final, "error", d.Err)
+			},
+		}
+		for _, t := range sc.Targets {
+			header := http.Header{}
+			for k, v := range t.Headers {
+				header.Set(k, v)
+			}
+			r.Targets = append(r.Targets, relay.Target{
+				Name:   t.Name,
+				URL:    t.URL,
+				Secret: t.Secret,
+				Types:  eventt.Types(t.Types...),
+				Header: header,
+			})
+		}
+		return r, r.Close, nil
+	case "file":
+		j := &journal.File{Path: sc.Path, Sync: sc.Sync}
+		return j, func(context.Context) error { return j.Close() }, nil
+	case "discord":
+		n := &notify.Discord{WebhookURL: sc.URL, Options: options}
+		return n, n.Close, nil
+	case "slack":
+		n := &notify.Slack{WebhookURL: sc.URL, Options: options}
+		return n, n.Close, nil
+	case "telegram":
+		n := &notify.Telegram{Token: sc.Token, ChatID: sc.ChatID, APIURL: sc.URL, Options: options}
+		return n, n.Close, nil
+	case "ntfy":
+		n := &notify.Ntfy{Server: sc.URL, Topic: sc.Topic, Token: sc.Token, Options: options}
+		return n, n.Close, nil
+	case "gotify":
+		n := &notify.Gotify{Server: sc.URL, Token: sc.Token, Options: options}
+		return n, n.Close, nil
+	}
+	return nil, nil, fmt.Errorf("unknown sink type '%s'", sc.Type)
+}
+
+// filtered apply the global filters and the sink event types before calling the sink.
+type filtered struct {
+	name    string
+	filters FilterConfig
+	types   eventt.TypeFilter
+	sink    eventt.Sink
+}
+
+func (f *filtered) Send(ctx context.Context, event eventt.Event, payload []byte) error {
+	t := eventt.TypeOf(event)
+	if !f.types.Match(t) || !eventt.Types(f.filters.Types...).Match(t) {
+		return nil
+	}
+	if title, ok := seriesTitle(event); ok {
+		if len(f.filters.Series) > 0 && !containsFold(f.filters.Series, title) {
+			return nil
+		}
+		if containsFold(f.filters.ExcludeSeries, title) {
+			return nil
+		}
+	}
+	if err := f.sink.Send(ctx, event, payload); err != nil {
+		return fmt.Errorf("%s: %w", f.name, err)
+	}
+	return nil
+}
+
+// seriesTitle return the series title of events related to a series.
+func seriesTitle(event eventt.Event) (string, bool) {
+	switch e := event.(type) {
+	case eventt.GrabEvent:
+		return e.Series.Title, true
+	case eventt.DownloadEvent:
+		return e.Series.Title, true
+	case eventt.RenameEvent:
+		return e.Series.Title, true
+	case eventt.EpisodeFileDeleteEvent:
+		return e.Series.Title, true
+	case eventt.SeriesDeleteEvent:
+		return e.Series.Title, true
+	}
+	return "", false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// basicAuth require the credentials set in Sonarr webhook connection, if auth is empty
+// the handler is returned as is.
+func basicAuth(auth AuthConfig, next http.Handler) http.Handler {
+	if auth.Username == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok ||
+			subtle.ConstantTimeCompare([]byte(user), []byte(auth.Username)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(pass), []byte(auth.Password)) != 1 {
+			slog.Warn("rejected request with invalid credentials", "remote", r.RemoteAddr)
+			w.Header().Set("WWW-Authenticate", `Basic realm="eventt"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
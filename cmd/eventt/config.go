@@ -0,0 +1,384 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"github.com/k-x7/eventt"
+)
+
+// Config eventt daemon configuration file.
+type Config struct {
+	// Listen address of the webhook server, e.g. ":8281", "unix:/run/eventt/eventt.sock"
+	// or "systemd" for socket activation, see eventt.Server.Addr.
+	Listen string `yaml:"listen" toml:"listen"`
+	// SocketMode octal permissions of the Unix domain socket, e.g. "0660".
+	SocketMode string `yaml:"socket_mode" toml:"socket_mode"`
+	// Path where Sonarr sends the events, default /events.
+	Path string `yaml:"path" toml:"path"`
+	// Metrics path to expose Prometheus metrics, empty disable it.
+	Metrics string `yaml:"metrics" toml:"metrics"`
+	// Healthz and Readyz paths of liveness and readiness endpoints, empty disable them.
+	Healthz string     `yaml:"healthz" toml:"healthz"`
+	Readyz  string     `yaml:"readyz" toml:"readyz"`
+	TLS     TLSConfig  `yaml:"tls" toml:"tls"`
+	Auth    AuthConfig `yaml:"auth" toml:"auth"`
+	Limits  struct {
+		MaxPayloadSize int64         `yaml:"max_payload_size" toml:"max_payload_size"`
+		ReadTimeout    time.Duration `yaml:"read_timeout" toml:"read_timeout"`
+		RequireJSON    bool          `yaml:"require_json" toml:"require_json"`
+	} `yaml:"limits" toml:"limits"`
+	Access *struct {
+		Allow          []string `yaml:"allow" toml:"allow"`
+		TrustedProxies []string `yaml:"trusted_proxies" toml:"trusted_proxies"`
+		RateLimit      float64  `yaml:"rate_limit" toml:"rate_limit"`
+		Burst          int      `yaml:"burst" toml:"burst"`
+	} `yaml:"access" toml:"access"`
+	Sonarr    SonarrConfig  `yaml:"sonarr" toml:"sonarr"`
+	Filters   FilterConfig  `yaml:"filters" toml:"filters"`
+	Templates string        `yaml:"templates" toml:"templates"`
+	Sinks     []SinkConfig  `yaml:"sinks" toml:"sinks"`
+	Logging   LoggingConfig `yaml:"logging" toml:"logging"`
+}
+
+// TLSConfig certificate and key files, TLS is enabled when both are set, the
+// certificate is reloaded when the files change.
+type TLSConfig struct {
+	Cert string `yaml:"cert" toml:"cert"`
+	Key  string `yaml:"key" toml:"key"`
+	// ClientCA CA certificates file, if set clients must present a certificate signed by them.
+	ClientCA string `yaml:"client_ca" toml:"client_ca"`
+}
+
+// AuthConfig basic authentication credentials set in Sonarr webhook connection.
+type AuthConfig struct {
+	Username string `yaml:"username" toml:"username"`
+	Password string `yaml:"password" toml:"password"`
+}
+
+// SonarrConfig Sonarr API used by the register command.
+type SonarrConfig struct {
+	// URL of Sonarr, e.g. http://localhost:8989.
+	URL string `yaml:"url" toml:"url"`
+	// APIKey from Sonarr Settings > General.
+	APIKey string `yaml:"api_key" toml:"api_key"`
+	// WebhookURL eventt endpoint as reachable from Sonarr, e.g. http://eventt:8281/events.
+	WebhookURL string `yaml:"webhook_url" toml:"webhook_url"`
+	// Tags series tags which trigger the webhook, empty means all series.
+	Tags []string `yaml:"tags" toml:"tags"`
+}
+
+// FilterConfig events passed to the sinks, events not matching are acknowledged
+// to Sonarr and ignored.
+type FilterConfig struct {
+	// Types event types to process, empty means all.
+	Types []eventt.EventType `yaml:"types" toml:"types"`
+	// Series titles to process, empty means all.
+	Series []string `yaml:"series" toml:"series"`
+	// ExcludeSeries titles to ignore.
+	ExcludeSeries []string `yaml:"exclude_series" toml:"exclude_series"`
+}
+
+// LoggingConfig log level and format.
+type LoggingConfig struct {
+	// Level one of debug, info, warn or error, default info.
+	Level string `yaml:"level" toml:"level"`
+	// Format text or json, default text.
+	Format string `yaml:"format" toml:"format"`
+}
+
+// SinkConfig configuration of a single sink, fields used depend on Type.
+type SinkConfig struct {
+	// Type exec, relay, file, discord, slack, telegram, ntfy or gotify.
+	Type string `yaml:"type" toml:"type"`
+	// Name used in logs and templates, default Type.
+	Name string `yaml:"name" toml:"name"`
+	// Types events sent to this sink, empty means all.
+	Types []eventt.EventType `yaml:"types" toml:"types"`
+
+	// exec
+	Command       string        `yaml:"command" toml:"command"`
+	Args          []string      `yaml:"args" toml:"args"`
+	Dir           string        `yaml:"dir" toml:"dir"`
+	Env           []string      `yaml:"env" toml:"env"`
+	Stdin         bool          `yaml:"stdin" toml:"stdin"`
+	Timeout       time.Duration `yaml:"timeout" toml:"timeout"`
+	MaxConcurrent int           `yaml:"max_concurrent" toml:"max_concurrent"`
+
+	// relay
+	Targets     []TargetConfig `yaml:"targets" toml:"targets"`
+	MaxAttempts int            `yaml:"max_attempts" toml:"max_attempts"`
+	MinBackoff  time.Duration  `yaml:"min_backoff" toml:"min_backoff"`
+	MaxBackoff  time.Duration  `yaml:"max_backoff" toml:"max_backoff"`
+
+	// file
+	Path string `yaml:"path" toml:"path"`
+	Sync bool   `yaml:"sync" toml:"sync"`
+
+	// discord, slack: webhook url, ntfy, gotify: server url, telegram: api url
+	URL string `yaml:"url" toml:"url"`
+	// discord, slack, telegram, ntfy, gotify: group messages of the same series, zero means no grouping
+	GroupWindow time.Duration `yaml:"group_window" toml:"group_window"`
+	// telegram, ntfy, gotify
+	Token string `yaml:"token" toml:"token"`
+	// telegram
+	ChatID string `yaml:"chat_id" toml:"chat_id"`
+	// ntfy
+	Topic string `yaml:"topic" toml:"topic"`
+}
+
+// TargetConfig relay target.
+type TargetConfig struct {
+	Name    string             `yaml:"name" toml:"name"`
+	URL     string             `yaml:"url" toml:"url"`
+	Secret  string             `yaml:"secret" toml:"secret"`
+	Types   []eventt.EventType `yaml:"types" toml:"types"`
+	Headers map[string]string  `yaml:"headers" toml:"headers"`
+}
+
+var sinkTypes = map[string]bool{
+	"exec": true, "relay": true, "file": true,
+	"discord": true, "slack": true, "telegram": true, "ntfy": true, "gotify": true,
+}
+
+var eventTypes = map[eventt.EventType]bool{
+	eventt.Grab: true, eventt.Download: true, eventt.Rename: true,
+	eventt.EpisodeFileDelete: true, eventt.SeriesDelete: true, eventt.Health: true,
+	eventt.ApplicationUpdate: true, eventt.Test: true, eventt.Unknown: true,
+}
+
+// LoadConfig read and validate the configuration file, files with .toml extension are
+// read as TOML and other files as YAML, unknown fields are rejected.
+func LoadConfig(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{}
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		err = decodeTOML(b, cfg)
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(b))
+		dec.KnownFields(true)
+		err = dec.Decode(cfg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	cfg.defaults()
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// decodeTOML decode a TOML config, unknown keys are rejected like in YAML configs.
+func decodeTOML(b []byte, cfg *Config) error {
+	md, err := toml.Decode(string(b), cfg)
+	if err != nil {
+		return err
+	}
+	if keys := md.Undecoded(); len(keys) > 0 {
+		names := make([]string, len(keys))
+		for i, k := range keys {
+			names[i] = k.String()
+		}
+		return fmt.Errorf("unknown fields: %s", strings.Join(names, ", "))
+	}
+	return nil
+}
+
+func (c *Config) defaults() {
+	if c.Listen == "" {
+		c.Listen = ":8281"
+	}
+	if c.Path == "" {
+		c.Path = "/events"
+	}
+	for i := range c.Sinks {
+		if c.Sinks[i].Name == "" {
+			c.Sinks[i].Name = c.Sinks[i].Type
+		}
+	}
+}
+
+// Validate check the configuration for errors, all errors are reported at once.
+func (c *Config) Validate() error {
+	var errs []string
+	add := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Sprintf(format, args...))
+	}
+
+	if c.SocketMode != "" {
+		if _, err := c.socketMode(); err != nil {
+			add("socket_mode: must be octal permissions, e.g. \"0660\"")
+		}
+	}
+	if !strings.HasPrefix(c.Path, "/") {
+		add("path: must start with '/'")
+	}
+	endpoints := map[string]string{"metrics": c.Metrics, "healthz": c.Healthz, "readyz": c.Readyz}
+	used := make(map[string]string)
+	for _, name := range []string{"metrics", "healthz", "readyz"} {
+		p := endpoints[name]
+		if p == "" {
+			continue
+		}
+		if other, ok := used[p]; ok {
+			add("%s: must be different from %s", name, other)
+		}
+		used[p] = name
+		if !strings.HasPrefix(p, "/") {
+			add("%s: must start with '/'", name)
+		}
+		if p == c.Path {
+			add("%s: must be different from path", name)
+		}
+	}
+	if (c.TLS.Cert == "") != (c.TLS.Key == "") {
+		add("tls: both cert and key are required")
+	}
+	if c.TLS.ClientCA != "" && c.TLS.Cert == "" {
+		add("tls: client_ca requires cert and key")
+	}
+	if (c.Auth.Username == "") != (c.Auth.Password == "") {
+		add("auth: both username and password are required")
+	}
+	if c.Access != nil {
+		access := &eventt.AccessControl{Allow: c.Access.Allow, TrustedProxies: c.Access.TrustedProxies}
+		if err := access.Validate(); err != nil {
+			add("access: %v", err)
+		}
+	}
+	if c.Sonarr.URL != "" {
+		if err := validURL(c.Sonarr.URL); err != nil {
+			add("sonarr.url: %v", err)
+		}
+	}
+	if c.Sonarr.WebhookURL != "" {
+		if err := validURL(c.Sonarr.WebhookURL); err != nil {
+			add("sonarr.webhook_url: %v", err)
+		}
+	}
+	for _, t := range c.Filters.Types {
+		if !eventTypes[t] {
+			add("filters.types: unknown event type '%s'", t)
+		}
+	}
+	switch c.Logging.Level {
+	case "", "debug", "info", "warn", "error":
+	default:
+		add("logging.level: unknown level '%s'", c.Logging.Level)
+	}
+	switch c.Logging.Format {
+	case "", "text", "json":
+	default:
+		add("logging.format: unknown format '%s'", c.Logging.Format)
+	}
+	if c.Templates != "" {
+		if err := (&eventt.Templates{}).ParseDir(c.Templates); err != nil {
+			add("templates: %v", err)
+		}
+	}
+
+	names := make(map[string]bool)
+	for i, s := range c.Sinks {
+		prefix := fmt.Sprintf("sinks[%d] (%s)", i, s.Name)
+		if names[s.Name] {
+			add("%s: duplicate sink name, set a unique name", prefix)
+		}
+		names[s.Name] = true
+		for _, t := range s.Types {
+			if !eventTypes[t] {
+				add("%s: types: unknown event type '%s'", prefix, t)
+			}
+		}
+
+		switch s.Type {
+		case "exec":
+			if s.Command == "" {
+				add("%s: command is required", prefix)
+			}
+		case "relay":
+			if len(s.Targets) == 0 {
+				add("%s: at least one target is required", prefix)
+			}
+			for j, t := range s.Targets {
+				if err := validURL(t.URL); err != nil {
+					add("%s: targets[%d].url: %v", prefix, j, err)
+				}
+				for _, et := range t.Types {
+					if !eventTypes[et] {
+						add("%s: targets[%d].types: unknown event type '%s'", prefix, j, et)
+					}
+				}
+			}
+		case "file":
+			if s.Path == "" {
+				add("%s: path is required", prefix)
+			}
+		case "discord", "slack":
+			if err := validURL(s.URL); err != nil {
+				add("%s: url: %v", prefix, err)
+			}
+		case "telegram":
+			if s.Token == "" || s.ChatID == "" {
+				add("%s: token and chat_id are required", prefix)
+			}
+		case "ntfy":
+			if s.Topic == "" {
+				add("%s: topic is required", prefix)
+			}
+		case "gotify":
+			if err := validURL(s.URL); err != nil {
+				add("%s: url: %v", prefix, err)
+			}
+			if s.Token == "" {
+				add("%s: token is required", prefix)
+			}
+		default:
+			add("%s: unknown sink type '%s'", prefix, s.Type)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+func (c *Config) socketMode() (os.FileMode, error) {
+	if c.SocketMode == "" {
+		return 0, nil
+	}
+	mode, err := strconv.ParseUint(c.SocketMode, 8, 32)
+	if err != nil || mode > 0o777 {
+		return 0, fmt.Errorf("invalid socket mode '%s'", c.SocketMode)
+	}
+	return os.FileMode(mode), nil
+}
+
+func validURL(s string) error {
+	if s == "" {
+		return errors.New("is required")
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme '%s'", u.Scheme)
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/k-x7/eventt"
+)
+
+// writeConfig write content to a config file named name in a temporary directory.
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigExamples(t *testing.T) {
+	yamlConfig, err := LoadConfig("eventt.example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tomlConfig, err := LoadConfig("eventt.example.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(yamlConfig, tomlConfig) {
+		t.Errorf("examples differ:\nyaml %+v\ntoml %+v", yamlConfig, tomlConfig)
+	}
+
+	c := tomlConfig
+	if c.Listen != ":8281" || c.Limits.ReadTimeout != 10*time.Second || c.Access == nil || c.Access.Burst != 20 {
+		t.Errorf("config = %+v", c)
+	}
+	if len(c.Sinks) != 5 || c.Sinks[0].Name != "file" || c.Sinks[1].Timeout != 30*time.Second ||
+		len(c.Sinks[2].Targets) != 2 || c.Sinks[2].Targets[1].Types[1] != eventt.Download || c.Sinks[3].GroupWindow != 5*time.Minute {
+		t.Errorf("sinks = %+v", c.Sinks)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		err     string
+	}{
+		{
+			"unix socket with access", "eventt.yaml",
+			"listen: unix:/run/eventt/eventt.sock\nsocket_mode: \"0600\"\naccess:\n  allow: [127.0.0.1]\n  trusted_proxies: [127.0.0.1]\n",
+			"",
+		},
+		{
+			"unix socket with access", "eventt.toml",
+			"listen = \"unix:/run/eventt/eventt.sock\"\nsocket_mode = \"0600\"\n[access]\nallow = [\"127.0.0.1\"]\ntrusted_proxies = [\"127.0.0.1\"]\n",
+			"",
+		},
+		{"unknown yaml field", "eventt.yml", "listne: :8281\n", "field listne not found"},
+		{"unknown toml field", "eventt.toml", "listne = \":8281\"\n[limits]\nread_timout = \"1s\"\n", "unknown fields: listne, limits.read_timout"},
+		{"invalid toml", "eventt.toml", "listen = \n", "eventt.toml"},
+		{"invalid toml duration", "eventt.toml", "[limits]\nread_timeout = \"soon\"\n", "soon"},
+		{"invalid config", "eventt.toml", "path = \"events\"\n", "path: must start with '/'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+" "+tt.file, func(t *testing.T) {
+			c, err := LoadConfig(writeConfig(t, tt.file, tt.content))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got %v, want error containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mode, err := c.socketMode(); err != nil || mode != 0o600 {
+				t.Errorf("socket mode %v, %v", mode, err)
+			}
+			if c.Listen != "unix:/run/eventt/eventt.sock" || c.Path != "/events" || c.Access == nil || c.Access.TrustedProxies[0] != "127.0.0.1" {
+				t.Errorf("config = %+v", c)
+			}
+		})
+	}
+
+	if _, err := LoadConfig(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("missing file loaded")
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	valid := func() *Config {
+		c := &Config{Sinks: []SinkConfig{{Type: "ntfy", Topic: "sonarr"}}}
+		c.defaults()
+		return c
+	}
+	tests := []struct {
+		name   string
+		update func(c *Config)
+		errs   []string
+	}{
+		{"valid", func(c *Config) {}, nil},
+		{"unix socket with access", func(c *Config) {
+			c.Listen = "unix:/run/eventt/eventt.sock"
+			c.Access = &struct {
+				Allow          []string `yaml:"allow" toml:"allow"`
+				TrustedProxies []string `yaml:"trusted_proxies" toml:"trusted_proxies"`
+				RateLimit      float64  `yaml:"rate_limit" toml:"rate_limit"`
+				Burst          int      `yaml:"burst" toml:"burst"`
+			}{Allow: []string{"127.0.0.1"}}
+		}, nil},
+		{"socket mode", func(c *Config) { c.SocketMode = "0999" }, []string{"socket_mode: must be octal permissions"}},
+		{"paths", func(c *Config) { c.Path = "events"; c.Metrics = "/m"; c.Healthz = "/m"; c.Readyz = "ready" }, []string{
+			"path: must start with '/'",
+			"healthz: must be different from metrics",
+			"readyz: must start with '/'",
+		}},
+		{"endpoint same as path", func(c *Config) { c.Metrics = "/events" }, []string{"metrics: must be different from path"}},
+		{"tls", func(c *Config) { c.TLS.Cert = "cert.pem"; c.TLS.ClientCA = "ca.pem" }, []string{"tls: both cert and key are required"}},
+		{"client ca without cert", func(c *Config) { c.TLS.ClientCA = "ca.pem" }, []string{"tls: client_ca requires cert and key"}},
+		{"auth", func(c *Config) { c.Auth.Username = "sonarr" }, []string{"auth: both username and password are required"}},
+		{"access", func(c *Config) {
+			c.Access = &struct {
+				Allow          []string `yaml:"allow" toml:"allow"`
+				TrustedProxies []string `yaml:"trusted_proxies" toml:"trusted_proxies"`
+				RateLimit      float64  `yaml:"rate_limit" toml:"rate_limit"`
+				Burst          int      `yaml:"burst" toml:"burst"`
+			}{Allow: []string{"10.0.0.0/33"}}
+		}, []string{"access: "}},
+		{"sonarr urls", func(c *Config) { c.Sonarr.URL = "ftp://sonarr"; c.Sonarr.WebhookURL = "eventt" }, []string{
+			"sonarr.url: unsupported scheme 'ftp'",
+			"sonarr.webhook_url: unsupported scheme ''",
+		}},
+		{"filters", func(c *Config) { c.Filters.Types = []eventt.EventType{"Grabbed"} }, []string{"filters.types: unknown event type 'Grabbed'"}},
+		{"logging", func(c *Config) { c.Logging = LoggingConfig{Level: "trace", Format: "xml"} }, []string{
+			"logging.level: unknown level 'trace'",
+			"logging.format: unknown format 'xml'",
+		}},
+		{"sinks", func(c *Config) {
+			c.Sinks = []SinkConfig{
+				{Type: "exec", Name: "exec"},
+				{Type: "exec", Name: "exec", Command: "true", Types: []eventt.EventType{"Nope"}},
+				{Type: "relay", Name: "relay"},
+				{Type: "relay", Name: "relay2", Targets: []TargetConfig{{URL: "x"}}},
+				{Type: "file", Name: "file"},
+				{Type: "discord", Name: "discord"},
+				{Type: "telegram", Name: "telegram", Token: "t"},
+				{Type: "ntfy", Name: "ntfy"},
+				{Type: "gotify", Name: "gotify", URL: "https://gotify"},
+				{Type: "pager", Name: "pager"},
+			}
+		}, []string{
+			"sinks[0] (exec): command is required",
+			"sinks[1] (exec): duplicate sink name, set a unique name",
+			"sinks[1] (exec): types: unknown event type 'Nope'",
+			"sinks[2] (relay): at least one target is required",
+			"sinks[3] (relay2): targets[0].url: unsupported scheme ''",
+			"sinks[4] (file): path is required",
+			"sinks[5] (discord): url: is required",
+			"sinks[6] (telegram): token and chat_id are required",
+			"sinks[7] (ntfy): topic is required",
+			"sinks[8] (gotify): token is required",
+			"sinks[9] (pager): unknown sink type 'pager'",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := valid()
+			tt.update(c)
+			err := c.Validate()
+			if len(tt.errs) == 0 {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("got no error, want %v", tt.errs)
+			}
+			// all errors are reported at once, one per line.
+			lines := strings.Split(err.Error(), "\n")
+			if len(lines) != len(tt.errs) {
+				t.Fatalf("got %d errors, want %d:\n%v", len(lines), len(tt.errs), err)
+			}
+			for i, want := range tt.errs {
+				if !strings.HasPrefix(lines[i], want) {
+					t.Errorf("error %d = %q, want %q", i, lines[i], want)
+				}
+			}
+		})
+	}
+}
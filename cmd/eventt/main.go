@@ -0,0 +1,150 @@
+// Command eventt standalone server receiving Sonarr webhook events and sending them
+// to the sinks (exec, relay, file and notifications) defined in a YAML or TOML config file.
+//
+// Usage:
+//
+//	eventt -config /etc/eventt/eventt.yaml
+//	eventt -config /etc/eventt/eventt.yaml --check-config
+//	eventt register -config /etc/eventt/eventt.yaml
+//	eventt schema -out schema
+//
+// register create or update the webhook connection in Sonarr using the sonarr section
+// of the configuration, see runRegister. schema write the JSON Schema of each event
+// payload, for consumers of the events in other languages.
+//
+// The configuration is reloaded on SIGHUP or when the file changes, filters, sinks,
+// auth, limits and logging are swapped without dropping in-flight requests, if the
+// new configuration is invalid the current one is kept. listen and tls changes
+// require a restart.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"golang.org/x/exp/slog"
+
+	"github.com/k-x7/eventt"
+	"github.com/k-x7/eventt/systemd"
+)
+
+// shutdownTimeout maximum wait for in-flight requests and pending deliveries on exit.
+const shutdownTimeout = 30 * time.Second
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "register" {
+		os.Exit(runRegister(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "schema" {
+		os.Exit(runSchema(os.Args[2:]))
+	}
+
+	configPath := flag.String("config", "eventt.yaml", "path of the configuration file")
+	checkConfig := flag.Bool("check-config", false, "validate the configuration file and exit")
+	watch := flag.Duration("watch", 2*time.Second, "interval to check the configuration file for changes, 0 disable it")
+	flag.Parse()
+
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration: %v\n", err)
+		os.Exit(2)
+	}
+	if *checkConfig {
+		fmt.Printf("%s: configuration is valid\n", *configPath)
+		return
+	}
+
+	slog.SetDefault(newLogger(cfg.Logging))
+	if err := run(*configPath, cfg, *watch); err != nil {
+		slog.Error("eventt stopped", err)
+		os.Exit(1)
+	}
+}
+
+func run(path string, cfg *Config, watch time.Duration) error {
+	inst, err := build(cfg, nil)
+	if err != nil {
+		return err
+	}
+	handler := newReloader(path, inst)
+
+	mode, _ := cfg.socketMode()
+	srv := &eventt.Server{
+		Addr:         cfg.Listen,
+		SocketMode:   mode,
+		Handler:      handler,
+		CertFile:     cfg.TLS.Cert,
+		KeyFile:      cfg.TLS.Key,
+		ClientCAFile: cfg.TLS.ClientCA,
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		slog.Info("listening for Sonarr events", "addr", cfg.Listen, "path", cfg.Path,
+			"tls", cfg.TLS.Cert != "", "mtls", cfg.TLS.ClientCA != "")
+		errc <- srv.ListenAndServe()
+	}()
+
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	defer stopWatch()
+	if watch > 0 {
+		go handler.watch(watchCtx, watch)
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+
+loop:
+	for {
+		select {
+		case err := <-errc:
+			if !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			break loop
+		case sig := <-stop:
+			if sig == syscall.SIGHUP {
+				slog.Info("received SIGHUP, reloading configuration", "path", path)
+				systemd.Notify(systemd.Reloading)
+				handler.reload()
+				systemd.Notify(systemd.Ready)
+				continue
+			}
+			slog.Info("shutting down", "signal", sig.String())
+			break loop
+		}
+	}
+	stopWatch()
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		slog.Error("error shutting down server", err)
+	}
+	handler.shutdown(ctx)
+	return nil
+}
+
+func newLogger(cfg LoggingConfig) *slog.Logger {
+	level := slog.LevelInfo
+	switch cfg.Level {
+	case "debug":
+		level = slog.LevelDebug
+	case "warn":
+		level = slog.LevelWarn
+	case "error":
+		level = slog.LevelError
+	}
+	opts := slog.HandlerOptions{Level: level}
+	if cfg.Format == "json" {
+		return slog.New(opts.NewJSONHandler(os.Stderr))
+	}
+	return slog.New(opts.NewTextHandler(os.Stderr))
+}
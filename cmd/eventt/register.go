@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/k-x7/eventt"
+	"github.com/k-x7/eventt/sonarr"
+)
+
+// runRegister create or update the webhook connection in Sonarr, flags override the
+// sonarr section of the configuration file. the triggers are the filters types, or
+// all events if not set, and the credentials are the auth section.
+func runRegister(args []string) int {
+	fs := flag.NewFlagSet("register", flag.ExitOnError)
+	configPath := fs.String("config", "eventt.yaml", "path of the configuration file")
+	sonarrURL := fs.String("sonarr-url", "", "Sonarr url, default sonarr.url")
+	apiKey := fs.String("api-key", os.Getenv("SONARR_API_KEY"), "Sonarr API key, default sonarr.api_key or $SONARR_API_KEY")
+	webhookURL := fs.String("url", "", "eventt url as reachable from Sonarr, default sonarr.webhook_url")
+	name := fs.String("name", eventt.DefaultWebhookName, "name of the connection in Sonarr")
+	tags := fs.String("tags", "", "comma separated series tags, default sonarr.tags")
+	skipTest := fs.Bool("skip-test", false, "do not send a Test event before saving")
+	fs.Parse(args)
+
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration: %v\n", err)
+		return 2
+	}
+	sc := cfg.Sonarr
+	if *sonarrURL != "" {
+		sc.URL = *sonarrURL
+	}
+	if *apiKey != "" {
+		sc.APIKey = *apiKey
+	}
+	if *webhookURL != "" {
+		sc.WebhookURL = *webhookURL
+	}
+	if *tags != "" {
+		sc.Tags = strings.Split(*tags, ",")
+	}
+	if sc.URL == "" || sc.APIKey == "" || sc.WebhookURL == "" {
+		fmt.Fprintln(os.Stderr, "sonarr url, api key and webhook url are required")
+		return 2
+	}
+
+	types := cfg.Filters.Types
+	if len(types) == 0 {
+		types = []eventt.EventType{
+			eventt.Grab, eventt.Download, eventt.Rename, eventt.EpisodeFileDelete,
+			eventt.SeriesDelete, eventt.Health, eventt.ApplicationUpdate,
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	client := &sonarr.Client{BaseURL: sc.URL, APIKey: sc.APIKey}
+	n, err := (&eventt.SonarrTriggers{}).Register(ctx, client, eventt.Webhook{
+		Name:     *name,
+		URL:      sc.WebhookURL,
+		Username: cfg.Auth.Username,
+		Password: cfg.Auth.Password,
+		Tags:     sc.Tags,
+		Types:    types,
+		SkipTest: *skipTest,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("webhook '%s' (id %d) registered in %s\n", n.Name, n.ID, sc.URL)
+	return 0
+}
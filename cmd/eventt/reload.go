@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/exp/slog"
+)
+
+// reloader http.Handler which serve requests using the current instance, the instance
+// can be replaced at any time without dropping in-flight requests.
+type reloader struct {
+	path    string
+	current atomic.Pointer[instance]
+	// mu serialize reloads.
+	mu sync.Mutex
+}
+
+func newReloader(path string, inst *instance) *reloader {
+	r := &reloader{path: path}
+	r.current.Store(inst)
+	return r
+}
+
+func (r *reloader) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	for {
+		inst := r.current.Load()
+		// the instance may be retired between Load and acquire, then the
+		// new one is already stored.
+		if inst.acquire() {
+			defer inst.release()
+			inst.handler.ServeHTTP(w, req)
+			return
+		}
+	}
+}
+
+// reload load and validate the configuration file, then swap the filters, sinks and
+// auth atomically, if the new configuration is invalid the current one is kept.
+func (r *reloader) reload() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old := r.current.Load()
+	cfg, err := LoadConfig(r.path)
+	if err != nil {
+		slog.Error("configuration not reloaded, keeping the current one", err)
+		return
+	}
+	inst, err := build(cfg, old.triggers.Metrics)
+	if err != nil {
+		slog.Error("configuration not reloaded, keeping the current one", err)
+		return
+	}
+
+	if cfg.Listen != old.cfg.Listen || cfg.TLS != old.cfg.TLS {
+		slog.Warn("listen and tls changes require a restart, they are ignored")
+	}
+	slog.SetDefault(newLogger(cfg.Logging))
+	r.current.Store(inst)
+	slog.Info("configuration reloaded", "sinks", len(cfg.Sinks))
+
+	go func() {
+		<-old.retire()
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		old.close(ctx)
+	}()
+}
+
+// watch poll the configuration file every interval and reload it when it changes,
+// until ctx is done.
+func (r *reloader) watch(ctx context.Context, interval time.Duration) {
+	last, _ := os.Stat(r.path)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		info, err := os.Stat(r.path)
+		if err != nil {
+			continue
+		}
+		if last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size() {
+			continue
+		}
+		last = info
+		slog.Info("configuration file changed, reloading", "path", r.path)
+		r.reload()
+	}
+}
+
+// shutdown retire the current instance and close it when in-flight requests are done.
+func (r *reloader) shutdown(ctx context.Context) {
+	inst := r.current.Load()
+	select {
+	case <-inst.retire():
+	case <-ctx.Done():
+	}
+	inst.close(ctx)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/k-x7/eventt"
+)
+
+// runSchema write <EventType>.json schema files to the output directory.
+func runSchema(args []string) int {
+	fs := flag.NewFlagSet("schema", flag.ExitOnError)
+	out := fs.String("out", "schema", "output directory")
+	fs.Parse(args)
+
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	for _, t := range eventt.SchemaTypes() {
+		b, err := eventt.Schema(t)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		path := filepath.Join(*out, string(t)+".json")
+		if err := os.WriteFile(path, append(b, '\n'), 0o644); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	return 0
+}
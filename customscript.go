@@ -0,0 +1,430 @@
+package eventt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Custom Script event types, they differ from webhook event types only for health events.
+// see: https://github.com/Sonarr/Sonarr/blob/v3.0.9.1549/src/NzbDrone.Core/Notifications/CustomScript/CustomScript.cs
+const customScriptHealth = "HealthIssue"
+
+// Env return the environment variables Sonarr Custom Script connection set for the event,
+// e.g. sonarr_eventtype=Grab and sonarr_series_title=..., so existing scripts can be
+// triggered by webhook events without changes. variables which are not part of the
+// webhook payload, like sonarr_series_genres, are not set and variables of fields
+// only sent by Sonarr v4, like sonarr_episodefile_sourcepath, are empty for v3.
+// lists are joined with "," except titles and paths which are joined with "|" as Sonarr does.
+// see: https://wiki.servarr.com/sonarr/custom-scripts
+func Env(e Event) []string {
+	env := map[string]string{"sonarr_eventtype": string(TypeOf(e))}
+
+	switch e := e.(type) {
+	case GrabEvent:
+		seriesEnv(env, e.Series)
+		episodesEnv(env, "sonarr_release_", e.Episodes)
+		env["sonarr_release_title"] = e.Release.ReleaseTitle
+		env["sonarr_release_indexer"] = e.Release.Indexer
+		env["sonarr_release_size"] = strconv.Itoa(e.Release.Size)
+		env["sonarr_release_quality"] = e.Release.Quality
+		env["sonarr_release_qualityversion"] = strconv.Itoa(e.Release.QualityVersion)
+		env["sonarr_release_releasegroup"] = e.Release.ReleaseGroup
+		env["sonarr_download_client"] = e.DownloadClient
+		env["sonarr_download_client_type"] = e.DownloadClientType
+		env["sonarr_download_id"] = e.DownloadID
+	case DownloadEvent:
+		seriesEnv(env, e.Series)
+		episodesEnv(env, "sonarr_episodefile_", e.Episodes)
+		env["sonarr_isupgrade"] = boolEnv(e.IsUpgrade)
+		env["sonarr_episodefile_id"] = strconv.Itoa(e.EpisodeFile.ID)
+		env["sonarr_episodefile_relativepath"] = e.EpisodeFile.RelativePath
+		env["sonarr_episodefile_path"] = e.EpisodeFile.Path
+		env["sonarr_episodefile_quality"] = e.EpisodeFile.Quality
+		env["sonarr_episodefile_qualityversion"] = strconv.Itoa(e.EpisodeFile.QualityVersion)
+		env["sonarr_episodefile_releasegroup"] = e.EpisodeFile.ReleaseGroup
+		env["sonarr_episodefile_scenename"] = e.EpisodeFile.SceneName
+		env["sonarr_episodefile_sourcepath"] = e.EpisodeFile.SourcePath
+		env["sonarr_episodefile_sourcefolder"] = dirPath(e.EpisodeFile.SourcePath)
+		env["sonarr_download_client"] = e.DownloadClient
+		env["sonarr_download_client_type"] = e.DownloadClientType
+		env["sonarr_download_id"] = e.DownloadID
+		var deletedRelativePaths, deletedPaths []string
+		for _, f := range e.DeletedFiles {
+			deletedRelativePaths = append(deletedRelativePaths, f.RelativePath)
+			deletedPaths = append(deletedPaths, f.Path)
+		}
+		env["sonarr_deletedrelativepaths"] = strings.Join(deletedRelativePaths, "|")
+		env["sonarr_deletedpaths"] = strings.Join(deletedPaths, "|")
+	case RenameEvent:
+		seriesEnv(env, e.Series)
+		var ids, relativePaths, paths, previousRelativePaths, previousPaths []string
+		for _, f := range e.RenamedEpisodeFiles {
+			ids = append(ids, strconv.Itoa(f.ID))
+			relativePaths = append(relativePaths, f.RelativePath)
+			paths = append(paths, joinPath(e.Series.Path, f.RelativePath))
+			previousRelativePaths = append(previousRelativePaths, f.PreviousRelativePath)
+			previousPaths = append(previousPaths, f.PreviousPath)
+		}
+		env["sonarr_episodefile_ids"] = strings.Join(ids, ",")
+		env["sonarr_episodefile_relativepaths"] = strings.Join(relativePaths, "|")
+		env["sonarr_episodefile_paths"] = strings.Join(paths, "|")
+		env["sonarr_episodefile_previousrelativepaths"] = strings.Join(previousRelativePaths, "|")
+		env["sonarr_episodefile_previouspaths"] = strings.Join(previousPaths, "|")
+	case EpisodeFileDeleteEvent:
+		seriesEnv(env, e.Series)
+		episodesEnv(env, "sonarr_episodefile_", e.Episodes)
+		env["sonarr_episodefile_deletereason"] = e.DeleteReason
+		env["sonarr_episodefile_id"] = strconv.Itoa(e.EpisodeFile.ID)
+		env["sonarr_episodefile_relativepath"] = e.EpisodeFile.RelativePath
+		env["sonarr_episodefile_path"] = e.EpisodeFile.Path
+		env["sonarr_episodefile_quality"] = e.EpisodeFile.Quality
+		env["sonarr_episodefile_qualityversion"] = strconv.Itoa(e.EpisodeFile.QualityVersion)
+		env["sonarr_episodefile_releasegroup"] = e.EpisodeFile.ReleaseGroup
+	case SeriesDeleteEvent:
+		seriesEnv(env, e.Series)
+		env["sonarr_series_deletedfiles"] = boolEnv(e.DeletedFiles)
+	case HealthEvent:
+		env["sonarr_eventtype"] = customScriptHealth
+		env["sonarr_health_issue_level"] = e.Level
+		env["sonarr_health_issue_message"] = e.Message
+		env["sonarr_health_issue_type"] = e.Type
+		env["sonarr_health_issue_wiki"] = e.WikiURL
+	case ApplicationUpdateEvent:
+		env["sonarr_update_message"] = e.Message
+		env["sonarr_update_previousversion"] = e.PreviousVersion
+		env["sonarr_update_newversion"] = e.NewVersion
+	case UnknownEvent:
+		if t, ok := e["eventType"].(string); ok {
+			env["sonarr_eventtype"] = t
+		}
+	}
+
+	vars := make([]string, 0, len(env))
+	for k, v := range env {
+		vars = append(vars, k+"="+v)
+	}
+	sort.Strings(vars)
+	return vars
+}
+
+func seriesEnv(env map[string]string, s Series) {
+	env["sonarr_series_id"] = strconv.Itoa(s.ID)
+	env["sonarr_series_title"] = s.Title
+	env["sonarr_series_path"] = s.Path
+	env["sonarr_series_tvdbid"] = strconv.Itoa(s.TvdbID)
+	env["sonarr_series_tvmazeid"] = strconv.Itoa(s.TvMazeID)
+	env["sonarr_series_imdbid"] = s.ImdbID
+	env["sonarr_series_type"] = s.Type
+}
+
+func episodesEnv(env map[string]string, prefix string, episodes []Episode) {
+	var ids, numbers, airDates, airDatesUtc, titles []string
+	for _, ep := range episodes {
+		ids = append(ids, strconv.Itoa(ep.ID))
+		numbers = append(numbers, strconv.Itoa(ep.EpisodeNumber))
+		airDates = append(airDates, ep.AirDate)
+		if ep.AirDateUtc.IsZero() {
+			airDatesUtc = append(airDatesUtc, "")
+		} else {
+			airDatesUtc = append(airDatesUtc, ep.AirDateUtc.UTC().Format(time.RFC3339))
+		}
+		titles = append(titles, ep.Title)
+	}
+	env[prefix+"episodecount"] = strconv.Itoa(len(episodes))
+	if len(episodes) > 0 {
+		env[prefix+"seasonnumber"] = strconv.Itoa(episodes[0].SeasonNumber)
+	}
+	if prefix == "sonarr_episodefile_" {
+		env[prefix+"episodeids"] = strings.Join(ids, ",")
+	}
+	env[prefix+"episodenumbers"] = strings.Join(numbers, ",")
+	env[prefix+"episodeairdates"] = strings.Join(airDates, ",")
+	env[prefix+"episodeairdatesutc"] = strings.Join(airDatesUtc, ",")
+	env[prefix+"episodetitles"] = strings.Join(titles, "|")
+}
+
+// boolEnv format booleans like .NET does.
+func boolEnv(b bool) string {
+	if b {
+		return "True"
+	}
+	return "False"
+}
+
+func joinPath(dir, rel string) string {
+	if dir == "" {
+		return rel
+	}
+	sep := "/"
+	if strings.Contains(dir, `\`) {
+		sep = `\`
+	}
+	return strings.TrimRight(dir, `/\`) + sep + rel
+}
+
+// dirPath directory of a Unix or Windows path, empty if path has no directory.
+func dirPath(path string) string {
+	if i := strings.LastIndexAny(path, `/\`); i >= 0 {
+		return path[:i]
+	}
+	return ""
+}
+
+// ErrNotCustomScript returned by FromEnv when sonarr_eventtype is not set.
+var ErrNotCustomScript = errors.New("sonarr_eventtype is not set, not running as Sonarr Custom Script")
+
+// FromEnv build the typed event from Sonarr Custom Script environment variables, it is
+// the reverse of Env and it allows instances which can only run Custom Scripts to use
+// the same handlers, e.g. eventt.FromEnv(os.Environ()). unknown event types are
+// returned as UnknownEvent with all sonarr_* variables. the Source version of the
+// events is 4 when the variables only set by Sonarr v4 are present, otherwise 3.
+func FromEnv(environ []string) (Event, error) {
+	env := &scriptEnv{vars: make(map[string]string)}
+	for _, kv := range environ {
+		if k, v, ok := strings.Cut(kv, "="); ok && strings.HasPrefix(k, "sonarr_") {
+			env.vars[k] = v
+		}
+	}
+	eventType, ok := env.vars["sonarr_eventtype"]
+	if !ok {
+		return nil, ErrNotCustomScript
+	}
+
+	var e Event
+	switch eventType {
+	case string(Grab):
+		ev := GrabEvent{EventType: eventType, Series: env.series(), Episodes: env.episodes("sonarr_release_")}
+		ev.Release.ReleaseTitle = env.get("sonarr_release_title")
+		ev.Release.Indexer = env.get("sonarr_release_indexer")
+		ev.Release.Size = env.int("sonarr_release_size")
+		ev.Release.Quality = env.get("sonarr_release_quality")
+		ev.Release.QualityVersion = env.int("sonarr_release_qualityversion")
+		ev.Release.ReleaseGroup = env.get("sonarr_release_releasegroup")
+		ev.DownloadClient = env.get("sonarr_download_client")
+		ev.DownloadClientType = env.get("sonarr_download_client_type")
+		ev.DownloadID = env.get("sonarr_download_id")
+		e = ev
+	case string(Download):
+		ev := DownloadEvent{EventType: eventType, Series: env.series(), Episodes: env.episodes("sonarr_episodefile_")}
+		ev.IsUpgrade = env.bool("sonarr_isupgrade")
+		ev.EpisodeFile.ID = env.int("sonarr_episodefile_id")
+		ev.EpisodeFile.RelativePath = env.get("sonarr_episodefile_relativepath")
+		ev.EpisodeFile.Path = env.get("sonarr_episodefile_path")
+		ev.EpisodeFile.Quality = env.get("sonarr_episodefile_quality")
+		ev.EpisodeFile.QualityVersion = env.int("sonarr_episodefile_qualityversion")
+		ev.EpisodeFile.ReleaseGroup = env.get("sonarr_episodefile_releasegroup")
+		ev.EpisodeFile.SceneName = env.get("sonarr_episodefile_scenename")
+		ev.EpisodeFile.SourcePath = env.get("sonarr_episodefile_sourcepath")
+		ev.DownloadClient = env.get("sonarr_download_client")
+		ev.DownloadClientType = env.get("sonarr_download_client_type")
+		ev.DownloadID = env.get("sonarr_download_id")
+		deletedPaths := env.list("sonarr_deletedpaths", "|")
+		for i, relativePath := range env.list("sonarr_deletedrelativepaths", "|") {
+			ev.DeletedFiles = append(ev.DeletedFiles, EpisodeFile{RelativePath: relativePath, Path: item(deletedPaths, i)})
+		}
+		e = ev
+	case string(Rename):
+		ev := RenameEvent{EventType: eventType, Series: env.series()}
+		ids := env.list("sonarr_episodefile_ids", ",")
+		relativePaths := env.list("sonarr_episodefile_relativepaths", "|")
+		previousRelativePaths := env.list("sonarr_episodefile_previousrelativepaths", "|")
+		previousPaths := env.list("sonarr_episodefile_previouspaths", "|")
+		for i, relativePath := range relativePaths {
+			ev.RenamedEpisodeFiles = append(ev.RenamedEpisodeFiles, RenamedEpisodeFile{
+				ID:                   env.atoi("sonarr_episodefile_ids", item(ids, i)),
+				RelativePath:         relativePath,
+				PreviousRelativePath: item(previousRelativePaths, i),
+				PreviousPath:         item(previousPaths, i),
+			})
+		}
+		e = ev
+	case string(EpisodeFileDelete):
+		ev := EpisodeFileDeleteEvent{EventType: eventType, Series: env.series(), Episodes: env.episodes("sonarr_episodefile_")}
+		ev.DeleteReason = env.get("sonarr_episodefile_deletereason")
+		ev.EpisodeFile.ID = env.int("sonarr_episodefile_id")
+		ev.EpisodeFile.RelativePath = env.get("sonarr_episodefile_relativepath")
+		ev.EpisodeFile.Path = env.get("sonarr_episodefile_path")
+		ev.EpisodeFile.Quality = env.get("sonarr_episodefile_quality")
+		ev.EpisodeFile.QualityVersion = env.int("sonarr_episodefile_qualityversion")
+		ev.EpisodeFile.ReleaseGroup = env.get("sonarr_episodefile_releasegroup")
+		e = ev
+	case string(SeriesDelete):
+		ev := SeriesDeleteEvent{EventType: eventType, Series: env.series()}
+		ev.DeletedFiles = env.bool("sonarr_series_deletedfiles")
+		e = ev
+	case customScriptHealth, string(Health):
+		e = HealthEvent{
+			EventType: string(Health),
+			Level:     env.get("sonarr_health_issue_level"),
+			Message:   env.get("sonarr_health_issue_message"),
+			Type:      env.get("sonarr_health_issue_type"),
+			WikiURL:   env.get("sonarr_health_issue_wiki"),
+		}
+	case string(ApplicationUpdate):
+		e = ApplicationUpdateEvent{
+			EventType:       eventType,
+			Message:         env.get("sonarr_update_message"),
+			PreviousVersion: env.get("sonarr_update_previousversion"),
+			NewVersion:      env.get("sonarr_update_newversion"),
+		}
+	case string(Test):
+		e = TestEvent{EventType: eventType}
+	default:
+		ev := UnknownEvent{"eventType": eventType}
+		for k, v := range env.vars {
+			ev[k] = v
+		}
+		e = ev
+	}
+
+	if env.err != nil {
+		return nil, fmt.Errorf("error parsing '%s' event from environment: %w", eventType, env.err)
+	}
+	return withSource(e, Source{Version: env.version()}), nil
+}
+
+// DispatchEnv build the event from Sonarr Custom Script environment variables and
+// dispatch it like Dispatch, it is meant for small binaries used as Custom Script:
+//
+//	func main() {
+//		events := eventt.SonarrTriggers{OnGrab: ...}
+//		if err := events.DispatchEnv(context.Background(), os.Environ()); err != nil {
+//			os.Exit(1)
+//		}
+//	}
+func (s *SonarrTriggers) DispatchEnv(ctx context.Context, environ []string) error {
+	e, err := FromEnv(environ)
+	if err != nil {
+		s.handleErrors(nil, err)
+		s.Metrics.failure(ctx)
+		return err
+	}
+	if name := InstanceFromContext(ctx); name != "" {
+		e = withInstance(e, name)
+	}
+	// sinks receive the event encoded as webhook payload.
+	payload, err := json.Marshal(e)
+	if err != nil {
+		err = fmt.Errorf("error encoding '%s' event: %w", TypeOf(e), err)
+		s.handleErrors(nil, err)
+		s.Metrics.failure(ctx)
+		return err
+	}
+	eventType := string(TypeOf(e))
+	if m, ok := e.(UnknownEvent); ok {
+		eventType, _ = m["eventType"].(string)
+	}
+	_, err = s.deliver(ctx, eventType, e, payload, true)
+	return err
+}
+
+// scriptEnv sonarr_* variables, the first conversion error is kept in err.
+type scriptEnv struct {
+	vars map[string]string
+	err  error
+}
+
+// version guess the Sonarr major version, only Sonarr v4 set the instance name and
+// application url variables.
+func (env *scriptEnv) version() Version {
+	if _, ok := env.vars["sonarr_instancename"]; ok {
+		return Version{Major: 4}
+	}
+	if _, ok := env.vars["sonarr_applicationurl"]; ok {
+		return Version{Major: 4}
+	}
+	return Version{Major: 3}
+}
+
+func (env *scriptEnv) get(key string) string {
+	return env.vars[key]
+}
+
+func (env *scriptEnv) int(key string) int {
+	return env.atoi(key, env.vars[key])
+}
+
+func (env *scriptEnv) atoi(key, value string) int {
+	if value == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil && env.err == nil {
+		env.err = fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return n
+}
+
+func (env *scriptEnv) bool(key string) bool {
+	return strings.EqualFold(env.vars[key], "true")
+}
+
+func (env *scriptEnv) list(key, sep string) []string {
+	if env.vars[key] == "" {
+		return nil
+	}
+	return strings.Split(env.vars[key], sep)
+}
+
+func (env *scriptEnv) series() Series {
+	return Series{
+		ID:       env.int("sonarr_series_id"),
+		Title:    env.get("sonarr_series_title"),
+		Path:     env.get("sonarr_series_path"),
+		TvdbID:   env.int("sonarr_series_tvdbid"),
+		TvMazeID: env.int("sonarr_series_tvmazeid"),
+		ImdbID:   env.get("sonarr_series_imdbid"),
+		Type:     env.get("sonarr_series_type"),
+	}
+}
+
+// airDateLayouts formats used by Sonarr for sonarr_*_episodeairdatesutc, it depends
+// on the culture Sonarr runs with, Env use RFC3339.
+var airDateLayouts = []string{
+	time.RFC3339,
+	"1/2/2006 3:04:05 PM",
+	"2006-01-02 15:04:05",
+	"01/02/2006 15:04:05",
+}
+
+func (env *scriptEnv) episodes(prefix string) []Episode {
+	numbers := env.list(prefix+"episodenumbers", ",")
+	ids := env.list(prefix+"episodeids", ",")
+	titles := env.list(prefix+"episodetitles", "|")
+	airDates := env.list(prefix+"episodeairdates", ",")
+	airDatesUtc := env.list(prefix+"episodeairdatesutc", ",")
+	season := env.int(prefix + "seasonnumber")
+
+	episodes := make([]Episode, 0, len(numbers))
+	for i, number := range numbers {
+		ep := Episode{
+			ID:            env.atoi(prefix+"episodeids", item(ids, i)),
+			EpisodeNumber: env.atoi(prefix+"episodenumbers", number),
+			SeasonNumber:  season,
+			Title:         item(titles, i),
+			AirDate:       item(airDates, i),
+		}
+		for _, layout := range airDateLayouts {
+			if t, err := time.Parse(layout, item(airDatesUtc, i)); err == nil {
+				ep.AirDateUtc = t.UTC()
+				break
+			}
+		}
+		episodes = append(episodes, ep)
+	}
+	return episodes
+}
+
+// item return list[i] or empty string if the list is shorter.
+func item(list []string, i int) string {
+	if i < len(list) {
+		return list[i]
+	}
+	return ""
+}
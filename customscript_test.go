@@ -0,0 +1,228 @@
+package eventt
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// envMap index variables returned by Env by name.
+func envMap(t *testing.T, vars []string) map[string]string {
+	t.Helper()
+	env := make(map[string]string, len(vars))
+	for _, kv := range vars {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			t.Fatalf("invalid variable %q", kv)
+		}
+		env[k] = v
+	}
+	return env
+}
+
+func TestEnv(t *testing.T) {
+	series := Series{ID: 7, Title: "Mob Psycho 100", Path: "/tv/Mob Psycho 100", TvdbID: 301366, Type: SeriesAnime}
+	episodes := Episodes{
+		{ID: 11, SeasonNumber: 3, EpisodeNumber: 1, Title: "Dissolution", AirDate: "2022-10-05", AirDateUtc: time.Date(2022, 10, 5, 14, 0, 0, 0, time.UTC)},
+		{ID: 12, SeasonNumber: 3, EpisodeNumber: 2, Title: "Cowardly"},
+	}
+	grab := GrabEvent{Series: series, Episodes: episodes, DownloadClient: "qBittorrent"}
+	grab.Release.ReleaseTitle = "Mob.Psycho.100.S03E01E02.1080p.WEB-DL-GROUP"
+	grab.Release.Size = 1 << 30
+	download := DownloadEvent{Series: series, Episodes: episodes[:1], IsUpgrade: true}
+	download.EpisodeFile.ID = 3
+	download.EpisodeFile.SourcePath = `C:\downloads\Mob.Psycho.100.S03E01.mkv`
+	download.DeletedFiles = []EpisodeFile{{RelativePath: "a.mkv", Path: "/tv/a.mkv"}, {RelativePath: "b.mkv", Path: "/tv/b.mkv"}}
+	rename := RenameEvent{Series: series, RenamedEpisodeFiles: []RenamedEpisodeFile{
+		{ID: 1, RelativePath: "Season 3/S03E01.mkv", PreviousPath: "/tv/old1.mkv"},
+		{ID: 2, RelativePath: "Season 3/S03E02.mkv", PreviousPath: "/tv/old2.mkv"},
+	}}
+
+	tests := []struct {
+		name  string
+		event Event
+		want  map[string]string
+	}{
+		{"grab", grab, map[string]string{
+			"sonarr_eventtype":                  "Grab",
+			"sonarr_series_id":                  "7",
+			"sonarr_series_title":               "Mob Psycho 100",
+			"sonarr_series_tvdbid":              "301366",
+			"sonarr_series_type":                "anime",
+			"sonarr_release_episodecount":       "2",
+			"sonarr_release_seasonnumber":       "3",
+			"sonarr_release_episodenumbers":     "1,2",
+			"sonarr_release_episodeairdates":    "2022-10-05,",
+			"sonarr_release_episodeairdatesutc": "2022-10-05T14:00:00Z,",
+			"sonarr_release_episodetitles":      "Dissolution|Cowardly",
+			"sonarr_release_title":              "Mob.Psycho.100.S03E01E02.1080p.WEB-DL-GROUP",
+			"sonarr_release_size":               "1073741824",
+			"sonarr_download_client":            "qBittorrent",
+		}},
+		{"download", download, map[string]string{
+			"sonarr_eventtype":                "Download",
+			"sonarr_isupgrade":                "True",
+			"sonarr_episodefile_id":           "3",
+			"sonarr_episodefile_episodeids":   "11",
+			"sonarr_episodefile_sourcefolder": `C:\downloads`,
+			"sonarr_deletedrelativepaths":     "a.mkv|b.mkv",
+			"sonarr_deletedpaths":             "/tv/a.mkv|/tv/b.mkv",
+		}},
+		{"rename", rename, map[string]string{
+			"sonarr_eventtype":                 "Rename",
+			"sonarr_episodefile_ids":           "1,2",
+			"sonarr_episodefile_paths":         "/tv/Mob Psycho 100/Season 3/S03E01.mkv|/tv/Mob Psycho 100/Season 3/S03E02.mkv",
+			"sonarr_episodefile_previouspaths": "/tv/old1.mkv|/tv/old2.mkv",
+			"sonarr_episodefile_relativepaths": "Season 3/S03E01.mkv|Season 3/S03E02.mkv",
+		}},
+		{"series delete", SeriesDeleteEvent{Series: series}, map[string]string{
+			"sonarr_eventtype":           "SeriesDelete",
+			"sonarr_series_deletedfiles": "False",
+		}},
+		{"health", HealthEvent{Level: "warning", Message: "Indexers unavailable", Type: "IndexerStatusCheck"}, map[string]string{
+			"sonarr_eventtype":            "HealthIssue",
+			"sonarr_health_issue_level":   "warning",
+			"sonarr_health_issue_message": "Indexers unavailable",
+			"sonarr_health_issue_type":    "IndexerStatusCheck",
+		}},
+		{"unknown", UnknownEvent{"eventType": "ManualInteractionRequired"}, map[string]string{
+			"sonarr_eventtype": "ManualInteractionRequired",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := envMap(t, Env(tt.event))
+			for k, want := range tt.want {
+				if got, ok := env[k]; !ok || got != want {
+					t.Errorf("%s = %q, want %q", k, got, want)
+				}
+			}
+		})
+	}
+
+	if env := envMap(t, Env(TestEvent{})); len(env) != 1 || env["sonarr_eventtype"] != "Test" {
+		t.Errorf("test event env = %v", env)
+	}
+	if _, ok := envMap(t, Env(download))["sonarr_release_title"]; ok {
+		t.Error("download env has grab variables")
+	}
+}
+
+func TestFromEnvRoundTrip(t *testing.T) {
+	series := Series{ID: 7, Title: "Mob Psycho 100", Path: "/tv/Mob Psycho 100", TvdbID: 301366, TvMazeID: 2731, ImdbID: "tt5897304", Type: SeriesAnime}
+	episodes := Episodes{
+		{ID: 11, SeasonNumber: 3, EpisodeNumber: 1, Title: "Dissolution", AirDate: "2022-10-05", AirDateUtc: time.Date(2022, 10, 5, 14, 0, 0, 0, time.UTC)},
+		{ID: 12, SeasonNumber: 3, EpisodeNumber: 2, Title: "Cowardly", AirDate: "2022-10-12", AirDateUtc: time.Date(2022, 10, 12, 14, 0, 0, 0, time.UTC)},
+	}
+	// release episode ids are not part of the grab variables.
+	released := Episodes{episodes[0], episodes[1]}
+	released[0].ID, released[1].ID = 0, 0
+
+	grab := GrabEvent{EventType: "Grab", Series: series, Episodes: released, DownloadClient: "qBittorrent", DownloadClientType: "qBittorrent", DownloadID: "ABC"}
+	grab.Release.ReleaseTitle = "Mob.Psycho.100.S03E01E02.1080p.WEB-DL-GROUP"
+	grab.Release.Indexer = "Nyaa"
+	grab.Release.Size = 1 << 30
+	grab.Release.Quality = "WEBDL-1080p"
+	grab.Release.QualityVersion = 2
+	grab.Release.ReleaseGroup = "GROUP"
+
+	download := DownloadEvent{EventType: "Download", Series: series, Episodes: episodes, IsUpgrade: true, DownloadClient: "qBittorrent", DownloadID: "ABC"}
+	download.EpisodeFile = EpisodeFile{
+		ID: 3, RelativePath: "Season 3/S03E01.mkv", Path: "/tv/Mob Psycho 100/Season 3/S03E01.mkv",
+		Quality: "WEBDL-1080p", QualityVersion: 1, ReleaseGroup: "GROUP",
+		SceneName: "Mob.Psycho.100.S03E01E02.1080p.WEB-DL-GROUP", SourcePath: "/downloads/Mob.Psycho.100.S03E01E02.mkv",
+	}
+	download.DeletedFiles = []EpisodeFile{{RelativePath: "old.mkv", Path: "/tv/Mob Psycho 100/old.mkv"}}
+
+	fileDelete := EpisodeFileDeleteEvent{EventType: "EpisodeFileDelete", Series: series, Episodes: episodes[:1], DeleteReason: "upgrade"}
+	fileDelete.EpisodeFile = EpisodeFile{ID: 3, RelativePath: "Season 3/S03E01.mkv", Path: "/tv/Mob Psycho 100/Season 3/S03E01.mkv", Quality: "HDTV-720p", QualityVersion: 1, ReleaseGroup: "GROUP"}
+
+	events := []Event{
+		grab,
+		download,
+		RenameEvent{EventType: "Rename", Series: series, RenamedEpisodeFiles: []RenamedEpisodeFile{
+			{ID: 1, RelativePath: "Season 3/S03E01.mkv", PreviousRelativePath: "S03E01.mkv", PreviousPath: "/tv/Mob Psycho 100/S03E01.mkv"},
+			{ID: 2, RelativePath: "Season 3/S03E02.mkv", PreviousRelativePath: "S03E02.mkv", PreviousPath: "/tv/Mob Psycho 100/S03E02.mkv"},
+		}},
+		fileDelete,
+		SeriesDeleteEvent{EventType: "SeriesDelete", Series: series, DeletedFiles: true},
+		HealthEvent{EventType: "Health", Level: "warning", Message: "Indexers unavailable", Type: "IndexerStatusCheck", WikiURL: "https://wiki.servarr.com/sonarr/system#indexers"},
+		ApplicationUpdateEvent{EventType: "ApplicationUpdate", Message: "Sonarr updated", PreviousVersion: "3.0.9.1549", NewVersion: "3.0.10.1567"},
+		TestEvent{EventType: "Test"},
+	}
+	for _, e := range events {
+		t.Run(string(TypeOf(e)), func(t *testing.T) {
+			got, err := FromEnv(Env(e))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := withSource(e, Source{Version: Version{Major: 3}})
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got  %+v\nwant %+v", got, want)
+			}
+		})
+	}
+
+	unknown, err := FromEnv([]string{"sonarr_eventtype=ManualInteractionRequired", "sonarr_download_id=ABC", "PATH=/bin"})
+	if want := (UnknownEvent{"eventType": "ManualInteractionRequired", "sonarr_eventtype": "ManualInteractionRequired", "sonarr_download_id": "ABC"}); err != nil || !reflect.DeepEqual(unknown, want) {
+		t.Errorf("got %v, %v", unknown, err)
+	}
+}
+
+func TestFromEnvVersion(t *testing.T) {
+	for _, tt := range []struct {
+		environ []string
+		want    Version
+	}{
+		{[]string{"sonarr_eventtype=Test"}, Version{Major: 3}},
+		{[]string{"sonarr_eventtype=Test", "sonarr_instancename=Sonarr"}, Version{Major: 4}},
+		{[]string{"sonarr_eventtype=Test", "sonarr_applicationurl="}, Version{Major: 4}},
+	} {
+		e, err := FromEnv(tt.environ)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := e.(TestEvent).Source.Version; got != tt.want {
+			t.Errorf("%v: version %v, want %v", tt.environ, got, tt.want)
+		}
+	}
+	if _, err := FromEnv([]string{"PATH=/bin"}); !errors.Is(err, ErrNotCustomScript) {
+		t.Errorf("got %v, want ErrNotCustomScript", err)
+	}
+	if _, err := FromEnv([]string{"sonarr_eventtype=Grab", "sonarr_release_size=big"}); err == nil {
+		t.Error("invalid size parsed")
+	}
+}
+
+func TestDispatchEnv(t *testing.T) {
+	var (
+		download DownloadEvent
+		unknown  string
+	)
+	s := &SonarrTriggers{
+		OnDownload: func(e DownloadEvent) { download = e },
+		OnUnknown:  func(eventType string, e UnknownEvent) { unknown = eventType },
+	}
+	ctx := WithInstance(context.Background(), "hd")
+	err := s.DispatchEnv(ctx, []string{
+		"sonarr_eventtype=Download",
+		"sonarr_series_title=Mob Psycho 100",
+		"sonarr_episodefile_sourcepath=/downloads/Mob.Psycho.100.S03E01.mkv",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the source path is only in v4 payloads, but the event does not come from one.
+	if download.Series.Title != "Mob Psycho 100" || download.Source.Version != (Version{Major: 3}) || download.InstanceName != "hd" {
+		t.Errorf("download = %+v", download)
+	}
+	if err := s.DispatchEnv(context.Background(), []string{"sonarr_eventtype=ManualInteractionRequired"}); err != nil || unknown != "ManualInteractionRequired" {
+		t.Errorf("unknown event %q, %v", unknown, err)
+	}
+	if err := s.DispatchEnv(context.Background(), nil); !errors.Is(err, ErrNotCustomScript) {
+		t.Errorf("got %v, want ErrNotCustomScript", err)
+	}
+}
@@ -0,0 +1,101 @@
+package eventt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// Dispatch process a webhook payload received outside of Monitor, e.g. from a queue,
+// it invokes the matching trigger and subscriptions exactly like Monitor does, errors
+// are reported to OnError and returned, the http status returned by OnError is ignored.
+func (s *SonarrTriggers) Dispatch(ctx context.Context, payload []byte) error {
+	_, err := s.dispatch(ctx, payload)
+	return err
+}
+
+// Parse decode a webhook payload into its typed event, e.g. GrabEvent for Grab events,
+// events not implemented by this library are returned as UnknownEvent. the Sonarr
+// version is detected from the payload shape, see DetectVersion.
+func Parse(payload []byte) (Event, error) {
+	eventType, err := parseType(payload)
+	if err != nil {
+		return nil, err
+	}
+	return decodeEvent(context.Background(), payload, eventType)
+}
+
+// decodeEvent decode the payload of eventType into its typed event with its Source,
+// and its instance name when ctx has one, see WithInstance.
+func decodeEvent(ctx context.Context, payload []byte, eventType string) (Event, error) {
+	var (
+		e   Event
+		err error
+	)
+	switch EventType(eventType) {
+	case Grab:
+		e, err = asEvent(decode[GrabEvent](payload))
+	case Download:
+		e, err = asEvent(decode[DownloadEvent](payload))
+	case Rename:
+		e, err = asEvent(decode[RenameEvent](payload))
+	case EpisodeFileDelete:
+		e, err = asEvent(decode[EpisodeFileDeleteEvent](payload))
+	case SeriesDelete:
+		e, err = asEvent(decode[SeriesDeleteEvent](payload))
+	case Health:
+		e, err = asEvent(decode[HealthEvent](payload))
+	case ApplicationUpdate:
+		e, err = asEvent(decode[ApplicationUpdateEvent](payload))
+	case Test:
+		e, err = asEvent(decode[TestEvent](payload))
+	default:
+		e, err = asEvent(decode[UnknownEvent](payload))
+	}
+	if err != nil {
+		return nil, err
+	}
+	e = withSource(e, sourceOf(TypeOf(e), userAgentFromContext(ctx), payload))
+	if name := InstanceFromContext(ctx); name != "" {
+		e = withInstance(e, name)
+	}
+	return e, nil
+}
+
+// eventKind event type of the typed event eventType is decoded into, Unknown for
+// event types not implemented by this library.
+func eventKind(eventType string) EventType {
+	if _, ok := eventStructs[EventType(eventType)]; ok {
+		return EventType(eventType)
+	}
+	return Unknown
+}
+
+func parseType(payload []byte) (string, error) {
+	eventType := &WebhookEvent{}
+	if err := json.Unmarshal(payload, eventType); err != nil {
+		return "", fmt.Errorf("error parsing event type: %w", err)
+	}
+	return eventType.EventType, nil
+}
+
+func decode[T Event](payload []byte) (T, error) {
+	var e T
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return e, fmt.Errorf("error parsing '%s' event: %w", e.eventName(), err)
+	}
+	raw := append(json.RawMessage(nil), payload...)
+	if ev, ok := withRaw(e, raw, unknownFields(payload, reflect.TypeOf(e))).(T); ok {
+		e = ev
+	}
+	return e, nil
+}
+
+// asEvent convert decode result to Event, it avoids returning non-nil Event on errors.
+func asEvent[T Event](e T, err error) (Event, error) {
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
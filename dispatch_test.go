@@ -0,0 +1,85 @@
+package eventt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		payload string
+		want    interface{}
+	}{
+		{`{"eventType":"Grab"}`, GrabEvent{}},
+		{`{"eventType":"Download"}`, DownloadEvent{}},
+		{`{"eventType":"Rename"}`, RenameEvent{}},
+		{`{"eventType":"EpisodeFileDelete"}`, EpisodeFileDeleteEvent{}},
+		{`{"eventType":"SeriesDelete"}`, SeriesDeleteEvent{}},
+		{`{"eventType":"Health"}`, HealthEvent{}},
+		{`{"eventType":"ApplicationUpdate"}`, ApplicationUpdateEvent{}},
+		{`{"eventType":"Test"}`, TestEvent{}},
+		{`{"eventType":"ManualInteractionRequired"}`, UnknownEvent{}},
+	}
+	for _, tt := range tests {
+		e, err := Parse([]byte(tt.payload))
+		if err != nil {
+			t.Errorf("%s: %v", tt.payload, err)
+			continue
+		}
+		if reflect.TypeOf(e) != reflect.TypeOf(tt.want) {
+			t.Errorf("%s: got %T, want %T", tt.payload, e, tt.want)
+		}
+	}
+
+	e, err := Parse([]byte(`{"eventType":"Grab","series":{"title":"Mob Psycho 100"},"release":{"quality":"WEBDL-1080p"}}`))
+	if g, ok := e.(GrabEvent); err != nil || !ok || g.Series.Title != "Mob Psycho 100" || g.Release.Quality != "WEBDL-1080p" {
+		t.Errorf("got %+v, %v", e, err)
+	}
+	for _, payload := range []string{`{`, `{"eventType":"Grab","series":[]}`} {
+		if _, err := Parse([]byte(payload)); err == nil {
+			t.Errorf("%s: invalid payload parsed", payload)
+		}
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	var (
+		grab    GrabEvent
+		unknown string
+		errs    []error
+	)
+	s := &SonarrTriggers{
+		OnGrab:    func(e GrabEvent) { grab = e },
+		OnUnknown: func(eventType string, e UnknownEvent) { unknown = eventType },
+		OnError: func(payload []byte, err error) int {
+			errs = append(errs, err)
+			return http.StatusTeapot
+		},
+	}
+
+	ctx := WithUserAgent(WithInstance(context.Background(), "hd"), "Sonarr/3.0.9.1549")
+	if err := s.Dispatch(ctx, []byte(`{"eventType":"Grab","series":{"title":"Mob Psycho 100"}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if grab.Series.Title != "Mob Psycho 100" || grab.InstanceName != "hd" || grab.Source.Version.String() != "3.0.9.1549" {
+		t.Errorf("grab = %+v", grab)
+	}
+	if err := s.Dispatch(context.Background(), []byte(`{"eventType":"ManualInteractionRequired"}`)); err != nil || unknown != "ManualInteractionRequired" {
+		t.Errorf("unknown event %q, %v", unknown, err)
+	}
+	// events without callback are not decoded, so invalid fields are not reported.
+	if err := s.Dispatch(context.Background(), []byte(`{"eventType":"Health","message":1}`)); err != nil {
+		t.Errorf("unhandled event: %v", err)
+	}
+
+	err := s.Dispatch(context.Background(), []byte(`{"eventType":"Grab","series":[]}`))
+	if err == nil || len(errs) != 1 || !errors.Is(errs[0], err) {
+		t.Errorf("got %v, reported %v", err, errs)
+	}
+	if err := s.Dispatch(context.Background(), []byte(`not json`)); err == nil || len(errs) != 2 {
+		t.Errorf("got %v, reported %v", err, errs)
+	}
+}
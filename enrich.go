@@ -0,0 +1,272 @@
+package eventt
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/k-x7/eventt/sonarr"
+)
+
+// DefaultEnrichTTL used when Enricher.TTL is not set.
+const DefaultEnrichTTL = 5 * time.Minute
+
+// Enrichment full series, episodes and tags fetched from Sonarr API for an event,
+// webhook payloads only include few fields of them.
+type Enrichment struct {
+	Series   *sonarr.Series
+	Episodes []sonarr.Episode
+	// Tags of the series.
+	Tags []sonarr.Tag
+}
+
+// Enricher fetch the Enrichment of events from Sonarr API, set it on
+// SonarrTriggers.Enricher to fill the Enrichment field of GrabEvent, DownloadEvent,
+// RenameEvent and EpisodeFileDeleteEvent before they are dispatched:
+//
+//	events := eventt.SonarrTriggers{
+//		Enricher: &eventt.Enricher{
+//			Client: &sonarr.Client{BaseURL: "http://localhost:8989", APIKey: "..."},
+//		},
+//		OnDownload: func(event eventt.DownloadEvent) {
+//			if event.Enrichment != nil {
+//				fmt.Println(event.Enrichment.Series.Network)
+//			}
+//		},
+//	}
+//
+// the responses are cached for TTL, so bursts of events of the same series call the
+// API once. it is safe for concurrent use.
+type Enricher struct {
+	Client *sonarr.Client
+	// TTL how long responses are cached, default DefaultEnrichTTL, negative value
+	// disable the cache.
+	TTL time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// Enrich fetch the series, episodes and tags of e, it returns nil Enrichment for
+// events which are not related to an existing series, e.g. HealthEvent, SeriesDeleteEvent
+// and TestEvent which use fake ids.
+func (en *Enricher) Enrich(ctx context.Context, e Event) (*Enrichment, error) {
+	var seriesID int
+	var episodeIDs []int
+	switch e := e.(type) {
+	case GrabEvent:
+		seriesID, episodeIDs = e.Series.ID, episodeIDsOf(e.Episodes)
+	case DownloadEvent:
+		seriesID, episodeIDs = e.Series.ID, episodeIDsOf(e.Episodes)
+	case RenameEvent:
+		seriesID = e.Series.ID
+	case EpisodeFileDeleteEvent:
+		seriesID, episodeIDs = e.Series.ID, episodeIDsOf(e.Episodes)
+	default:
+		return nil, nil
+	}
+
+	series, err := cached(en, fmt.Sprintf("series/%d", seriesID), func() (*sonarr.Series, error) {
+		return en.Client.Series(ctx, seriesID)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching series %d: %w", seriesID, err)
+	}
+	// cached values are shared by all events, handlers get their own copy.
+	enr := &Enrichment{Series: cloneSeries(series)}
+
+	if len(episodeIDs) > 0 {
+		if enr.Episodes, err = en.episodes(ctx, seriesID, episodeIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(series.Tags) > 0 {
+		tags, err := cached(en, "tags", func() ([]sonarr.Tag, error) {
+			return en.Client.Tags(ctx)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error fetching tags: %w", err)
+		}
+		for _, t := range tags {
+			for _, id := range series.Tags {
+				if t.ID == id {
+					enr.Tags = append(enr.Tags, t)
+				}
+			}
+		}
+	}
+	return enr, nil
+}
+
+// episodes return copies of the episodes of the series with the given ids, the
+// episodes of the series are fetched with a single request and fetched again when
+// an episode is missing from the cache, e.g. an episode added since.
+func (en *Enricher) episodes(ctx context.Context, seriesID int, ids []int) ([]sonarr.Episode, error) {
+	key := fmt.Sprintf("episodes/%d", seriesID)
+	fetch := func() ([]sonarr.Episode, error) {
+		return en.Client.Episodes(ctx, seriesID)
+	}
+	for attempt := 0; ; attempt++ {
+		all, err := cached(en, key, fetch)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching episodes of series %d: %w", seriesID, err)
+		}
+		byID := make(map[int]sonarr.Episode, len(all))
+		for _, ep := range all {
+			byID[ep.ID] = ep
+		}
+		episodes := make([]sonarr.Episode, 0, len(ids))
+		for _, id := range ids {
+			ep, ok := byID[id]
+			if !ok {
+				break
+			}
+			ep.Images = append([]sonarr.Image(nil), ep.Images...)
+			episodes = append(episodes, ep)
+		}
+		switch {
+		case len(episodes) == len(ids):
+			return episodes, nil
+		case attempt > 0:
+			return nil, fmt.Errorf("error fetching episode %d: %w", ids[len(episodes)], sonarr.ErrNotFound)
+		}
+		en.forget(key)
+	}
+}
+
+// Purge remove all cached responses, e.g. after series are edited in Sonarr.
+func (en *Enricher) Purge() {
+	en.mu.Lock()
+	defer en.mu.Unlock()
+	en.cache = nil
+}
+
+// forget remove the cached value of key.
+func (en *Enricher) forget(key string) {
+	en.mu.Lock()
+	defer en.mu.Unlock()
+	delete(en.cache, key)
+}
+
+// cached return the cached value of key or call fetch and cache its result, errors
+// are not cached.
+func cached[T any](en *Enricher, key string, fetch func() (T, error)) (T, error) {
+	ttl := en.TTL
+	if ttl == 0 {
+		ttl = DefaultEnrichTTL
+	}
+
+	now := time.Now()
+	if ttl > 0 {
+		en.mu.Lock()
+		entry, ok := en.cache[key]
+		en.mu.Unlock()
+		if ok && now.Before(entry.expires) {
+			return entry.value.(T), nil
+		}
+	}
+
+	v, err := fetch()
+	if err != nil || ttl < 0 {
+		return v, err
+	}
+
+	en.mu.Lock()
+	defer en.mu.Unlock()
+	if en.cache == nil {
+		en.cache = make(map[string]cacheEntry)
+	}
+	// drop expired entries once the cache grows, deleted series and old episodes
+	// are not requested again.
+	if len(en.cache) >= 1024 {
+		for k, entry := range en.cache {
+			if now.After(entry.expires) {
+				delete(en.cache, k)
+			}
+		}
+	}
+	en.cache[key] = cacheEntry{value: v, expires: now.Add(ttl)}
+	return v, nil
+}
+
+// cloneSeries copy s with its slices and statistics.
+func cloneSeries(s *sonarr.Series) *sonarr.Series {
+	c := *s
+	c.Images = append([]sonarr.Image(nil), s.Images...)
+	c.Genres = append([]string(nil), s.Genres...)
+	c.Tags = append([]int(nil), s.Tags...)
+	c.Statistics = cloneStatistics(s.Statistics)
+	c.Seasons = make([]sonarr.Season, len(s.Seasons))
+	for i, season := range s.Seasons {
+		season.Statistics = cloneStatistics(season.Statistics)
+		c.Seasons[i] = season
+	}
+	return &c
+}
+
+func cloneStatistics(s *sonarr.Statistics) *sonarr.Statistics {
+	if s == nil {
+		return nil
+	}
+	c := *s
+	return &c
+}
+
+func episodeIDsOf(episodes []Episode) []int {
+	ids := make([]int, 0, len(episodes))
+	for _, e := range episodes {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+// withAbsoluteNumbers return a copy of es with the absolute numbers of the enriched episodes.
+func (es Episodes) withAbsoluteNumbers(enriched []sonarr.Episode) Episodes {
+	if len(enriched) == 0 {
+		return es
+	}
+	absolute := make(map[int]int, len(enriched))
+	for _, ep := range enriched {
+		absolute[ep.ID] = ep.AbsoluteEpisodeNumber
+	}
+	out := make(Episodes, len(es))
+	for i, ep := range es {
+		if n, ok := absolute[ep.ID]; ok && n > 0 {
+			ep.AbsoluteEpisodeNumber = n
+		}
+		out[i] = ep
+	}
+	return out
+}
+
+// withEnrichment set the Enrichment field of the typed event e and the absolute
+// episode numbers of its episodes.
+func withEnrichment(e Event, enr *Enrichment) Event {
+	return updateEvent(e, func(f eventFields) {
+		if f.enrichment == nil {
+			return
+		}
+		*f.enrichment = enr
+		if f.episodes != nil {
+			*f.episodes = f.episodes.withAbsoluteNumbers(enr.Episodes)
+		}
+	})
+}
+
+// enrichmentOf return the Enrichment of the typed event e, nil if it is not enriched.
+func enrichmentOf(e Event) *Enrichment {
+	var enr *Enrichment
+	updateEvent(e, func(f eventFields) {
+		if f.enrichment != nil {
+			enr = *f.enrichment
+		}
+	})
+	return enr
+}
@@ -0,0 +1,230 @@
+package eventt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/k-x7/eventt/sonarr"
+)
+
+// fakeSonarr stand-in Sonarr API serving series 1, its episodes and tags, it counts
+// the requests by path.
+type fakeSonarr struct {
+	mu       sync.Mutex
+	requests map[string]int
+	episodes []sonarr.Episode
+}
+
+func newFakeSonarr(t *testing.T) (*fakeSonarr, *sonarr.Client) {
+	t.Helper()
+	f := &fakeSonarr{
+		requests: make(map[string]int),
+		episodes: []sonarr.Episode{
+			{ID: 11, SeriesID: 1, SeasonNumber: 1, EpisodeNumber: 1, AbsoluteEpisodeNumber: 1},
+			{ID: 12, SeriesID: 1, SeasonNumber: 1, EpisodeNumber: 2, AbsoluteEpisodeNumber: 2},
+		},
+	}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, &sonarr.Client{BaseURL: srv.URL, APIKey: "key"}
+}
+
+func (f *fakeSonarr) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	path := r.URL.Path
+	if r.URL.RawQuery != "" {
+		path += "?" + r.URL.RawQuery
+	}
+	f.requests[path]++
+
+	if r.Header.Get("X-Api-Key") != "key" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var v interface{}
+	switch path {
+	case "/api/v3/series/1":
+		v = sonarr.Series{
+			ID: 1, Title: "Mob Psycho 100", SeriesType: "anime", Network: "Tokyo MX",
+			Genres: []string{"Action", "Comedy"}, Tags: []int{2},
+			Seasons: []sonarr.Season{{SeasonNumber: 1, Statistics: &sonarr.Statistics{EpisodeCount: 12}}},
+		}
+	case "/api/v3/episode?seriesId=1":
+		v = f.episodes
+	case "/api/v3/tag":
+		v = []sonarr.Tag{{ID: 1, Label: "hd"}, {ID: 2, Label: "anime"}}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (f *fakeSonarr) count(path string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests[path]
+}
+
+func (f *fakeSonarr) total() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, c := range f.requests {
+		n += c
+	}
+	return n
+}
+
+func downloadEvent(episodeIDs ...int) DownloadEvent {
+	e := DownloadEvent{Series: Series{ID: 1, Title: "Mob Psycho 100", Type: SeriesAnime}}
+	for _, id := range episodeIDs {
+		e.Episodes = append(e.Episodes, Episode{ID: id, SeasonNumber: 1, EpisodeNumber: id - 10})
+	}
+	return e
+}
+
+func TestEnricherEnrich(t *testing.T) {
+	f, client := newFakeSonarr(t)
+	en := &Enricher{Client: client}
+
+	enr, err := en.Enrich(context.Background(), downloadEvent(11, 12))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enr.Series.Network != "Tokyo MX" {
+		t.Errorf("series = %+v", enr.Series)
+	}
+	if len(enr.Episodes) != 2 || enr.Episodes[0].ID != 11 || enr.Episodes[1].AbsoluteEpisodeNumber != 2 {
+		t.Errorf("episodes = %+v", enr.Episodes)
+	}
+	if len(enr.Tags) != 1 || enr.Tags[0].Label != "anime" {
+		t.Errorf("tags = %+v", enr.Tags)
+	}
+	if n := f.count("/api/v3/episode?seriesId=1"); n != 1 {
+		t.Errorf("episodes of the series fetched %d times, want 1", n)
+	}
+	if n := f.total(); n != 3 {
+		t.Errorf("got %d requests, want series, episodes and tags", n)
+	}
+}
+
+func TestEnricherCache(t *testing.T) {
+	f, client := newFakeSonarr(t)
+	en := &Enricher{Client: client, TTL: time.Minute}
+
+	for i := 0; i < 3; i++ {
+		if _, err := en.Enrich(context.Background(), downloadEvent(11, 12)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := f.total(); n != 3 {
+		t.Errorf("got %d requests, want 3 cached requests", n)
+	}
+
+	en.Purge()
+	if _, err := en.Enrich(context.Background(), downloadEvent(11)); err != nil {
+		t.Fatal(err)
+	}
+	if n := f.total(); n != 6 {
+		t.Errorf("got %d requests after Purge, want 6", n)
+	}
+}
+
+func TestEnricherCacheCopies(t *testing.T) {
+	_, client := newFakeSonarr(t)
+	en := &Enricher{Client: client}
+
+	first, err := en.Enrich(context.Background(), downloadEvent(11))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Series.Title = "changed"
+	first.Series.Genres[0] = "changed"
+	first.Series.Seasons[0].Statistics.EpisodeCount = 0
+	first.Episodes[0].Title = "changed"
+
+	second, err := en.Enrich(context.Background(), downloadEvent(11))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Series.Title != "Mob Psycho 100" || second.Series.Genres[0] != "Action" ||
+		second.Series.Seasons[0].Statistics.EpisodeCount != 12 || second.Episodes[0].Title != "" {
+		t.Errorf("changes of an event leaked into the cache: %+v %+v", second.Series, second.Episodes)
+	}
+}
+
+func TestEnricherNewEpisode(t *testing.T) {
+	f, client := newFakeSonarr(t)
+	en := &Enricher{Client: client}
+
+	if _, err := en.Enrich(context.Background(), downloadEvent(11)); err != nil {
+		t.Fatal(err)
+	}
+	f.mu.Lock()
+	f.episodes = append(f.episodes, sonarr.Episode{ID: 13, SeriesID: 1, SeasonNumber: 1, EpisodeNumber: 3})
+	f.mu.Unlock()
+
+	enr, err := en.Enrich(context.Background(), downloadEvent(13))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(enr.Episodes) != 1 || enr.Episodes[0].ID != 13 {
+		t.Errorf("episodes = %+v", enr.Episodes)
+	}
+	if n := f.count("/api/v3/episode?seriesId=1"); n != 2 {
+		t.Errorf("episodes fetched %d times, want 2", n)
+	}
+
+	_, err = en.Enrich(context.Background(), downloadEvent(99))
+	if !errors.Is(err, sonarr.ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound for missing episode", err)
+	}
+}
+
+func TestEnricherErrors(t *testing.T) {
+	_, client := newFakeSonarr(t)
+	en := &Enricher{Client: client}
+
+	e := downloadEvent(11)
+	e.Series.ID = 2
+	if _, err := en.Enrich(context.Background(), e); !errors.Is(err, sonarr.ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound for missing series", err)
+	}
+
+	client.APIKey = "wrong"
+	var apiErr *sonarr.APIError
+	if _, err := (&Enricher{Client: client}).Enrich(context.Background(), downloadEvent(11)); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("got %v, want 401 API error", err)
+	}
+
+	if enr, err := en.Enrich(context.Background(), HealthEvent{}); enr != nil || err != nil {
+		t.Errorf("health events should not be enriched: %v %v", enr, err)
+	}
+}
+
+func TestEnricherTriggers(t *testing.T) {
+	_, client := newFakeSonarr(t)
+	var got DownloadEvent
+	events := &SonarrTriggers{
+		Enricher:   &Enricher{Client: client},
+		OnDownload: func(e DownloadEvent) { got = e },
+	}
+	payload := `{"eventType":"Download","series":{"id":1,"title":"Mob Psycho 100","type":"anime"},"episodes":[{"id":12,"seasonNumber":1,"episodeNumber":2}]}`
+	if err := events.Dispatch(context.Background(), []byte(payload)); err != nil {
+		t.Fatal(err)
+	}
+	if got.Enrichment == nil || got.Enrichment.Series.Network != "Tokyo MX" {
+		t.Fatalf("event not enriched: %+v", got.Enrichment)
+	}
+	if code := got.Episodes.CodeFor(got.Series.Type); code != "002" {
+		t.Errorf("episode code = %s, want absolute number 002", code)
+	}
+}
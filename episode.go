@@ -0,0 +1,115 @@
+package eventt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Series types as sent by Sonarr in Series.Type, they define how episodes are numbered.
+const (
+	SeriesStandard = "standard"
+	SeriesDaily    = "daily"
+	SeriesAnime    = "anime"
+)
+
+// Episodes webhook episodes of an event with numbering helpers.
+type Episodes []Episode
+
+// Code return the standard episode code, e.g. S03E04.
+func (e Episode) Code() string {
+	return fmt.Sprintf("S%02dE%02d", e.SeasonNumber, e.EpisodeNumber)
+}
+
+// CodeFor return the episode code for the series type: the air date for daily series,
+// e.g. 2023-01-02, the absolute number for anime series, e.g. 012, and the standard
+// code otherwise or when the air date or absolute number is not known.
+func (e Episode) CodeFor(seriesType string) string {
+	switch {
+	case seriesType == SeriesDaily && e.AirDate != "":
+		return e.AirDate
+	case seriesType == SeriesAnime && e.AbsoluteEpisodeNumber > 0:
+		return fmt.Sprintf("%03d", e.AbsoluteEpisodeNumber)
+	}
+	return e.Code()
+}
+
+// Code return the standard codes of the episodes sorted and separated by space,
+// consecutive episodes of the same season are compressed, e.g. S03E04-E06 S04E01.
+func (es Episodes) Code() string {
+	return es.CodeFor(SeriesStandard)
+}
+
+// CodeFor same as Code using the numbering of the series type, see Episode.CodeFor:
+// daily episodes are listed by air date and consecutive anime episodes are
+// compressed by absolute number, e.g. 012-014.
+func (es Episodes) CodeFor(seriesType string) string {
+	sorted := es.Sorted()
+	codes := make([]string, 0, len(sorted))
+	for i := 0; i < len(sorted); {
+		first := sorted[i]
+		j := i + 1
+		for j < len(sorted) && consecutive(sorted[j-1], sorted[j], seriesType) {
+			j++
+		}
+		last := sorted[j-1]
+
+		code := first.CodeFor(seriesType)
+		switch {
+		case j-i == 1:
+		case code == first.Code():
+			code += fmt.Sprintf("-E%02d", last.EpisodeNumber)
+		default:
+			code += "-" + last.CodeFor(seriesType)
+		}
+		codes = append(codes, code)
+		i = j
+	}
+	return strings.Join(codes, " ")
+}
+
+// consecutive report whether b directly follows a in the series type numbering.
+func consecutive(a, b Episode, seriesType string) bool {
+	aStandard, bStandard := a.CodeFor(seriesType) == a.Code(), b.CodeFor(seriesType) == b.Code()
+	switch {
+	case aStandard && bStandard:
+		return a.SeasonNumber == b.SeasonNumber && b.EpisodeNumber == a.EpisodeNumber+1
+	case !aStandard && !bStandard && seriesType == SeriesAnime:
+		return b.AbsoluteEpisodeNumber == a.AbsoluteEpisodeNumber+1
+	}
+	// daily episodes or mixed numbering are never compressed.
+	return false
+}
+
+// Sorted return a copy of the episodes sorted by season and episode number.
+func (es Episodes) Sorted() Episodes {
+	sorted := make(Episodes, len(es))
+	copy(sorted, es)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].SeasonNumber != sorted[j].SeasonNumber {
+			return sorted[i].SeasonNumber < sorted[j].SeasonNumber
+		}
+		return sorted[i].EpisodeNumber < sorted[j].EpisodeNumber
+	})
+	return sorted
+}
+
+// BySeason group the episodes by season number, episodes of each season are sorted.
+func (es Episodes) BySeason() map[int]Episodes {
+	seasons := make(map[int]Episodes)
+	for _, ep := range es.Sorted() {
+		seasons[ep.SeasonNumber] = append(seasons[ep.SeasonNumber], ep)
+	}
+	return seasons
+}
+
+// Seasons return the sorted season numbers of the episodes without duplicates.
+func (es Episodes) Seasons() []int {
+	var seasons []int
+	for _, ep := range es.Sorted() {
+		if n := len(seasons); n == 0 || seasons[n-1] != ep.SeasonNumber {
+			seasons = append(seasons, ep.SeasonNumber)
+		}
+	}
+	return seasons
+}
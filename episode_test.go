@@ -0,0 +1,122 @@
+package eventt
+
+import (
+	"reflect"
+	"testing"
+)
+
+// ep return an episode of season s, number n, absolute number abs and air date.
+func ep(s, n, abs int, airDate string) Episode {
+	return Episode{ID: s*100 + n, SeasonNumber: s, EpisodeNumber: n, AbsoluteEpisodeNumber: abs, AirDate: airDate}
+}
+
+func TestEpisodeCodeFor(t *testing.T) {
+	tests := []struct {
+		ep         Episode
+		seriesType string
+		want       string
+	}{
+		{ep(3, 4, 0, ""), SeriesStandard, "S03E04"},
+		{ep(3, 4, 0, ""), "", "S03E04"},
+		{ep(12, 104, 0, ""), SeriesStandard, "S12E104"},
+		{ep(0, 1, 0, ""), SeriesStandard, "S00E01"},
+		{ep(2023, 1, 0, "2023-01-02"), SeriesDaily, "2023-01-02"},
+		{ep(2023, 1, 0, ""), SeriesDaily, "S2023E01"},
+		{ep(2, 1, 12, ""), SeriesAnime, "012"},
+		{ep(20, 1, 1071, ""), SeriesAnime, "1071"},
+		{ep(2, 1, 0, ""), SeriesAnime, "S02E01"},
+		{ep(2, 1, 12, "2023-01-02"), SeriesStandard, "S02E01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.seriesType+"/"+tt.want, func(t *testing.T) {
+			if got := tt.ep.CodeFor(tt.seriesType); got != tt.want {
+				t.Errorf("CodeFor(%q) = %q, want %q", tt.seriesType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEpisodesCodeFor(t *testing.T) {
+	tests := []struct {
+		name       string
+		episodes   Episodes
+		seriesType string
+		want       string
+	}{
+		{"empty", nil, SeriesStandard, ""},
+		{"single", Episodes{ep(3, 4, 0, "")}, SeriesStandard, "S03E04"},
+		{"range", Episodes{ep(3, 4, 0, ""), ep(3, 5, 0, ""), ep(3, 6, 0, "")}, SeriesStandard, "S03E04-E06"},
+		{"unsorted", Episodes{ep(3, 6, 0, ""), ep(3, 4, 0, ""), ep(3, 5, 0, "")}, SeriesStandard, "S03E04-E06"},
+		{"gap", Episodes{ep(3, 4, 0, ""), ep(3, 5, 0, ""), ep(3, 7, 0, "")}, SeriesStandard, "S03E04-E05 S03E07"},
+		{"seasons", Episodes{ep(4, 1, 0, ""), ep(3, 4, 0, ""), ep(3, 5, 0, "")}, SeriesStandard, "S03E04-E05 S04E01"},
+		{"season boundary", Episodes{ep(3, 10, 0, ""), ep(4, 11, 0, "")}, SeriesStandard, "S03E10 S04E11"},
+		{"duplicate", Episodes{ep(1, 1, 0, ""), ep(1, 1, 0, "")}, SeriesStandard, "S01E01 S01E01"},
+		{"daily", Episodes{ep(2023, 2, 0, "2023-01-03"), ep(2023, 1, 0, "2023-01-02")}, SeriesDaily, "2023-01-02 2023-01-03"},
+		{"daily without air date", Episodes{ep(2023, 1, 0, ""), ep(2023, 2, 0, "")}, SeriesDaily, "S2023E01-E02"},
+		{"anime range", Episodes{ep(2, 1, 13, ""), ep(2, 2, 14, ""), ep(1, 12, 12, "")}, SeriesAnime, "012-014"},
+		{"anime gap", Episodes{ep(1, 1, 1, ""), ep(1, 2, 2, ""), ep(1, 4, 4, "")}, SeriesAnime, "001-002 004"},
+		{"anime without absolute", Episodes{ep(1, 1, 0, ""), ep(1, 2, 0, "")}, SeriesAnime, "S01E01-E02"},
+		{"anime mixed", Episodes{ep(1, 1, 1, ""), ep(1, 2, 0, ""), ep(1, 3, 0, "")}, SeriesAnime, "001 S01E02-E03"},
+		{"anime as standard", Episodes{ep(2, 1, 13, ""), ep(2, 2, 14, "")}, SeriesStandard, "S02E01-E02"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.episodes.CodeFor(tt.seriesType); got != tt.want {
+				t.Errorf("CodeFor(%q) = %q, want %q", tt.seriesType, got, tt.want)
+			}
+			if tt.seriesType == SeriesStandard {
+				if got := tt.episodes.Code(); got != tt.want {
+					t.Errorf("Code() = %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestEpisodesSorted(t *testing.T) {
+	es := Episodes{ep(2, 1, 0, ""), ep(1, 2, 0, ""), ep(1, 1, 0, "")}
+	want := Episodes{ep(1, 1, 0, ""), ep(1, 2, 0, ""), ep(2, 1, 0, "")}
+	if got := es.Sorted(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Sorted() = %+v, want %+v", got, want)
+	}
+	if es[0] != ep(2, 1, 0, "") {
+		t.Errorf("Sorted() modified the episodes: %+v", es)
+	}
+}
+
+func TestEpisodesSeasons(t *testing.T) {
+	tests := []struct {
+		name     string
+		episodes Episodes
+		seasons  []int
+		bySeason map[int]Episodes
+	}{
+		{"empty", nil, nil, map[int]Episodes{}},
+		{
+			"single season",
+			Episodes{ep(1, 2, 0, ""), ep(1, 1, 0, "")},
+			[]int{1},
+			map[int]Episodes{1: {ep(1, 1, 0, ""), ep(1, 2, 0, "")}},
+		},
+		{
+			"several seasons",
+			Episodes{ep(3, 1, 0, ""), ep(0, 1, 0, ""), ep(1, 2, 0, ""), ep(3, 2, 0, ""), ep(1, 1, 0, "")},
+			[]int{0, 1, 3},
+			map[int]Episodes{
+				0: {ep(0, 1, 0, "")},
+				1: {ep(1, 1, 0, ""), ep(1, 2, 0, "")},
+				3: {ep(3, 1, 0, ""), ep(3, 2, 0, "")},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.episodes.Seasons(); !reflect.DeepEqual(got, tt.seasons) {
+				t.Errorf("Seasons() = %v, want %v", got, tt.seasons)
+			}
+			if got := tt.episodes.BySeason(); !reflect.DeepEqual(got, tt.bySeason) {
+				t.Errorf("BySeason() = %+v, want %+v", got, tt.bySeason)
+			}
+		})
+	}
+}
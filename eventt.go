@@ -1,14 +1,33 @@
 package eventt
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
+	"sync"
+	"time"
 
 	"golang.org/x/exp/slog"
 )
 
+// DefaultMaxPayloadSize used when SonarrTriggers.MaxPayloadSize is not set, the largest
+// payload sent by Sonarr v3 is EpisodeFileDeleteEvent which include the full series with
+// actors, images and profiles, it rarely exceed few hundreds kilobytes.
+const DefaultMaxPayloadSize = 1 << 20
+
+var (
+	// ErrPayloadTooLarge returned when request body exceed SonarrTriggers.MaxPayloadSize.
+	ErrPayloadTooLarge = errors.New("payload too large")
+	// ErrReadTimeout returned when reading request body takes longer than SonarrTriggers.ReadTimeout.
+	ErrReadTimeout = errors.New("timeout while reading payload")
+	// ErrUnsupportedContentType returned when SonarrTriggers.RequireJSON is set and
+	// the request content type is not JSON.
+	ErrUnsupportedContentType = errors.New("unsupported content type")
+)
+
 // SonarrTriggers sonarr events or triggers using webhook connection
 // see: https://wiki.servarr.com/sonarr/settings#connection-triggers
 type SonarrTriggers struct {
@@ -39,99 +58,318 @@ type SonarrTriggers struct {
 	// LogOnError should we log errors, if true it will use slog.Error to log errors and
 	// it will include the payload. see SonarrTriggers.handleErrors for more details.
 	LogOnError bool
-	b          []byte
+	// MaxPayloadSize maximum size in bytes of the request body, bigger requests will be
+	// rejected with 413 and reported to OnError with ErrPayloadTooLarge.
+	// if zero DefaultMaxPayloadSize is used, negative value disable the limit.
+	MaxPayloadSize int64
+	// ReadTimeout maximum duration to read the request body, slow requests will be
+	// rejected with 408 and reported to OnError with ErrReadTimeout. zero means no timeout.
+	ReadTimeout time.Duration
+	// RequireJSON reject requests with content type other than application/json with 415
+	// and report it to OnError with ErrUnsupportedContentType.
+	RequireJSON bool
+	// ValidateSchema check payloads against the event schema before they are decoded,
+	// invalid payloads are reported to OnError with *ValidationError listing every
+	// failure path. see Schema.
+	ValidateSchema bool
+	// Access optional source allow list and per IP rate limiting, it is checked by
+	// Monitor before reading the request body.
+	Access *AccessControl
+	// Metrics optional counters for processed, failed and rejected requests.
+	Metrics *Metrics
+	// Enricher optional, fetch the full series, episodes and tags from Sonarr API before
+	// the event is dispatched. if it fails the event is dispatched without Enrichment
+	// and the error is reported to OnError, the returned status is ignored.
+	Enricher *Enricher
+	// Sinks receive every event after the On... callbacks, errors returned by sinks
+	// are reported to OnError.
+	Sinks []Sink
+
+	mu     sync.RWMutex
+	subs   map[*subscription]struct{}
+	checks map[string]HealthCheck
+	closed bool
 }
 
 // Monitor http handler to invoke the correct trigger from SonarrTriggers based on
 // the received event from Sonarr, see SonarrTriggers all the events and error handling.
 func (s *SonarrTriggers) Monitor(w http.ResponseWriter, r *http.Request) {
-	var err error
+	payload, ok := s.receive(w, r)
+	if !ok {
+		return
+	}
+	status, _ := s.dispatch(WithUserAgent(r.Context(), r.UserAgent()), payload)
+	w.WriteHeader(status)
+}
+
+// receive check Access and RequireJSON and read the request body, if the request is
+// rejected the response is written and it returns false.
+func (s *SonarrTriggers) receive(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	ctx := r.Context()
+	if !s.allow(w, r) {
+		return nil, false
+	}
 
-	s.b, err = io.ReadAll(r.Body)
+	if s.RequireJSON && !isJSON(r.Header.Get("Content-Type")) {
+		s.handleErrors(
+			nil, fmt.Errorf("%w: '%s'", ErrUnsupportedContentType, r.Header.Get("Content-Type")),
+		)
+		s.Metrics.reject(ctx, RejectUnsupportedContent)
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return nil, false
+	}
+
+	payload, err := s.readBody(w, r)
 	if err != nil {
 		status := s.handleErrors(
-			fmt.Errorf("error while reading request body %w", err),
+			payload, fmt.Errorf("error while reading request body %w", err),
 		)
+		switch {
+		case errors.Is(err, ErrPayloadTooLarge):
+			s.Metrics.reject(ctx, RejectPayloadTooLarge)
+			status = http.StatusRequestEntityTooLarge
+		case errors.Is(err, ErrReadTimeout):
+			s.Metrics.reject(ctx, RejectReadTimeout)
+			status = http.StatusRequestTimeout
+		default:
+			s.Metrics.failure(ctx)
+		}
 		w.WriteHeader(status)
-		return
+		return nil, false
 	}
 	r.Body.Close()
+	return payload, true
+}
 
-	eventType := &WebhookEvent{}
+// allow check Access, if the request is rejected the response is written and it
+// returns false.
+func (s *SonarrTriggers) allow(w http.ResponseWriter, r *http.Request) bool {
+	if s.Access == nil {
+		return true
+	}
+	aerr := s.Access.check(r)
+	if aerr == nil {
+		return true
+	}
+	s.handleErrors(nil, aerr)
+	switch {
+	case errors.Is(aerr, ErrForbidden):
+		s.Metrics.reject(r.Context(), RejectForbidden)
+	case errors.Is(aerr, ErrRateLimited):
+		s.Metrics.reject(r.Context(), RejectRateLimited)
+	default:
+		s.Metrics.failure(r.Context())
+	}
+	aerr.reject(w)
+	return false
+}
 
-	if err := json.Unmarshal(s.b, eventType); err != nil {
-		status := s.handleErrors(
-			fmt.Errorf("error parsing event type: %w", err),
-		)
-		w.WriteHeader(status)
-		return
+// readBody read the request body respecting MaxPayloadSize and ReadTimeout.
+func (s *SonarrTriggers) readBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	limit := s.MaxPayloadSize
+	if limit == 0 {
+		limit = DefaultMaxPayloadSize
+	}
+	body := r.Body
+	if limit > 0 {
+		body = http.MaxBytesReader(w, r.Body, limit)
 	}
 
-	if err := s.handleEvent(eventType.EventType); err != nil {
-		status := s.handleErrors(
-			fmt.Errorf("error handle '%s' event: %w", eventType.EventType, err),
-		)
-		w.WriteHeader(status)
-		return
+	type result struct {
+		b   []byte
+		err error
+	}
+	read := func() result {
+		b, err := io.ReadAll(body)
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			err = fmt.Errorf("%w: limit is %d bytes", ErrPayloadTooLarge, maxErr.Limit)
+		}
+		return result{b, err}
 	}
 
-	w.WriteHeader(http.StatusOK)
+	if s.ReadTimeout <= 0 {
+		res := read()
+		return res.b, res.err
+	}
+
+	// the reader goroutine will be released when the server close the body
+	// after Monitor returns.
+	done := make(chan result, 1)
+	go func() { done <- read() }()
+
+	timer := time.NewTimer(s.ReadTimeout)
+	defer timer.Stop()
+
+	select {
+	case res := <-done:
+		return res.b, res.err
+	case <-timer.C:
+		return nil, fmt.Errorf("%w: exceeded %s", ErrReadTimeout, s.ReadTimeout)
+	case <-r.Context().Done():
+		return nil, r.Context().Err()
+	}
 }
 
-func (s *SonarrTriggers) handleEvent(eventType string) error {
-	switch eventType {
-	case grab:
-		return handleGenericEvent(s.b, s.OnGrab)
-	case download:
-		return handleGenericEvent(s.b, s.OnDownload)
-	case rename:
-		return handleGenericEvent(s.b, s.OnRename)
-	case episodeFileDelete:
-		return handleGenericEvent(s.b, s.OnEpisodeFileDelete)
-	case seriesDelete:
-		return handleGenericEvent(s.b, s.OnSeriesDelete)
-	case health:
-		return handleGenericEvent(s.b, s.OnHealth)
-	case applicationUpdate:
-		return handleGenericEvent(s.b, s.OnApplicationUpdate)
-	case test:
-		return handleGenericEvent(s.b, s.OnTest)
-	default:
-		return s.handleUnknown(eventType)
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json"
+}
+
+// dispatch decode the payload and invoke the matching trigger, errors are reported
+// to OnError and it returns the http status to reply with.
+func (s *SonarrTriggers) dispatch(ctx context.Context, payload []byte) (int, error) {
+	eventType, e, status, err := s.decode(ctx, payload, s.handles)
+	if err != nil {
+		return status, err
+	}
+	return s.deliver(ctx, eventType, e, payload, true)
+}
+
+// decode parse the event type, validate the payload and decode it into its typed
+// event when wanted report the event is handled, otherwise the event is nil.
+// errors are reported to OnError and counted in Metrics.
+func (s *SonarrTriggers) decode(ctx context.Context, payload []byte, wanted func(EventType) bool) (string, Event, int, error) {
+	eventType, err := parseType(payload)
+	if err != nil {
+		status := s.handleErrors(payload, err)
+		s.Metrics.failure(ctx)
+		return "", nil, status, err
+	}
+
+	if s.ValidateSchema {
+		if err := ValidatePayload(payload); err != nil {
+			status := s.handleErrors(payload, err)
+			s.Metrics.failure(ctx)
+			return "", nil, status, err
+		}
+	}
+
+	if !wanted(eventKind(eventType)) {
+		return eventType, nil, http.StatusOK, nil
+	}
+	e, err := decodeEvent(ctx, payload, eventType)
+	if err != nil {
+		err = fmt.Errorf("error handle '%s' event: %w", eventType, err)
+		status := s.handleErrors(payload, err)
+		s.Metrics.failure(ctx)
+		return "", nil, status, err
+	}
+	return eventType, e, http.StatusOK, nil
+}
+
+// deliver invoke the trigger, subscriptions and sinks of the decoded event e if they
+// handle it, count report whether the event is counted in Metrics.
+func (s *SonarrTriggers) deliver(ctx context.Context, eventType string, e Event, payload []byte, count bool) (int, error) {
+	if e != nil && s.handles(TypeOf(e)) {
+		if err := s.handleEvent(ctx, e, payload); err != nil {
+			err = fmt.Errorf("error handle '%s' event: %w", eventType, err)
+			status := s.handleErrors(payload, err)
+			if count {
+				s.Metrics.failure(ctx)
+			}
+			return status, err
+		}
+	}
+	if count {
+		s.Metrics.event(ctx, eventType)
+	}
+	return http.StatusOK, nil
+}
+
+func (s *SonarrTriggers) handleEvent(ctx context.Context, e Event, payload []byte) error {
+	switch e := e.(type) {
+	case GrabEvent:
+		return handleGenericEvent(ctx, s, e, payload, s.OnGrab)
+	case DownloadEvent:
+		return handleGenericEvent(ctx, s, e, payload, s.OnDownload)
+	case RenameEvent:
+		return handleGenericEvent(ctx, s, e, payload, s.OnRename)
+	case EpisodeFileDeleteEvent:
+		return handleGenericEvent(ctx, s, e, payload, s.OnEpisodeFileDelete)
+	case SeriesDeleteEvent:
+		return handleGenericEvent(ctx, s, e, payload, s.OnSeriesDelete)
+	case HealthEvent:
+		return handleGenericEvent(ctx, s, e, payload, s.OnHealth)
+	case ApplicationUpdateEvent:
+		return handleGenericEvent(ctx, s, e, payload, s.OnApplicationUpdate)
+	case TestEvent:
+		return handleGenericEvent(ctx, s, e, payload, s.OnTest)
+	case UnknownEvent:
+		return s.handleUnknown(ctx, e, payload)
 	}
+	return nil
 }
 
-func (s *SonarrTriggers) handleErrors(err error) int {
+func (s *SonarrTriggers) handleErrors(payload []byte, err error) int {
 	status := http.StatusBadRequest
 	if s.OnError != nil {
-		status = s.OnError(s.b, err)
+		status = s.OnError(payload, err)
 	}
 	if s.LogOnError {
-		slog.Error("error processing new event", err, "payload", string(s.b))
+		slog.Error("error processing new event", err, "payload", string(payload))
 	}
 	return status
 }
 
-func handleGenericEvent[T eventType](b []byte, f func(e T)) error {
-	if f == nil {
-		return nil
+func handleGenericEvent[T Event](ctx context.Context, s *SonarrTriggers, e T, payload []byte, f func(e T)) error {
+	if s.Enricher != nil && enrichmentOf(e) == nil {
+		enr, err := s.Enricher.Enrich(ctx, e)
+		if err != nil {
+			s.handleErrors(payload, fmt.Errorf("error enriching '%s' event: %w", e.eventName(), err))
+		}
+		if enr != nil {
+			e = withEnrichment(e, enr).(T)
+		}
 	}
-	var e T
-	if err := json.Unmarshal(b, &e); err != nil {
-		return fmt.Errorf("error parsing '%s' event: %w", e.eventName(), err)
+	if f != nil {
+		f(e)
 	}
-	f(e)
-	return nil
+	s.publish(ctx, e)
+	return s.send(ctx, e, payload)
+}
+
+func (s *SonarrTriggers) handleUnknown(ctx context.Context, m UnknownEvent, payload []byte) error {
+	if s.OnUnknown != nil {
+		eventType, _ := m["eventType"].(string)
+		s.OnUnknown(eventType, m)
+	}
+	s.publish(ctx, m)
+	return s.send(ctx, m, payload)
 }
 
-func (s *SonarrTriggers) handleUnknown(eventType string) error {
-	if s.OnUnknown == nil {
-		return nil
+// handles report whether events of type t are handled by a trigger, a sink or a
+// subscription, other events are not decoded.
+func (s *SonarrTriggers) handles(t EventType) bool {
+	if s.wants(t) {
+		return true
 	}
-	m := make(UnknownEvent)
-	if err := json.Unmarshal(s.b, &m); err != nil {
-		return fmt.Errorf("error parsing 'Unknown' event: %w", err)
+	switch t {
+	case Grab:
+		return s.OnGrab != nil
+	case Download:
+		return s.OnDownload != nil
+	case Rename:
+		return s.OnRename != nil
+	case EpisodeFileDelete:
+		return s.OnEpisodeFileDelete != nil
+	case SeriesDelete:
+		return s.OnSeriesDelete != nil
+	case Health:
+		return s.OnHealth != nil
+	case ApplicationUpdate:
+		return s.OnApplicationUpdate != nil
+	case Test:
+		return s.OnTest != nil
 	}
-	s.OnUnknown(eventType, m)
-	return nil
+	return s.OnUnknown != nil
+}
+
+// wants report whether events of type t should be decoded even without callback,
+// because there are sinks or subscriptions for them.
+func (s *SonarrTriggers) wants(t EventType) bool {
+	return len(s.Sinks) > 0 || s.subscribed(t)
 }
@@ -3,3 +3,7 @@ module github.com/k-x7/eventt
 go 1.19
 
 require golang.org/x/exp v0.0.0-20221217163422-3c43f8badb15
+
+require gopkg.in/yaml.v3 v3.0.1
+
+require github.com/BurntSushi/toml v1.2.1
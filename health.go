@@ -0,0 +1,126 @@
+package eventt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultCheckTimeout maximum duration of each readiness check.
+const DefaultCheckTimeout = 5 * time.Second
+
+// HealthCheck report whether a subsystem is able to process events, it returns
+// nil when it is ready.
+type HealthCheck func(ctx context.Context) error
+
+// HealthChecker implemented by sinks which can report their health, e.g. journal.File
+// and relay.Relay, they are checked by Readyz without being registered.
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
+
+// Readiness result of SonarrTriggers.Ready.
+type Readiness struct {
+	// Ready true when all the checks passed.
+	Ready bool `json:"ready"`
+	// LastEvent time of the last successfully processed event, zero if none or
+	// Metrics is not set.
+	LastEvent time.Time `json:"last_event"`
+	// QueueDepth number of events waiting in subscriptions buffers.
+	QueueDepth int `json:"queue_depth"`
+	// Checks result of each check by name, empty when passed.
+	Checks map[string]string `json:"checks"`
+}
+
+// RegisterCheck add a readiness check, e.g. a database used by the handlers, a check
+// with the same name is replaced.
+func (s *SonarrTriggers) RegisterCheck(name string, check HealthCheck) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.checks == nil {
+		s.checks = make(map[string]HealthCheck)
+	}
+	s.checks[name] = check
+}
+
+// Ready run the registered checks and the sinks implementing HealthChecker concurrently,
+// each with DefaultCheckTimeout. it is not ready once Close is called.
+func (s *SonarrTriggers) Ready(ctx context.Context) Readiness {
+	checks := make(map[string]HealthCheck)
+	s.mu.RLock()
+	closed := s.closed
+	for name, check := range s.checks {
+		checks[name] = check
+	}
+	depth := 0
+	for sub := range s.subs {
+		depth += len(sub.ch)
+	}
+	s.mu.RUnlock()
+	for i, sink := range s.Sinks {
+		if hc, ok := sink.(HealthChecker); ok {
+			checks[fmt.Sprintf("sink %d (%T)", i, sink)] = hc.HealthCheck
+		}
+	}
+
+	res := Readiness{
+		Ready:      !closed,
+		LastEvent:  s.Metrics.lastEventTime(),
+		QueueDepth: depth,
+		Checks:     make(map[string]string, len(checks)),
+	}
+	if closed {
+		res.Checks["triggers"] = "closed"
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check HealthCheck) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, DefaultCheckTimeout)
+			defer cancel()
+			err := check(ctx)
+
+			mu.Lock()
+			defer mu.Unlock()
+			res.Checks[name] = ""
+			if err != nil {
+				res.Checks[name] = err.Error()
+				res.Ready = false
+			}
+		}(name, check)
+	}
+	wg.Wait()
+	return res
+}
+
+// Healthz liveness http handler, it returns 200 while the triggers are not closed,
+// use Readyz to know whether events can be processed.
+func (s *SonarrTriggers) Healthz(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	closed := s.closed
+	s.mu.RUnlock()
+	if closed {
+		http.Error(w, "closed", http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+// Readyz readiness http handler, it returns Ready result as JSON with 200 when ready
+// and 503 otherwise:
+//
+//	{"ready":false,"last_event":"...","queue_depth":0,"checks":{"sink 0 (*journal.File)":"journal: permission denied"}}
+func (s *SonarrTriggers) Readyz(w http.ResponseWriter, r *http.Request) {
+	res := s.Ready(r.Context())
+	w.Header().Set("Content-Type", "application/json")
+	if !res.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,115 @@
+package eventt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// checkedSink sink implementing HealthChecker.
+type checkedSink struct {
+	err error
+}
+
+func (s checkedSink) Send(ctx context.Context, event Event, payload []byte) error { return nil }
+
+func (s checkedSink) HealthCheck(ctx context.Context) error { return s.err }
+
+func TestReady(t *testing.T) {
+	s := &SonarrTriggers{
+		Sinks:   []Sink{checkedSink{}, SinkFunc(func(context.Context, Event, []byte) error { return nil })},
+		Metrics: &Metrics{},
+	}
+	sub := s.Subscribe(context.Background(), nil, 4)
+	dispatchAll(t, s, `{"eventType":"Test"}`, `{"eventType":"Test"}`)
+
+	s.RegisterCheck("db", func(ctx context.Context) error { return errors.New("db down") })
+	s.RegisterCheck("db", func(ctx context.Context) error { return nil })
+	res := s.Ready(context.Background())
+	want := map[string]string{"db": "", "sink 0 (eventt.checkedSink)": ""}
+	if !res.Ready || res.QueueDepth != 2 || res.LastEvent.IsZero() || len(res.Checks) != len(want) {
+		t.Fatalf("got %+v", res)
+	}
+	for name, msg := range want {
+		if got, ok := res.Checks[name]; !ok || got != msg {
+			t.Errorf("check %q = %q, %v", name, got, ok)
+		}
+	}
+	receiveAll(sub)
+
+	// checks are bounded by ctx, a check which hang fails.
+	s.RegisterCheck("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	s.Sinks[0] = checkedSink{err: errors.New("disk full")}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	res = s.Ready(ctx)
+	if res.Ready || res.QueueDepth != 0 || res.Checks["slow"] != context.DeadlineExceeded.Error() ||
+		res.Checks["sink 0 (eventt.checkedSink)"] != "disk full" || res.Checks["db"] != "" {
+		t.Errorf("got %+v", res)
+	}
+
+	s.RegisterCheck("slow", func(ctx context.Context) error { return nil })
+	s.Close()
+	if res := (&SonarrTriggers{}).Ready(context.Background()); !res.Ready || !res.LastEvent.IsZero() {
+		t.Errorf("triggers without checks and metrics: %+v", res)
+	}
+	if res := s.Ready(context.Background()); res.Ready || res.Checks["triggers"] != "closed" {
+		t.Errorf("closed triggers: %+v", res)
+	}
+}
+
+func TestHealthzReadyz(t *testing.T) {
+	s := &SonarrTriggers{}
+	failing := false
+	s.RegisterCheck("db", func(ctx context.Context) error {
+		if failing {
+			return errors.New("db down")
+		}
+		return nil
+	})
+	get := func(handler http.HandlerFunc) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		return w
+	}
+	readyz := func(status int) Readiness {
+		t.Helper()
+		w := get(s.Readyz)
+		if w.Code != status || w.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("readyz %d %q, want %d", w.Code, w.Header().Get("Content-Type"), status)
+		}
+		var res Readiness
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	if w := get(s.Healthz); w.Code != http.StatusOK || w.Body.String() != "ok\n" {
+		t.Errorf("healthz %d %q", w.Code, w.Body)
+	}
+	if res := readyz(http.StatusOK); !res.Ready {
+		t.Errorf("got %+v", res)
+	}
+	failing = true
+	if res := readyz(http.StatusServiceUnavailable); res.Ready || res.Checks["db"] != "db down" {
+		t.Errorf("got %+v", res)
+	}
+	// a failing check does not affect liveness.
+	if w := get(s.Healthz); w.Code != http.StatusOK {
+		t.Errorf("healthz %d with failing check", w.Code)
+	}
+
+	s.Close()
+	if w := get(s.Healthz); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("healthz %d after Close", w.Code)
+	}
+	readyz(http.StatusServiceUnavailable)
+}
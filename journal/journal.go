@@ -0,0 +1,113 @@
+// Package journal eventt sink which append every received event to a file as JSON lines,
+// useful for archiving and replaying Sonarr events.
+package journal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/k-x7/eventt"
+)
+
+// Entry single line of the journal file.
+type Entry struct {
+	// Received time when the event was written.
+	Received time.Time `json:"received"`
+	// EventType event type of the payload.
+	EventType eventt.EventType `json:"eventType"`
+	// Payload original webhook payload as received from Sonarr.
+	Payload json.RawMessage `json:"payload"`
+}
+
+// File eventt.Sink which append each event to Path as a JSON line, see Entry.
+// the file is created if it does not exist and opened on first use.
+type File struct {
+	// Path of the journal file.
+	Path string
+	// Types events to write, empty means all events.
+	Types eventt.TypeFilter
+	// Sync flush the file to disk after each event.
+	Sync bool
+
+	mu sync.Mutex
+	f  *os.File
+}
+
+// Send implements eventt.Sink.
+func (j *File) Send(ctx context.Context, event eventt.Event, payload []byte) error {
+	if !j.Types.Match(eventt.TypeOf(event)) {
+		return nil
+	}
+
+	entry := Entry{Received: time.Now().UTC(), EventType: eventt.TypeOf(event), Payload: payload}
+	if !json.Valid(payload) {
+		// events built from environment or by other means may not have a payload.
+		b, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("journal: error encoding event: %w", err)
+		}
+		entry.Payload = b
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("journal: error encoding entry: %w", err)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.f == nil {
+		if j.f, err = os.OpenFile(j.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644); err != nil {
+			return fmt.Errorf("journal: %w", err)
+		}
+	}
+	if _, err := j.f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("journal: %w", err)
+	}
+	if j.Sync {
+		if err := j.f.Sync(); err != nil {
+			return fmt.Errorf("journal: %w", err)
+		}
+	}
+	return nil
+}
+
+// HealthCheck implements eventt.HealthChecker, it reports whether the journal file
+// can be opened for writing and it was not moved or removed while open.
+func (j *File) HealthCheck(ctx context.Context) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.f != nil {
+		open, err := j.f.Stat()
+		if err != nil {
+			return fmt.Errorf("journal: %w", err)
+		}
+		current, err := os.Stat(j.Path)
+		if err != nil || !os.SameFile(open, current) {
+			return fmt.Errorf("journal: '%s' was moved or removed, Close to reopen it", j.Path)
+		}
+		return nil
+	}
+	f, err := os.OpenFile(j.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("journal: %w", err)
+	}
+	j.f = f
+	return nil
+}
+
+// Close close the journal file, it will be reopened by the next Send.
+func (j *File) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.f == nil {
+		return nil
+	}
+	err := j.f.Close()
+	j.f = nil
+	return err
+}
@@ -0,0 +1,87 @@
+package journal
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/k-x7/eventt"
+)
+
+func TestFileSend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	j := &File{Path: path, Types: eventt.Types(eventt.Grab, eventt.Test), Sync: true}
+	defer j.Close()
+
+	sends := []struct {
+		event   eventt.Event
+		payload string
+	}{
+		{eventt.GrabEvent{}, `{"eventType":"Grab"}`},
+		{eventt.HealthEvent{}, `{"eventType":"Health"}`},
+		// events without payload are encoded.
+		{eventt.TestEvent{EventType: "Test"}, ``},
+	}
+	for _, s := range sends {
+		if err := j.Send(context.Background(), s.event, []byte(s.payload)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var entries []Entry
+	for scanner := bufio.NewScanner(f); scanner.Scan(); {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) != 2 || entries[0].EventType != eventt.Grab || string(entries[0].Payload) != `{"eventType":"Grab"}` ||
+		entries[1].EventType != eventt.Test || entries[0].Received.IsZero() {
+		t.Fatalf("entries = %+v", entries)
+	}
+	if e, err := eventt.Parse(entries[1].Payload); err != nil || eventt.TypeOf(e) != eventt.Test {
+		t.Errorf("encoded payload %s: %v", entries[1].Payload, err)
+	}
+}
+
+func TestFileHealthCheck(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "events.jsonl")
+	j := &File{Path: path}
+	defer j.Close()
+
+	if err := j.HealthCheck(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("journal file not created: %v", err)
+	}
+
+	// a rotated file is reported until the journal is reopened.
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.HealthCheck(context.Background()); err == nil {
+		t.Error("moved file reported healthy")
+	}
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.HealthCheck(context.Background()); err != nil {
+		t.Errorf("reopened journal: %v", err)
+	}
+
+	missing := &File{Path: filepath.Join(dir, "missing", "events.jsonl")}
+	if err := missing.HealthCheck(context.Background()); err == nil {
+		t.Error("journal in a missing directory reported healthy")
+	}
+}
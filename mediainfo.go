@@ -0,0 +1,231 @@
+package eventt
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HDRKind HDR format of a video as named in release names.
+type HDRKind string
+
+// HDR formats, HDRNone for SDR videos.
+const (
+	HDRNone     HDRKind = ""
+	HDR10       HDRKind = "HDR10"
+	HDR10Plus   HDRKind = "HDR10+"
+	DolbyVision HDRKind = "DV"
+	HLG         HDRKind = "HLG"
+	// HDRPQ PQ transfer without HDR10 metadata.
+	HDRPQ HDRKind = "PQ"
+)
+
+// hdrKinds HDR kinds of the Sonarr video dynamic range types.
+var hdrKinds = map[string]HDRKind{
+	"DV":        DolbyVision,
+	"HDR10":     HDR10,
+	"HDR10Plus": HDR10Plus,
+	"HLG":       HLG,
+	"PQ":        HDRPQ,
+}
+
+// Runtime return the video run time, 0 if it is not known.
+func (m MediaInfo) Runtime() time.Duration {
+	if m.RunTime == "" {
+		return 0
+	}
+	parts := strings.Split(m.RunTime, ":")
+	if len(parts) > 3 {
+		return 0
+	}
+	var d time.Duration
+	for i, p := range parts {
+		unit := time.Duration(1)
+		for j := i; j < len(parts)-1; j++ {
+			unit *= 60
+		}
+		n, err := strconv.ParseFloat(p, 64)
+		if err != nil || n < 0 {
+			return 0
+		}
+		d += time.Duration(n * float64(unit*time.Second))
+	}
+	return d.Round(time.Millisecond)
+}
+
+// Bitrate bitrate in bits per second.
+type Bitrate int
+
+// Kbps return the bitrate in kilobits per second.
+func (b Bitrate) Kbps() float64 {
+	return float64(b) / 1000
+}
+
+// Mbps return the bitrate in megabits per second.
+func (b Bitrate) Mbps() float64 {
+	return float64(b) / 1000000
+}
+
+// String return the bitrate in the largest unit, e.g. "4.5 Mbps" or "640 kbps", empty
+// if it is not known.
+func (b Bitrate) String() string {
+	switch {
+	case b <= 0:
+		return ""
+	case b >= 1000000:
+		return formatRate(b.Mbps()) + " Mbps"
+	case b >= 1000:
+		return formatRate(b.Kbps()) + " kbps"
+	}
+	return strconv.Itoa(int(b)) + " bps"
+}
+
+// formatRate format n with at most one decimal, e.g. 4.5 or 640.
+func formatRate(n float64) string {
+	return strconv.FormatFloat(math.Round(n*10)/10, 'f', -1, 64)
+}
+
+// VideoRate return the video bitrate, 0 if it is not known, it is only sent by Sonarr v3.
+func (m MediaInfo) VideoRate() Bitrate {
+	return Bitrate(m.VideoBitrate)
+}
+
+// AudioRate return the audio bitrate, 0 if it is not known, it is only sent by Sonarr v3.
+func (m MediaInfo) AudioRate() Bitrate {
+	return Bitrate(m.AudioBitrate)
+}
+
+// HDR return the main HDR format of the video, Dolby Vision when the video has a
+// fallback layer, e.g. "DV HDR10", see HDRFormats.
+func (m MediaInfo) HDR() HDRKind {
+	if formats := m.HDRFormats(); len(formats) > 0 {
+		return formats[0]
+	}
+	return HDRNone
+}
+
+// HDRFormats return all HDR formats of the video, e.g. [DV HDR10], nil for SDR videos.
+func (m MediaInfo) HDRFormats() []HDRKind {
+	var formats []HDRKind
+	for _, t := range strings.Fields(m.VideoDynamicRangeType) {
+		if kind, ok := hdrKinds[t]; ok {
+			formats = append(formats, kind)
+		}
+	}
+	return formats
+}
+
+// IsHDR report whether the video has a high dynamic range.
+func (m MediaInfo) IsHDR() bool {
+	return m.VideoDynamicRange == "HDR" || m.HDR() != HDRNone
+}
+
+// ChannelLayout return the audio channel layout, e.g. "5.1" or "2.0", empty if unknown.
+func (m MediaInfo) ChannelLayout() string {
+	if m.AudioChannels <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%.1f", m.AudioChannels)
+}
+
+// ResolutionClass return the resolution of the video as used by qualities, e.g. 1080
+// for 1920x800, 0 if unknown.
+func (m MediaInfo) ResolutionClass() int {
+	w, h := m.Width, m.Height
+	switch {
+	case w <= 0 && h <= 0:
+		return 0
+	case w >= 3200 || h >= 2100:
+		return 2160
+	case w >= 1800 || h >= 1000:
+		return 1080
+	case w >= 1200 || h >= 700:
+		return 720
+	case w >= 1000 || h >= 560:
+		return 576
+	}
+	return 480
+}
+
+// AudioLanguageCodes return the ISO 639-1 codes of the audio languages, e.g. "en".
+func (m MediaInfo) AudioLanguageCodes() []string {
+	return languageCodes(m.AudioLanguages)
+}
+
+// SubtitleCodes return the ISO 639-1 codes of the subtitles languages.
+func (m MediaInfo) SubtitleCodes() []string {
+	return languageCodes(m.Subtitles)
+}
+
+// languageCodes convert language names sent by Sonarr v3, e.g. "English", and ISO 639-2
+// codes sent by v4, e.g. "eng", to ISO 639-1 codes, duplicates are removed and unknown
+// languages are kept lower cased.
+func languageCodes(langs []string) []string {
+	var codes []string
+	for _, l := range langs {
+		l = strings.ToLower(strings.TrimSpace(l))
+		if l == "" {
+			continue
+		}
+		code, ok := languages[l]
+		if !ok {
+			code = l
+		}
+		if !containsString(codes, code) {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+// languages ISO 639-1 codes by lower cased language name and ISO 639-2 codes, both
+// bibliographic and terminology ones.
+var languages = func() map[string]string {
+	m := make(map[string]string)
+	for _, l := range []struct {
+		code  string
+		names []string
+	}{
+		{"ar", []string{"arabic", "ara"}},
+		{"bg", []string{"bulgarian", "bul"}},
+		{"cs", []string{"czech", "cze", "ces"}},
+		{"da", []string{"danish", "dan"}},
+		{"de", []string{"german", "ger", "deu"}},
+		{"el", []string{"greek", "gre", "ell"}},
+		{"en", []string{"english", "eng"}},
+		{"es", []string{"spanish", "spa"}},
+		{"fa", []string{"persian", "per", "fas"}},
+		{"fi", []string{"finnish", "fin"}},
+		{"fr", []string{"french", "fre", "fra"}},
+		{"he", []string{"hebrew", "heb"}},
+		{"hi", []string{"hindi", "hin"}},
+		{"hr", []string{"croatian", "hrv"}},
+		{"hu", []string{"hungarian", "hun"}},
+		{"id", []string{"indonesian", "ind"}},
+		{"is", []string{"icelandic", "ice", "isl"}},
+		{"it", []string{"italian", "ita"}},
+		{"ja", []string{"japanese", "jpn"}},
+		{"ko", []string{"korean", "kor"}},
+		{"lt", []string{"lithuanian", "lit"}},
+		{"nl", []string{"dutch", "flemish", "dut", "nld"}},
+		{"no", []string{"norwegian", "nor", "nob"}},
+		{"pl", []string{"polish", "pol"}},
+		{"pt", []string{"portuguese", "por"}},
+		{"ro", []string{"romanian", "rum", "ron"}},
+		{"ru", []string{"russian", "rus"}},
+		{"sk", []string{"slovak", "slo", "slk"}},
+		{"sv", []string{"swedish", "swe"}},
+		{"th", []string{"thai", "tha"}},
+		{"tr", []string{"turkish", "tur"}},
+		{"uk", []string{"ukrainian", "ukr"}},
+		{"vi", []string{"vietnamese", "vie"}},
+		{"zh", []string{"chinese", "chi", "zho"}},
+	} {
+		for _, name := range l.names {
+			m[name] = l.code
+		}
+	}
+	return m
+}()
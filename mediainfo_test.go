@@ -0,0 +1,207 @@
+package eventt
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMediaInfoBitrates(t *testing.T) {
+	tests := []struct {
+		video, audio int
+		want         [2]string
+	}{
+		{4534567, 640000, [2]string{"4.5 Mbps", "640 kbps"}},
+		{12000000, 1509000, [2]string{"12 Mbps", "1.5 Mbps"}},
+		{0, 96, [2]string{"", "96 bps"}},
+	}
+	for _, tt := range tests {
+		m := MediaInfo{VideoBitrate: tt.video, AudioBitrate: tt.audio}
+		if got := [2]string{m.VideoRate().String(), m.AudioRate().String()}; got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+		if m.VideoRate().Kbps() != float64(tt.video)/1000 {
+			t.Errorf("Kbps() = %v", m.VideoRate().Kbps())
+		}
+	}
+}
+
+func TestMediaInfoRuntime(t *testing.T) {
+	tests := []struct {
+		runTime string
+		want    time.Duration
+	}{
+		{"", 0},
+		{"00:42:15.123", 42*time.Minute + 15123*time.Millisecond},
+		{"1:02:03", time.Hour + 2*time.Minute + 3*time.Second},
+		{"42:15", 42*time.Minute + 15*time.Second},
+		{"90", 90 * time.Second},
+		{"00:00:01.5", 1500 * time.Millisecond},
+		{"1:02:03:04", 0},
+		{"00:aa:15", 0},
+		{"-1:00", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.runTime, func(t *testing.T) {
+			if got := (MediaInfo{RunTime: tt.runTime}).Runtime(); got != tt.want {
+				t.Errorf("Runtime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMediaInfoHDR(t *testing.T) {
+	tests := []struct {
+		dynamicRange, rangeType string
+		hdr                     HDRKind
+		formats                 []HDRKind
+		isHDR                   bool
+	}{
+		{"", "", HDRNone, nil, false},
+		{"SDR", "SDR", HDRNone, nil, false},
+		{"HDR", "HDR10", HDR10, []HDRKind{HDR10}, true},
+		{"HDR", "HDR10Plus", HDR10Plus, []HDRKind{HDR10Plus}, true},
+		{"HDR", "DV HDR10", DolbyVision, []HDRKind{DolbyVision, HDR10}, true},
+		{"HDR", "DV HDR10Plus", DolbyVision, []HDRKind{DolbyVision, HDR10Plus}, true},
+		{"HDR", "DV", DolbyVision, []HDRKind{DolbyVision}, true},
+		{"HDR", "HLG", HLG, []HDRKind{HLG}, true},
+		{"HDR", "PQ", HDRPQ, []HDRKind{HDRPQ}, true},
+		{"HDR", "", HDRNone, nil, true},
+		{"", "HDR10", HDR10, []HDRKind{HDR10}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dynamicRange+"/"+tt.rangeType, func(t *testing.T) {
+			m := MediaInfo{VideoDynamicRange: tt.dynamicRange, VideoDynamicRangeType: tt.rangeType}
+			if got := m.HDR(); got != tt.hdr {
+				t.Errorf("HDR() = %q, want %q", got, tt.hdr)
+			}
+			if got := m.HDRFormats(); !reflect.DeepEqual(got, tt.formats) {
+				t.Errorf("HDRFormats() = %q, want %q", got, tt.formats)
+			}
+			if got := m.IsHDR(); got != tt.isHDR {
+				t.Errorf("IsHDR() = %v, want %v", got, tt.isHDR)
+			}
+		})
+	}
+}
+
+func TestMediaInfoChannelLayout(t *testing.T) {
+	tests := []struct {
+		channels float64
+		want     string
+	}{
+		{0, ""},
+		{-1, ""},
+		{1, "1.0"},
+		{2, "2.0"},
+		{5.1, "5.1"},
+		{7.1, "7.1"},
+	}
+	for _, tt := range tests {
+		if got := (MediaInfo{AudioChannels: tt.channels}).ChannelLayout(); got != tt.want {
+			t.Errorf("ChannelLayout() of %v = %q, want %q", tt.channels, got, tt.want)
+		}
+	}
+}
+
+func TestMediaInfoResolutionClass(t *testing.T) {
+	tests := []struct {
+		width, height int
+		want          int
+	}{
+		{0, 0, 0},
+		{3840, 2160, 2160},
+		{3996, 1604, 2160},
+		{1920, 1080, 1080},
+		{1920, 800, 1080},
+		{1440, 1080, 1080},
+		{0, 1080, 1080},
+		{1280, 720, 720},
+		{1280, 536, 720},
+		{1024, 576, 576},
+		{720, 576, 576},
+		{720, 480, 480},
+		{640, 360, 480},
+	}
+	for _, tt := range tests {
+		if got := (MediaInfo{Width: tt.width, Height: tt.height}).ResolutionClass(); got != tt.want {
+			t.Errorf("ResolutionClass() of %dx%d = %d, want %d", tt.width, tt.height, got, tt.want)
+		}
+	}
+}
+
+func TestMediaInfoLanguageCodes(t *testing.T) {
+	tests := []struct {
+		name  string
+		langs []string
+		want  []string
+	}{
+		{"empty", nil, nil},
+		{"v3 names", []string{"English", "Japanese"}, []string{"en", "ja"}},
+		{"v4 codes", []string{"eng", "jpn", "spa"}, []string{"en", "ja", "es"}},
+		{"bibliographic and terminology", []string{"fre", "fra", "ger", "deu", "chi", "zho"}, []string{"fr", "de", "zh"}},
+		{"duplicates", []string{"English", "eng", "ENG"}, []string{"en"}},
+		{"spaces and case", []string{" French ", "DUTCH", "Flemish"}, []string{"fr", "nl"}},
+		{"unknown", []string{"Klingon", "", "und"}, []string{"klingon", "und"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := MediaInfo{AudioLanguages: tt.langs, Subtitles: tt.langs}
+			if got := m.AudioLanguageCodes(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AudioLanguageCodes() = %q, want %q", got, tt.want)
+			}
+			if got := m.SubtitleCodes(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SubtitleCodes() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestV3AudioChannels(t *testing.T) {
+	tests := []struct {
+		positions string
+		container int
+		want      float64
+	}{
+		{"3/2/0.1", 6, 5.1},
+		{"3/2/2.1", 8, 7.1},
+		{"2/0/0", 2, 2},
+		{"3/2/0.1, Front: L C R, Side: L R, LFE", 6, 5.1},
+		{"Front: L C R, Side: L R, LFE", 6, 5.1},
+		{"", 8, 7.1},
+		{"", 6, 5.1},
+		{"", 2, 2},
+		{"", 1, 1},
+		{"", 0, 0},
+	}
+	for _, tt := range tests {
+		if got := v3AudioChannels(tt.positions, tt.container); got != tt.want {
+			t.Errorf("v3AudioChannels(%q, %d) = %v, want %v", tt.positions, tt.container, got, tt.want)
+		}
+	}
+}
+
+func TestV3DynamicRangeType(t *testing.T) {
+	tests := []struct {
+		format, compatibility, transfer string
+		want                            string
+	}{
+		{"", "", "", ""},
+		{"", "", "BT.709", ""},
+		{"SMPTE ST 2086", "HDR10", "PQ", "HDR10"},
+		{"SMPTE ST 2094 App 4", "HDR10+ Profile A", "PQ", "HDR10Plus"},
+		{"Dolby Vision / SMPTE ST 2086", "HDR10", "PQ", "DV HDR10"},
+		{"Dolby Vision / SMPTE ST 2094 App 4", "HDR10+ Profile B", "PQ", "DV HDR10Plus"},
+		{"Dolby Vision", "", "PQ", "DV"},
+		{"Dolby Vision", "HLG", "HLG", "DV HLG"},
+		{"", "", "HLG", "HLG"},
+		{"", "", "arib-std-b67", "HLG"},
+		{"", "", "PQ", "PQ"},
+		{"", "", "smpte2084", "PQ"},
+	}
+	for _, tt := range tests {
+		if got := v3DynamicRangeType(tt.format, tt.compatibility, tt.transfer); got != tt.want {
+			t.Errorf("v3DynamicRangeType(%q, %q, %q) = %q, want %q", tt.format, tt.compatibility, tt.transfer, got, tt.want)
+		}
+	}
+}
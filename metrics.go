@@ -0,0 +1,235 @@
+package eventt
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rejection reasons recorded in Metrics.
+const (
+	RejectForbidden          = "forbidden"
+	RejectRateLimited        = "rate_limited"
+	RejectPayloadTooLarge    = "payload_too_large"
+	RejectReadTimeout        = "read_timeout"
+	RejectUnsupportedContent = "unsupported_content_type"
+)
+
+// Metrics counters for processed, failed and rejected webhook requests, set it on
+// SonarrTriggers.Metrics to collect them. it is safe for concurrent use and it
+// implements http.Handler to expose the counters in Prometheus text format.
+// counters of requests received through a Mux are kept by instance name.
+type Metrics struct {
+	mu        sync.Mutex
+	instances map[string]*counters
+}
+
+type counters struct {
+	events     map[string]uint64
+	failures   uint64
+	rejections map[string]uint64
+	lastEvent  time.Time
+}
+
+// MetricsSnapshot point in time copy of Metrics counters.
+type MetricsSnapshot struct {
+	// Events number of successfully processed events by event type.
+	Events map[string]uint64
+	// Failures number of requests failed to be processed.
+	Failures uint64
+	// Rejections number of rejected requests by reason, see Reject* constants.
+	Rejections map[string]uint64
+	// LastEvent time of the last successfully processed event.
+	LastEvent time.Time
+	// Instances counters by Mux instance name, requests received outside of a Mux
+	// are counted under the empty name. the counters above are the totals of all
+	// instances, it is nil in the instances snapshots.
+	Instances map[string]MetricsSnapshot
+}
+
+// Snapshot return a copy of the current counters.
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	total := MetricsSnapshot{
+		Events:     make(map[string]uint64),
+		Rejections: make(map[string]uint64),
+		Instances:  make(map[string]MetricsSnapshot, len(m.instances)),
+	}
+	for name, c := range m.instances {
+		snap := c.snapshot()
+		total.Instances[name] = snap
+		for k, v := range snap.Events {
+			total.Events[k] += v
+		}
+		for k, v := range snap.Rejections {
+			total.Rejections[k] += v
+		}
+		total.Failures += snap.Failures
+		if snap.LastEvent.After(total.LastEvent) {
+			total.LastEvent = snap.LastEvent
+		}
+	}
+	return total
+}
+
+func (c *counters) snapshot() MetricsSnapshot {
+	snap := MetricsSnapshot{
+		Events:     make(map[string]uint64, len(c.events)),
+		Failures:   c.failures,
+		Rejections: make(map[string]uint64, len(c.rejections)),
+		LastEvent:  c.lastEvent,
+	}
+	for k, v := range c.events {
+		snap.Events[k] = v
+	}
+	for k, v := range c.rejections {
+		snap.Rejections[k] = v
+	}
+	return snap
+}
+
+// ServeHTTP write the counters in Prometheus text format, counters of Mux instances
+// have an instance label.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	snap := m.Snapshot()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	names := make([]string, 0, len(snap.Instances))
+	for name := range snap.Instances {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "# TYPE eventt_events_total counter")
+	for _, name := range names {
+		events := snap.Instances[name].Events
+		for _, k := range sortedKeys(events) {
+			fmt.Fprintf(w, "eventt_events_total{%stype=%s} %d\n", instanceLabel(name), labelValue(k), events[k])
+		}
+	}
+	fmt.Fprintln(w, "# TYPE eventt_failures_total counter")
+	if len(names) == 0 {
+		fmt.Fprintln(w, "eventt_failures_total 0")
+	}
+	for _, name := range names {
+		if name == "" {
+			fmt.Fprintf(w, "eventt_failures_total %d\n", snap.Instances[name].Failures)
+			continue
+		}
+		fmt.Fprintf(w, "eventt_failures_total{instance=%s} %d\n", labelValue(name), snap.Instances[name].Failures)
+	}
+	fmt.Fprintln(w, "# TYPE eventt_rejections_total counter")
+	for _, name := range names {
+		rejections := snap.Instances[name].Rejections
+		for _, k := range sortedKeys(rejections) {
+			fmt.Fprintf(w, "eventt_rejections_total{%sreason=%s} %d\n", instanceLabel(name), labelValue(k), rejections[k])
+		}
+	}
+	if !snap.LastEvent.IsZero() {
+		fmt.Fprintln(w, "# TYPE eventt_last_event_timestamp_seconds gauge")
+		for _, name := range names {
+			last := snap.Instances[name].LastEvent
+			if last.IsZero() {
+				continue
+			}
+			if name == "" {
+				fmt.Fprintf(w, "eventt_last_event_timestamp_seconds %d\n", last.Unix())
+				continue
+			}
+			fmt.Fprintf(w, "eventt_last_event_timestamp_seconds{instance=%s} %d\n", labelValue(name), last.Unix())
+		}
+	}
+}
+
+func instanceLabel(name string) string {
+	if name == "" {
+		return ""
+	}
+	return "instance=" + labelValue(name) + ","
+}
+
+// labelEscaper escape label values as defined by the Prometheus text format.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// labelValue return s quoted as a Prometheus label value.
+func labelValue(s string) string {
+	return `"` + labelEscaper.Replace(s) + `"`
+}
+
+// the following methods are no-op on nil Metrics, so SonarrTriggers can call
+// them without checking if metrics are enabled. the instance name is taken from ctx.
+
+// event count an event of eventType, types not known by this library are counted as
+// Unknown so payloads can't create unbounded labels.
+func (m *Metrics) event(ctx context.Context, eventType string) {
+	if m == nil {
+		return
+	}
+	eventType = string(eventKind(eventType))
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c := m.counters(ctx)
+	if c.events == nil {
+		c.events = make(map[string]uint64)
+	}
+	c.events[eventType]++
+	c.lastEvent = time.Now()
+}
+
+func (m *Metrics) failure(ctx context.Context) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counters(ctx).failures++
+}
+
+func (m *Metrics) reject(ctx context.Context, reason string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c := m.counters(ctx)
+	if c.rejections == nil {
+		c.rejections = make(map[string]uint64)
+	}
+	c.rejections[reason]++
+}
+
+func (m *Metrics) lastEventTime() time.Time {
+	if m == nil {
+		return time.Time{}
+	}
+	return m.Snapshot().LastEvent
+}
+
+// counters return the counters of ctx instance, m.mu must be held.
+func (m *Metrics) counters(ctx context.Context) *counters {
+	name := InstanceFromContext(ctx)
+	if m.instances == nil {
+		m.instances = make(map[string]*counters)
+	}
+	c, ok := m.instances[name]
+	if !ok {
+		c = &counters{}
+		m.instances[name] = c
+	}
+	return c
+}
+
+func sortedKeys(m map[string]uint64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,54 @@
+package eventt
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsUnknownEventTypes(t *testing.T) {
+	metrics := &Metrics{}
+	events := &SonarrTriggers{Metrics: metrics, OnGrab: func(GrabEvent) {}}
+	for i := 0; i < 5; i++ {
+		payload := fmt.Sprintf(`{"eventType":"Random%d"}`, i)
+		w := httptest.NewRecorder()
+		events.Monitor(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(payload)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d", w.Code)
+		}
+	}
+	w := httptest.NewRecorder()
+	events.Monitor(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"eventType":"Grab"}`)))
+
+	snap := metrics.Snapshot()
+	if len(snap.Events) != 2 || snap.Events[string(Unknown)] != 5 || snap.Events[string(Grab)] != 1 {
+		t.Errorf("events = %v, want 5 Unknown and 1 Grab", snap.Events)
+	}
+}
+
+func TestMetricsLabelEscaping(t *testing.T) {
+	metrics := &Metrics{}
+	name := "a\"b\\c\nd\té"
+	ctx := WithInstance(context.Background(), name)
+	metrics.event(ctx, string(Grab))
+	metrics.failure(ctx)
+	metrics.reject(ctx, RejectForbidden)
+
+	w := httptest.NewRecorder()
+	metrics.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+	label := `instance="a\"b\\c\nd` + "\t" + `é"`
+	for _, want := range []string{
+		`eventt_events_total{` + label + `,type="Grab"} 1`,
+		`eventt_failures_total{` + label + `} 1`,
+		`eventt_rejections_total{` + label + `,reason="forbidden"} 1`,
+		`eventt_last_event_timestamp_seconds{` + label + `} `,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("missing %q in:\n%s", want, body)
+		}
+	}
+}
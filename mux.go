@@ -0,0 +1,181 @@
+package eventt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrUnknownInstance returned when Mux receive a request for an instance which is not
+// in Mux.Instances.
+var ErrUnknownInstance = errors.New("unknown instance")
+
+// DefaultMuxPrefix used when Mux.Prefix is not set.
+const DefaultMuxPrefix = "/events/"
+
+// Mux serve several Sonarr instances, e.g. HD, 4K and anime, from the same server.
+// each instance has its own SonarrTriggers mounted at Prefix + name:
+//
+//	mux := &eventt.Mux{
+//		Instances: map[string]*eventt.SonarrTriggers{
+//			"hd": {OnDownload: ...},
+//			"4k": {OnDownload: ...},
+//		},
+//		Global: &eventt.SonarrTriggers{OnHealth: ..., Metrics: metrics},
+//	}
+//	http.Handle("/events/", mux)
+//	http.Handle("/events", mux)
+//
+// requests to Prefix without name, e.g. /events, are routed by the instanceName field
+// sent by Sonarr v4, it matches the Instance Name set in Sonarr general settings.
+//
+// the instance name is set in the events InstanceName field and it is available to
+// sinks with InstanceFromContext, Metrics count the requests by instance.
+type Mux struct {
+	// Instances triggers by instance name, names must not contain '/'.
+	Instances map[string]*SonarrTriggers
+	// Prefix path where instances are mounted, default DefaultMuxPrefix.
+	Prefix string
+	// Global optional triggers invoked for every event of every instance after the
+	// instance triggers, the event is decoded once with the instance settings. its
+	// Access and limits are used to read the requests routed by instanceName, if nil
+	// the defaults of SonarrTriggers are used.
+	Global *SonarrTriggers
+}
+
+// ServeHTTP route the request to the instance triggers, see Mux.
+func (m *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	prefix := m.Prefix
+	if prefix == "" {
+		prefix = DefaultMuxPrefix
+	}
+	prefix = strings.TrimSuffix(prefix, "/")
+
+	name := strings.TrimPrefix(r.URL.Path, prefix)
+	if !strings.HasPrefix(r.URL.Path, prefix) || (name != "" && name[0] != '/') {
+		http.NotFound(w, r)
+		return
+	}
+	name = strings.Trim(name, "/")
+	if strings.Contains(name, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	if name == "" {
+		m.serveByInstanceName(w, r)
+		return
+	}
+
+	s, ok := m.Instances[name]
+	if !ok {
+		g := m.global()
+		g.handleErrors(nil, fmt.Errorf("%w: '%s'", ErrUnknownInstance, name))
+		g.Metrics.failure(r.Context())
+		http.NotFound(w, r)
+		return
+	}
+	r = r.WithContext(WithInstance(r.Context(), name))
+	payload, ok := s.receive(w, r)
+	if !ok {
+		return
+	}
+	w.WriteHeader(m.dispatch(WithUserAgent(r.Context(), r.UserAgent()), s, payload))
+}
+
+// serveByInstanceName read the request with Global limits and route it using the
+// instanceName field of the payload.
+func (m *Mux) serveByInstanceName(w http.ResponseWriter, r *http.Request) {
+	g := m.global()
+	payload, ok := g.receive(w, r)
+	if !ok {
+		return
+	}
+
+	var probe struct {
+		InstanceName string `json:"instanceName"`
+	}
+	if err := json.Unmarshal(payload, &probe); err != nil {
+		w.WriteHeader(g.handleErrors(payload, fmt.Errorf("error parsing instance name: %w", err)))
+		g.Metrics.failure(r.Context())
+		return
+	}
+	s, ok := m.Instances[probe.InstanceName]
+	if !ok {
+		g.handleErrors(payload, fmt.Errorf("%w: '%s'", ErrUnknownInstance, probe.InstanceName))
+		g.Metrics.failure(r.Context())
+		http.NotFound(w, r)
+		return
+	}
+
+	// the instance Access applies too, unless it is shared with Global and the
+	// request was already checked.
+	r = r.WithContext(WithInstance(r.Context(), probe.InstanceName))
+	if s.Access != g.Access && !s.allow(w, r) {
+		return
+	}
+	w.WriteHeader(m.dispatch(WithUserAgent(r.Context(), r.UserAgent()), s, payload))
+}
+
+// dispatch decode the payload once and invoke the instance triggers then the global
+// triggers with the same event, it returns the status of the first failure. a Metrics
+// shared by the instance and Global count the event once.
+func (m *Mux) dispatch(ctx context.Context, s *SonarrTriggers, payload []byte) int {
+	g := m.Global
+	if g == s {
+		g = nil
+	}
+	wanted := func(t EventType) bool {
+		return s.handles(t) || (g != nil && g.handles(t))
+	}
+	eventType, e, status, err := s.decode(ctx, payload, wanted)
+	if err != nil {
+		return status
+	}
+
+	status, err = s.deliver(ctx, eventType, e, payload, true)
+	if g == nil {
+		return status
+	}
+	gstatus, gerr := g.deliver(ctx, eventType, e, payload, g.Metrics != s.Metrics)
+	if err == nil && gerr != nil {
+		return gstatus
+	}
+	return status
+}
+
+func (m *Mux) global() *SonarrTriggers {
+	if m.Global != nil {
+		return m.Global
+	}
+	return &SonarrTriggers{}
+}
+
+type instanceKey struct{}
+
+// WithInstance return a copy of ctx with the instance name, it is set by Mux and it
+// can be used with SonarrTriggers.Dispatch to process events of a known instance.
+func WithInstance(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, instanceKey{}, name)
+}
+
+// InstanceFromContext return the instance name set by Mux or WithInstance, empty if
+// the event was not received through a Mux.
+func InstanceFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(instanceKey{}).(string)
+	return name
+}
+
+// withInstance set the InstanceName field of the typed event e.
+func withInstance(e Event, name string) Event {
+	if m, ok := e.(UnknownEvent); ok {
+		if m != nil {
+			m["instanceName"] = name
+		}
+		return m
+	}
+	return updateEvent(e, func(f eventFields) { *f.instanceName, f.source.instance = name, name })
+}
@@ -0,0 +1,85 @@
+package eventt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMuxSharedAccessAndMetrics(t *testing.T) {
+	access := &AccessControl{RateLimit: 0.001, Burst: 2}
+	metrics := &Metrics{}
+	var instanceEvents, globalEvents []GrabEvent
+	mux := &Mux{
+		Instances: map[string]*SonarrTriggers{
+			"hd": {
+				Access:  access,
+				Metrics: metrics,
+				OnGrab:  func(e GrabEvent) { instanceEvents = append(instanceEvents, e) },
+			},
+		},
+		Global: &SonarrTriggers{
+			Access:  access,
+			Metrics: metrics,
+			OnGrab:  func(e GrabEvent) { globalEvents = append(globalEvents, e) },
+		},
+	}
+
+	payload := `{"eventType":"Grab","instanceName":"hd","series":{"title":"Mob Psycho 100"}}`
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(payload)))
+		if w.Code != want {
+			t.Fatalf("request %d: status = %d, want %d", i, w.Code, want)
+		}
+	}
+
+	if len(instanceEvents) != 2 || len(globalEvents) != 2 {
+		t.Fatalf("got %d instance and %d global events, want 2", len(instanceEvents), len(globalEvents))
+	}
+	for _, e := range append(instanceEvents, globalEvents...) {
+		if e.InstanceName != "hd" || e.Series.Title != "Mob Psycho 100" {
+			t.Errorf("unexpected event %+v", e)
+		}
+	}
+	snap := metrics.Snapshot()
+	if n := snap.Events[string(Grab)]; n != 2 {
+		t.Errorf("shared metrics counted %d events, want 2", n)
+	}
+	if n := snap.Rejections[RejectRateLimited]; n != 1 {
+		t.Errorf("shared metrics counted %d rate limited requests, want 1", n)
+	}
+}
+
+func TestMuxGlobalOnly(t *testing.T) {
+	var global []Event
+	mux := &Mux{
+		Instances: map[string]*SonarrTriggers{"hd": {}},
+		Global: &SonarrTriggers{
+			OnHealth:  func(e HealthEvent) { global = append(global, e) },
+			OnUnknown: func(eventType string, e UnknownEvent) { global = append(global, e) },
+		},
+	}
+
+	for _, payload := range []string{
+		`{"eventType":"Health","message":"Indexers unavailable"}`,
+		`{"eventType":"ManualInteractionRequired"}`,
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/events/hd", strings.NewReader(payload)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d", w.Code)
+		}
+	}
+
+	if len(global) != 2 {
+		t.Fatalf("got %d global events, want 2", len(global))
+	}
+	if e, ok := global[0].(HealthEvent); !ok || e.InstanceName != "hd" {
+		t.Errorf("unexpected health event %#v", global[0])
+	}
+	if e, ok := global[1].(UnknownEvent); !ok || e["instanceName"] != "hd" {
+		t.Errorf("unexpected unknown event %#v", global[1])
+	}
+}
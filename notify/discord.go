@@ -0,0 +1,104 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/k-x7/eventt"
+)
+
+// Discord sink which post messages as embeds using a Discord webhook.
+// see: https://discord.com/developers/docs/resources/webhook#execute-webhook
+type Discord struct {
+	// WebhookURL Discord webhook URL, e.g. https://discord.com/api/webhooks/<id>/<token>
+	WebhookURL string
+	// Username override the default webhook username.
+	Username string
+	// AvatarURL override the default webhook avatar.
+	AvatarURL string
+	Options
+}
+
+type discordPayload struct {
+	Username  string         `json:"username,omitempty"`
+	AvatarURL string         `json:"avatar_url,omitempty"`
+	Embeds    []discordEmbed `json:"embeds"`
+}
+
+type discordEmbed struct {
+	Title       string         `json:"title"`
+	Description string         `json:"description,omitempty"`
+	URL         string         `json:"url,omitempty"`
+	Color       int            `json:"color"`
+	Fields      []discordField `json:"fields,omitempty"`
+}
+
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+var discordColors = map[Level]int{
+	LevelInfo:    0x3498db,
+	LevelSuccess: 0x2ecc71,
+	LevelWarning: 0xf1c40f,
+	LevelError:   0xe74c3c,
+}
+
+// Send implements eventt.Sink.
+func (d *Discord) Send(ctx context.Context, event eventt.Event, payload []byte) error {
+	return d.send(ctx, d, event, d.post)
+}
+
+// Close send the messages held by Options.Group for this sink.
+func (d *Discord) Close(ctx context.Context) error {
+	return d.Group.flush(ctx, d)
+}
+
+// post send m as Discord embed.
+func (d *Discord) post(ctx context.Context, m Message) error {
+
+	embed := discordEmbed{
+		Title:       m.Title,
+		Description: m.Body,
+		URL:         m.URL,
+		Color:       discordColors[m.Level],
+	}
+	for _, f := range m.Fields {
+		embed.Fields = append(embed.Fields, discordField{Name: f.Name, Value: f.Value, Inline: true})
+	}
+
+	err := d.postJSON(ctx, d.WebhookURL, nil, discordPayload{
+		Username:  d.Username,
+		AvatarURL: d.AvatarURL,
+		Embeds:    []discordEmbed{embed},
+	})
+	if err != nil {
+		// avoid leaking the webhook token from the url in the error.
+		var uerr *url.Error
+		if token := discordToken(d.WebhookURL); errors.As(err, &uerr) && token != "" {
+			uerr.URL = strings.ReplaceAll(uerr.URL, token, "<token>")
+		}
+		return fmt.Errorf("discord: %w", err)
+	}
+	return nil
+}
+
+// discordToken return the token segment of a webhook url: /api/webhooks/<id>/<token>.
+func discordToken(webhookURL string) string {
+	u, err := url.Parse(webhookURL)
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i := 0; i+2 < len(parts); i++ {
+		if parts[i] == "webhooks" {
+			return parts[i+2]
+		}
+	}
+	return ""
+}
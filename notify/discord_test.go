@@ -0,0 +1,78 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDiscordSend(t *testing.T) {
+	srv, requests := newService(t, http.StatusNoContent)
+	d := &Discord{WebhookURL: srv.URL + "/api/webhooks/1/token", Username: "Sonarr", AvatarURL: "https://example.com/sonarr.png"}
+	if err := d.Send(context.Background(), grabEvent(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	r := only(t, requests())
+	if r.path != "/api/webhooks/1/token" {
+		t.Errorf("path = %s", r.path)
+	}
+	var body discordPayload
+	r.decode(t, &body)
+	if body.Username != "Sonarr" || body.AvatarURL != "https://example.com/sonarr.png" {
+		t.Errorf("username and avatar not set: %+v", body)
+	}
+	if len(body.Embeds) != 1 {
+		t.Fatalf("got %d embeds, want 1", len(body.Embeds))
+	}
+	embed := body.Embeds[0]
+	if embed.Title != "Grabbed: Mob Psycho 100 S03E01-E02" {
+		t.Errorf("title = %q", embed.Title)
+	}
+	if embed.Description != "Mob.Psycho.100.S03E01E02.1080p.WEB-DL-GROUP" {
+		t.Errorf("description = %q", embed.Description)
+	}
+	if embed.Color != discordColors[LevelInfo] {
+		t.Errorf("color = %#x", embed.Color)
+	}
+	if len(embed.Fields) != 3 || embed.Fields[0] != (discordField{Name: "Quality", Value: "WEBDL-1080p", Inline: true}) {
+		t.Errorf("fields = %+v", embed.Fields)
+	}
+}
+
+func TestDiscordHealth(t *testing.T) {
+	srv, requests := newService(t, http.StatusNoContent)
+	d := &Discord{WebhookURL: srv.URL}
+	if err := d.Send(context.Background(), healthEvent(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var body map[string]interface{}
+	only(t, requests()).decode(t, &body)
+	if _, ok := body["username"]; ok {
+		t.Error("empty username should be omitted")
+	}
+	embed := body["embeds"].([]interface{})[0].(map[string]interface{})
+	if embed["url"] != healthEvent().WikiURL {
+		t.Errorf("url = %v", embed["url"])
+	}
+	if int(embed["color"].(float64)) != discordColors[LevelError] {
+		t.Errorf("color = %v", embed["color"])
+	}
+}
+
+func TestDiscordErrorHidesToken(t *testing.T) {
+	srv, _ := newService(t, http.StatusNoContent)
+	url := srv.URL
+	srv.Close()
+
+	d := &Discord{WebhookURL: url + "/api/webhooks/1/s3cret-token"}
+	err := d.Send(context.Background(), grabEvent(), nil)
+	if err == nil {
+		t.Fatal("expected error when the service is down")
+	}
+	if strings.Contains(err.Error(), "s3cret-token") || !strings.Contains(err.Error(), "/api/webhooks/1/<token>") {
+		t.Errorf("error should hide the webhook token: %v", err)
+	}
+}
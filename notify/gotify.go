@@ -0,0 +1,71 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/k-x7/eventt"
+)
+
+// Gotify sink which push messages to a Gotify server.
+// see: https://gotify.net/docs/pushmsg
+type Gotify struct {
+	// Server Gotify server URL, e.g. https://gotify.example.com
+	Server string
+	// Token application token.
+	Token string
+	Options
+}
+
+type gotifyPayload struct {
+	Title    string                 `json:"title"`
+	Message  string                 `json:"message"`
+	Priority int                    `json:"priority"`
+	Extras   map[string]interface{} `json:"extras,omitempty"`
+}
+
+var gotifyPriorities = map[Level]int{
+	LevelInfo:    2,
+	LevelSuccess: 4,
+	LevelWarning: 6,
+	LevelError:   8,
+}
+
+// Send implements eventt.Sink.
+func (g *Gotify) Send(ctx context.Context, event eventt.Event, payload []byte) error {
+	return g.send(ctx, g, event, g.post)
+}
+
+// Close send the messages held by Options.Group for this sink.
+func (g *Gotify) Close(ctx context.Context) error {
+	return g.Group.flush(ctx, g)
+}
+
+// post send m as Gotify message.
+func (g *Gotify) post(ctx context.Context, m Message) error {
+
+	var extras map[string]interface{}
+	if m.URL != "" {
+		extras = map[string]interface{}{
+			"client::notification": map[string]interface{}{
+				"click": map[string]string{"url": m.URL},
+			},
+		}
+	}
+
+	url := strings.TrimSuffix(g.Server, "/") + "/message"
+	header := http.Header{"X-Gotify-Key": {g.Token}}
+
+	err := g.postJSON(ctx, url, header, gotifyPayload{
+		Title:    m.Title,
+		Message:  m.Text(),
+		Priority: gotifyPriorities[m.Level],
+		Extras:   extras,
+	})
+	if err != nil {
+		return fmt.Errorf("gotify: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestGotifySend(t *testing.T) {
+	srv, requests := newService(t, http.StatusOK)
+	g := &Gotify{Server: srv.URL + "/", Token: "app-token"}
+	if err := g.Send(context.Background(), healthEvent(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	r := only(t, requests())
+	if r.path != "/message" {
+		t.Errorf("path = %s, want /message", r.path)
+	}
+	if key := r.header.Get("X-Gotify-Key"); key != "app-token" {
+		t.Errorf("X-Gotify-Key = %q", key)
+	}
+	var body struct {
+		Title    string `json:"title"`
+		Message  string `json:"message"`
+		Priority int    `json:"priority"`
+		Extras   struct {
+			Notification struct {
+				Click struct {
+					URL string `json:"url"`
+				} `json:"click"`
+			} `json:"client::notification"`
+		} `json:"extras"`
+	}
+	r.decode(t, &body)
+	if body.Title != "Health Issue: IndexerStatusCheck" || body.Message != "Indexers unavailable <all>" {
+		t.Errorf("title and message = %q %q", body.Title, body.Message)
+	}
+	if body.Priority != gotifyPriorities[LevelError] {
+		t.Errorf("priority = %d", body.Priority)
+	}
+	if body.Extras.Notification.Click.URL != healthEvent().WikiURL {
+		t.Errorf("click url = %q", body.Extras.Notification.Click.URL)
+	}
+}
+
+func TestGotifyWithoutURL(t *testing.T) {
+	srv, requests := newService(t, http.StatusOK)
+	g := &Gotify{Server: srv.URL, Token: "app-token"}
+	if err := g.Send(context.Background(), grabEvent(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var body map[string]interface{}
+	only(t, requests()).decode(t, &body)
+	if _, ok := body["extras"]; ok {
+		t.Errorf("extras should be omitted without url: %v", body)
+	}
+	if body["priority"] != float64(gotifyPriorities[LevelInfo]) {
+		t.Errorf("priority = %v", body["priority"])
+	}
+}
@@ -0,0 +1,137 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultGroupWindow used by SeriesGroup when Window is not set.
+const DefaultGroupWindow = time.Minute
+
+// SeriesGroup hold the messages about the same series for a time window and send
+// them as one message, e.g. a season pack grabbed and then downloaded episode by
+// episode produce a single notification. Messages without series, like Health and
+// Test, are sent right away. A SeriesGroup can be shared by several sinks, messages
+// are grouped per sink.
+//
+//	group := &notify.SeriesGroup{Window: 5 * time.Minute}
+//	discord := &notify.Discord{WebhookURL: url, Options: notify.Options{Group: group}}
+//	defer discord.Close(ctx)
+type SeriesGroup struct {
+	// Window time to wait for more messages after the first message of a series,
+	// if zero DefaultGroupWindow is used.
+	Window time.Duration
+	// OnError called with the errors of grouped messages sent after the window,
+	// as they can't be returned by Send.
+	OnError func(err error)
+
+	mu      sync.Mutex
+	pending map[groupKey]*group
+}
+
+type groupKey struct {
+	sink   interface{}
+	series string
+}
+
+// group messages held for a sink and series.
+type group struct {
+	messages []Message
+	post     func(context.Context, Message) error
+	timer    *time.Timer
+}
+
+// hold add m to the group of sink and m.Series, it returns false if m must be sent now.
+func (g *SeriesGroup) hold(sink interface{}, m Message, post func(context.Context, Message) error) bool {
+	if g == nil || m.Series == "" {
+		return false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	key := groupKey{sink: sink, series: m.Series}
+	if p, ok := g.pending[key]; ok {
+		p.messages = append(p.messages, m)
+		return true
+	}
+	if g.pending == nil {
+		g.pending = make(map[groupKey]*group)
+	}
+	window := g.Window
+	if window <= 0 {
+		window = DefaultGroupWindow
+	}
+	g.pending[key] = &group{
+		messages: []Message{m},
+		post:     post,
+		timer: time.AfterFunc(window, func() {
+			if err := g.send(context.Background(), key); err != nil && g.OnError != nil {
+				g.OnError(err)
+			}
+		}),
+	}
+	return true
+}
+
+// send post the messages of key as one message.
+func (g *SeriesGroup) send(ctx context.Context, key groupKey) error {
+	g.mu.Lock()
+	p, ok := g.pending[key]
+	delete(g.pending, key)
+	g.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	p.timer.Stop()
+	return p.post(ctx, merge(key.series, p.messages))
+}
+
+// flush send the messages held for sink without waiting for the window.
+func (g *SeriesGroup) flush(ctx context.Context, sink interface{}) error {
+	if g == nil {
+		return nil
+	}
+	g.mu.Lock()
+	var keys []groupKey
+	for key := range g.pending {
+		if key.sink == sink {
+			keys = append(keys, key)
+		}
+	}
+	g.mu.Unlock()
+
+	var errs []string
+	for _, key := range keys {
+		if err := g.send(ctx, key); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("error sending grouped messages: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// merge combine the messages of a series, a single message is returned as is.
+func merge(series string, messages []Message) Message {
+	if len(messages) == 1 {
+		return messages[0]
+	}
+	m := Message{
+		Event:  messages[0].Event,
+		Series: series,
+		Title:  fmt.Sprintf("%s: %d notifications", series, len(messages)),
+	}
+	titles := make([]string, len(messages))
+	for i, msg := range messages {
+		titles[i] = msg.Title
+		if msg.Level > m.Level {
+			m.Level = msg.Level
+		}
+	}
+	m.Body = strings.Join(titles, "\n")
+	return m
+}
@@ -0,0 +1,103 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/k-x7/eventt"
+)
+
+func TestSeriesGroupClose(t *testing.T) {
+	srv, requests := newService(t, http.StatusNoContent)
+	group := &SeriesGroup{Window: time.Hour}
+	d := &Discord{WebhookURL: srv.URL, Options: Options{Group: group}}
+	other := &Discord{WebhookURL: srv.URL, Options: Options{Group: group}}
+
+	download := eventt.DownloadEvent{
+		Series:   eventt.Series{Title: "Mob Psycho 100"},
+		Episodes: eventt.Episodes{{SeasonNumber: 3, EpisodeNumber: 1}},
+	}
+	for _, e := range []eventt.Event{grabEvent(), download, healthEvent()} {
+		if err := d.Send(context.Background(), e, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := other.Send(context.Background(), grabEvent(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// events without series are not held.
+	var body discordPayload
+	only(t, requests()).decode(t, &body)
+	if body.Embeds[0].Title != "Health Issue: IndexerStatusCheck" {
+		t.Errorf("title = %q", body.Embeds[0].Title)
+	}
+
+	if err := d.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(requests()); n != 2 {
+		t.Fatalf("got %d requests, want the group of d only", n)
+	}
+	body = discordPayload{}
+	requests()[1].decode(t, &body)
+	embed := body.Embeds[0]
+	if embed.Title != "Mob Psycho 100: 2 notifications" ||
+		embed.Description != "Grabbed: Mob Psycho 100 S03E01-E02\nDownloaded: Mob Psycho 100 S03E01" ||
+		embed.Color != discordColors[LevelSuccess] {
+		t.Errorf("grouped embed = %+v", embed)
+	}
+
+	if err := other.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	body = discordPayload{}
+	requests()[2].decode(t, &body)
+	if body.Embeds[0].Title != "Grabbed: Mob Psycho 100 S03E01-E02" {
+		t.Errorf("single message should be sent as is: %+v", body.Embeds[0])
+	}
+	if err := d.Close(context.Background()); err != nil || len(requests()) != 3 {
+		t.Errorf("second Close sent %d requests, %v", len(requests())-3, err)
+	}
+}
+
+func TestSeriesGroupWindow(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		sent []Message
+		errs = make(chan error, 1)
+	)
+	post := func(ctx context.Context, m Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, m)
+		return errors.New("service down")
+	}
+	group := &SeriesGroup{Window: 10 * time.Millisecond, OnError: func(err error) { errs <- err }}
+	for _, title := range []string{"one", "two"} {
+		if !group.hold("sink", Message{Series: "Show", Title: title}, post) {
+			t.Fatal("message with series not held")
+		}
+	}
+	if group.hold("sink", Message{Title: "health"}, post) {
+		t.Error("message without series held")
+	}
+
+	select {
+	case err := <-errs:
+		if err.Error() != "service down" {
+			t.Errorf("got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("group not sent after the window")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sent) != 1 || sent[0].Body != "one\ntwo" {
+		t.Errorf("sent %+v", sent)
+	}
+}
@@ -0,0 +1,278 @@
+// Package notify ready-made eventt sinks which send formatted notifications for Sonarr
+// events to chat and push services: Discord, Slack, Telegram, ntfy and Gotify.
+//
+//	events := eventt.SonarrTriggers{
+//		Sinks: []eventt.Sink{
+//			&notify.Discord{WebhookURL: "https://discord.com/api/webhooks/..."},
+//			&notify.Ntfy{Topic: "sonarr", Options: notify.Options{Types: eventt.Types(eventt.Download)}},
+//		},
+//	}
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/k-x7/eventt"
+)
+
+// DefaultTimeout used by sinks when Options.Client is not set.
+const DefaultTimeout = 10 * time.Second
+
+// Level severity of a notification, sinks use it for colors and priorities.
+type Level int
+
+const (
+	LevelInfo Level = iota
+	LevelSuccess
+	LevelWarning
+	LevelError
+)
+
+// Field name and value pair shown as a table or list by the sinks.
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Message service independent notification, each sink render it in its own format.
+type Message struct {
+	// Event type of the event which produced this message.
+	Event eventt.EventType
+	// Series title of the series the event is about, empty for events without series.
+	Series string
+	// Title short summary, e.g. "Downloaded: Mob Psycho 100 S03E01".
+	Title string
+	// Body longer description, it can be empty.
+	Body string
+	// Fields extra details, e.g. quality and size.
+	Fields []Field
+	// Level severity of the message.
+	Level Level
+	// URL optional link related to the message.
+	URL string
+}
+
+// Text plain text rendering of the message body and fields, used by sinks
+// without rich formatting.
+func (m Message) Text() string {
+	var b strings.Builder
+	b.WriteString(m.Body)
+	for _, f := range m.Fields {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%s: %s", f.Name, f.Value)
+	}
+	return b.String()
+}
+
+// Formatter convert an event to Message, it returns false to skip the event.
+type Formatter func(event eventt.Event) (Message, bool)
+
+// Options settings shared by all sinks.
+type Options struct {
+	// Types events to notify, empty means all events.
+	Types eventt.TypeFilter
+	// Format convert events to messages, if nil Format is used.
+	Format Formatter
+	// Client used to call the service API, if nil a client with DefaultTimeout is used.
+	Client *http.Client
+	// Group if set hold the messages of the same series and send them as one message.
+	Group *SeriesGroup
+}
+
+// message filter and format the event using the options.
+func (o Options) message(event eventt.Event) (Message, bool) {
+	if !o.Types.Match(eventt.TypeOf(event)) {
+		return Message{}, false
+	}
+	if o.Format != nil {
+		return o.Format(event)
+	}
+	return Format(event)
+}
+
+// send filter and format the event and post the message, or hold it in the
+// series group of sink.
+func (o Options) send(ctx context.Context, sink interface{}, event eventt.Event, post func(context.Context, Message) error) error {
+	m, ok := o.message(event)
+	if !ok {
+		return nil
+	}
+	if o.Group.hold(sink, m, post) {
+		return nil
+	}
+	return post(ctx, m)
+}
+
+func (o Options) client() *http.Client {
+	if o.Client != nil {
+		return o.Client
+	}
+	return &http.Client{Timeout: DefaultTimeout}
+}
+
+// postJSON send body as JSON to url and check the response status.
+func (o Options) postJSON(ctx context.Context, url string, header http.Header, body interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("error encoding message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// Format default Formatter, it produces a message for every event except UnknownEvent.
+func Format(event eventt.Event) (Message, bool) {
+	m := Message{Event: eventt.TypeOf(event), Level: LevelInfo}
+
+	switch e := event.(type) {
+	case eventt.GrabEvent:
+		m.Series = e.Series.Title
+		m.Title = fmt.Sprintf("Grabbed: %s %s", e.Series.Title, e.Episodes.CodeFor(e.Series.Type))
+		m.Body = e.Release.ReleaseTitle
+		m.Fields = fields(
+			"Quality", e.Release.Quality,
+			"Size", eventt.HumanSize(int64(e.Release.Size)),
+			"Release Group", e.Release.ReleaseGroup,
+			"Indexer", e.Release.Indexer,
+			"Download Client", e.DownloadClient,
+		)
+	case eventt.DownloadEvent:
+		m.Series = e.Series.Title
+		action := "Downloaded"
+		if e.IsUpgrade {
+			action = "Upgraded"
+		}
+		m.Title = fmt.Sprintf("%s: %s %s", action, e.Series.Title, e.Episodes.CodeFor(e.Series.Type))
+		m.Body = episodeTitles(e.Episodes)
+		m.Level = LevelSuccess
+		m.Fields = fields(
+			"Quality", e.EpisodeFile.Quality,
+			"Size", eventt.HumanSize(int64(e.EpisodeFile.Size)),
+			"Release Group", e.EpisodeFile.ReleaseGroup,
+		)
+	case eventt.RenameEvent:
+		m.Series = e.Series.Title
+		m.Title = fmt.Sprintf("Renamed: %s", e.Series.Title)
+		m.Body = fmt.Sprintf("%d episode file(s) renamed", len(e.RenamedEpisodeFiles))
+	case eventt.EpisodeFileDeleteEvent:
+		m.Series = e.Series.Title
+		m.Title = fmt.Sprintf("Episode File Deleted: %s %s", e.Series.Title, e.Episodes.CodeFor(e.Series.Type))
+		m.Body = e.EpisodeFile.RelativePath
+		m.Level = LevelWarning
+		m.Fields = fields(
+			"Reason", e.DeleteReason,
+			"Quality", e.EpisodeFile.Quality,
+			"Size", eventt.HumanSize(int64(e.EpisodeFile.Size)),
+		)
+	case eventt.SeriesDeleteEvent:
+		m.Series = e.Series.Title
+		m.Title = fmt.Sprintf("Series Deleted: %s", e.Series.Title)
+		m.Level = LevelWarning
+		if e.DeletedFiles {
+			m.Body = "Series files were deleted"
+		}
+	case eventt.HealthEvent:
+		m.Title = fmt.Sprintf("Health Issue: %s", e.Type)
+		m.Body = e.Message
+		m.URL = e.WikiURL
+		switch strings.ToLower(e.Level) {
+		case "error":
+			m.Level = LevelError
+		case "warning":
+			m.Level = LevelWarning
+		}
+	case eventt.ApplicationUpdateEvent:
+		m.Title = "Sonarr Updated"
+		m.Body = e.Message
+		m.Fields = fields(
+			"Previous Version", e.PreviousVersion,
+			"New Version", e.NewVersion,
+		)
+	case eventt.TestEvent:
+		m.Title = "Test Notification"
+		m.Body = "Test message from Sonarr"
+	default:
+		return Message{}, false
+	}
+	return m, true
+}
+
+// TemplateFormat Formatter which render messages using templates defined for sink,
+// the first line of the rendered template is the message title and the rest is
+// the body, events without template are formatted using Format.
+//
+//	templates := &eventt.Templates{}
+//	templates.Parse("discord", eventt.Grab, "Grabbed {{.Series.Title}} {{episodeCode .Episodes .Series.Type}}\n{{.Release.ReleaseTitle}}")
+//	discord := &notify.Discord{WebhookURL: url, Options: notify.Options{Format: notify.TemplateFormat(templates, "discord")}}
+func TemplateFormat(templates *eventt.Templates, sink string) Formatter {
+	return func(event eventt.Event) (Message, bool) {
+		if !templates.Has(sink, eventt.TypeOf(event)) {
+			return Format(event)
+		}
+		// keep level and url from the default message.
+		m, _ := Format(event)
+		m.Event = eventt.TypeOf(event)
+		m.Fields = nil
+
+		text, err := templates.Render(sink, event)
+		if err != nil {
+			m.Body = fmt.Sprintf("error rendering template: %v", err)
+			m.Level = LevelError
+			return m, true
+		}
+		title, body, _ := strings.Cut(strings.TrimSpace(text), "\n")
+		m.Title = strings.TrimSpace(title)
+		m.Body = strings.TrimSpace(body)
+		return m, true
+	}
+}
+
+// fields build a list of fields from name and value pairs skipping empty values.
+func fields(pairs ...string) []Field {
+	var f []Field
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] == "" {
+			continue
+		}
+		f = append(f, Field{Name: pairs[i], Value: pairs[i+1]})
+	}
+	return f
+}
+
+func episodeTitles(episodes []eventt.Episode) string {
+	titles := make([]string, 0, len(episodes))
+	for _, ep := range episodes {
+		if ep.Title != "" {
+			titles = append(titles, ep.Title)
+		}
+	}
+	return strings.Join(titles, ", ")
+}
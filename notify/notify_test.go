@@ -0,0 +1,184 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/k-x7/eventt"
+)
+
+// received request captured by the stand-in service.
+type received struct {
+	method string
+	path   string
+	header http.Header
+	body   []byte
+}
+
+// decode the received JSON body into v.
+func (r received) decode(t *testing.T, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(r.body, v); err != nil {
+		t.Fatalf("invalid JSON body %s: %v", r.body, err)
+	}
+}
+
+// newService start a stand-in service replying with status, the requests it received
+// are returned by the function.
+func newService(t *testing.T, status int) (*httptest.Server, func() []received) {
+	t.Helper()
+	var requests []received
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, received{
+			method: r.Method,
+			path:   r.URL.Path,
+			header: r.Header.Clone(),
+			body:   body,
+		})
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("service reply"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []received { return requests }
+}
+
+// only return the single request received by the service.
+func only(t *testing.T, requests []received) received {
+	t.Helper()
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+	r := requests[0]
+	if r.method != http.MethodPost {
+		t.Errorf("method = %s, want POST", r.method)
+	}
+	if ct := r.header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	return r
+}
+
+func grabEvent() eventt.GrabEvent {
+	e := eventt.GrabEvent{
+		Series: eventt.Series{Title: "Mob Psycho 100", Type: eventt.SeriesStandard},
+		Episodes: eventt.Episodes{
+			{SeasonNumber: 3, EpisodeNumber: 1, Title: "Dissolution"},
+			{SeasonNumber: 3, EpisodeNumber: 2, Title: "Cowardly"},
+		},
+		DownloadClient: "qBittorrent",
+	}
+	e.Release.Quality = "WEBDL-1080p"
+	e.Release.ReleaseTitle = "Mob.Psycho.100.S03E01E02.1080p.WEB-DL-GROUP"
+	e.Release.Size = 1 << 30
+	return e
+}
+
+func healthEvent() eventt.HealthEvent {
+	return eventt.HealthEvent{
+		Level:   "error",
+		Message: "Indexers unavailable <all>",
+		Type:    "IndexerStatusCheck",
+		WikiURL: "https://wiki.servarr.com/sonarr/system#indexers",
+	}
+}
+
+// sinks all notify sinks pointing to url.
+func sinks(url string, opts Options) map[string]eventt.Sink {
+	return map[string]eventt.Sink{
+		"discord":  &Discord{WebhookURL: url, Options: opts},
+		"slack":    &Slack{WebhookURL: url, Options: opts},
+		"telegram": &Telegram{Token: "123:secret", ChatID: "42", APIURL: url, Options: opts},
+		"ntfy":     &Ntfy{Server: url, Topic: "sonarr", Options: opts},
+		"gotify":   &Gotify{Server: url, Token: "app", Options: opts},
+	}
+}
+
+func TestSinksServiceError(t *testing.T) {
+	srv, _ := newService(t, http.StatusBadRequest)
+	for name, sink := range sinks(srv.URL, Options{}) {
+		err := sink.Send(context.Background(), grabEvent(), nil)
+		if err == nil {
+			t.Errorf("%s: expected error on 400 response", name)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), name+":") || !strings.Contains(err.Error(), "service reply") {
+			t.Errorf("%s: error %q should name the service and include the reply", name, err)
+		}
+	}
+}
+
+func TestSinksNetworkError(t *testing.T) {
+	srv, _ := newService(t, http.StatusOK)
+	url := srv.URL
+	srv.Close()
+	for name, sink := range sinks(url, Options{}) {
+		if err := sink.Send(context.Background(), grabEvent(), nil); err == nil {
+			t.Errorf("%s: expected error when the service is down", name)
+		}
+	}
+}
+
+func TestSinksFilter(t *testing.T) {
+	srv, requests := newService(t, http.StatusOK)
+	for name, sink := range sinks(srv.URL, Options{Types: eventt.Types(eventt.Download)}) {
+		if err := sink.Send(context.Background(), grabEvent(), nil); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	if n := len(requests()); n != 0 {
+		t.Errorf("filtered events sent %d requests", n)
+	}
+}
+
+func TestSinksFormatSkip(t *testing.T) {
+	srv, requests := newService(t, http.StatusOK)
+	for name, sink := range sinks(srv.URL, Options{}) {
+		if err := sink.Send(context.Background(), eventt.UnknownEvent{"eventType": "Other"}, nil); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	if n := len(requests()); n != 0 {
+		t.Errorf("unknown events sent %d requests", n)
+	}
+}
+
+func TestSinksContextCanceled(t *testing.T) {
+	srv, _ := newService(t, http.StatusOK)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for name, sink := range sinks(srv.URL, Options{}) {
+		if err := sink.Send(ctx, grabEvent(), nil); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: got %v, want context.Canceled", name, err)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	m, ok := Format(grabEvent())
+	if !ok {
+		t.Fatal("grab event skipped")
+	}
+	if m.Title != "Grabbed: Mob Psycho 100 S03E01-E02" {
+		t.Errorf("title = %q", m.Title)
+	}
+	want := []Field{
+		{"Quality", "WEBDL-1080p"},
+		{"Size", eventt.HumanSize(1 << 30)},
+		{"Download Client", "qBittorrent"},
+	}
+	if len(m.Fields) != len(want) {
+		t.Fatalf("fields = %v, want %v", m.Fields, want)
+	}
+	for i := range want {
+		if m.Fields[i] != want[i] {
+			t.Errorf("field %d = %v, want %v", i, m.Fields[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/k-x7/eventt"
+)
+
+// DefaultNtfyServer public ntfy server.
+const DefaultNtfyServer = "https://ntfy.sh"
+
+// Ntfy sink which publish messages to a ntfy topic.
+// see: https://docs.ntfy.sh/publish/#publish-as-json
+type Ntfy struct {
+	// Server ntfy server URL, if empty DefaultNtfyServer is used.
+	Server string
+	// Topic to publish to.
+	Topic string
+	// Token optional access token for protected topics.
+	Token string
+	Options
+}
+
+type ntfyPayload struct {
+	Topic    string   `json:"topic"`
+	Title    string   `json:"title"`
+	Message  string   `json:"message"`
+	Priority int      `json:"priority"`
+	Tags     []string `json:"tags,omitempty"`
+	Click    string   `json:"click,omitempty"`
+}
+
+var ntfyPriorities = map[Level]int{
+	LevelInfo:    3,
+	LevelSuccess: 3,
+	LevelWarning: 4,
+	LevelError:   5,
+}
+
+var ntfyTags = map[Level]string{
+	LevelInfo:    "information_source",
+	LevelSuccess: "white_check_mark",
+	LevelWarning: "warning",
+	LevelError:   "rotating_light",
+}
+
+// Send implements eventt.Sink.
+func (n *Ntfy) Send(ctx context.Context, event eventt.Event, payload []byte) error {
+	return n.send(ctx, n, event, n.post)
+}
+
+// Close send the messages held by Options.Group for this sink.
+func (n *Ntfy) Close(ctx context.Context) error {
+	return n.Group.flush(ctx, n)
+}
+
+// post send m as ntfy notification.
+func (n *Ntfy) post(ctx context.Context, m Message) error {
+
+	server := n.Server
+	if server == "" {
+		server = DefaultNtfyServer
+	}
+	var header http.Header
+	if n.Token != "" {
+		header = http.Header{"Authorization": {"Bearer " + n.Token}}
+	}
+
+	err := n.postJSON(ctx, strings.TrimSuffix(server, "/"), header, ntfyPayload{
+		Topic:    n.Topic,
+		Title:    m.Title,
+		Message:  m.Text(),
+		Priority: ntfyPriorities[m.Level],
+		Tags:     []string{ntfyTags[m.Level], strings.ToLower(string(m.Event))},
+		Click:    m.URL,
+	})
+	if err != nil {
+		return fmt.Errorf("ntfy: %w", err)
+	}
+	return nil
+}
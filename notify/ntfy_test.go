@@ -0,0 +1,56 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestNtfySend(t *testing.T) {
+	srv, requests := newService(t, http.StatusOK)
+	n := &Ntfy{Server: srv.URL + "/", Topic: "sonarr", Token: "tk_abc"}
+	if err := n.Send(context.Background(), healthEvent(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	r := only(t, requests())
+	if r.path != "/" && r.path != "" {
+		t.Errorf("path = %s, want the server root", r.path)
+	}
+	if auth := r.header.Get("Authorization"); auth != "Bearer tk_abc" {
+		t.Errorf("Authorization = %q", auth)
+	}
+	var body ntfyPayload
+	r.decode(t, &body)
+	if body.Topic != "sonarr" || body.Title != "Health Issue: IndexerStatusCheck" {
+		t.Errorf("topic and title = %q %q", body.Topic, body.Title)
+	}
+	if body.Message != "Indexers unavailable <all>" {
+		t.Errorf("message = %q", body.Message)
+	}
+	if body.Priority != 5 || body.Click != healthEvent().WikiURL {
+		t.Errorf("priority and click = %d %q", body.Priority, body.Click)
+	}
+	if len(body.Tags) != 2 || body.Tags[0] != "rotating_light" || body.Tags[1] != "health" {
+		t.Errorf("tags = %v", body.Tags)
+	}
+}
+
+func TestNtfyWithoutToken(t *testing.T) {
+	srv, requests := newService(t, http.StatusOK)
+	n := &Ntfy{Server: srv.URL, Topic: "sonarr"}
+	if err := n.Send(context.Background(), grabEvent(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	r := only(t, requests())
+	if auth := r.header.Get("Authorization"); auth != "" {
+		t.Errorf("Authorization = %q, want none", auth)
+	}
+	var body ntfyPayload
+	r.decode(t, &body)
+	want := "Mob.Psycho.100.S03E01E02.1080p.WEB-DL-GROUP\nQuality: WEBDL-1080p\nSize: 1.0 GiB\nDownload Client: qBittorrent"
+	if body.Message != want {
+		t.Errorf("message = %q\nwant %q", body.Message, want)
+	}
+}
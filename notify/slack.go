@@ -0,0 +1,79 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/k-x7/eventt"
+)
+
+// Slack sink which post messages as blocks using a Slack incoming webhook.
+// see: https://api.slack.com/messaging/webhooks
+type Slack struct {
+	// WebhookURL Slack incoming webhook URL, e.g. https://hooks.slack.com/services/...
+	WebhookURL string
+	Options
+}
+
+type slackPayload struct {
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+type slackBlock struct {
+	Type   string      `json:"type"`
+	Text   *slackText  `json:"text,omitempty"`
+	Fields []slackText `json:"fields,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// slackMaxFields maximum number of fields in a single section block.
+const slackMaxFields = 10
+
+// Send implements eventt.Sink.
+func (s *Slack) Send(ctx context.Context, event eventt.Event, payload []byte) error {
+	return s.send(ctx, s, event, s.post)
+}
+
+// Close send the messages held by Options.Group for this sink.
+func (s *Slack) Close(ctx context.Context) error {
+	return s.Group.flush(ctx, s)
+}
+
+// post send m as Slack blocks.
+func (s *Slack) post(ctx context.Context, m Message) error {
+
+	blocks := []slackBlock{{
+		Type: "header",
+		Text: &slackText{Type: "plain_text", Text: m.Title},
+	}}
+	if m.Body != "" {
+		body := m.Body
+		if m.URL != "" {
+			body += fmt.Sprintf("\n<%s|More details>", m.URL)
+		}
+		blocks = append(blocks, slackBlock{
+			Type: "section",
+			Text: &slackText{Type: "mrkdwn", Text: body},
+		})
+	}
+	for i := 0; i < len(m.Fields); i += slackMaxFields {
+		section := slackBlock{Type: "section"}
+		for j := i; j < len(m.Fields) && j < i+slackMaxFields; j++ {
+			section.Fields = append(section.Fields, slackText{
+				Type: "mrkdwn",
+				Text: fmt.Sprintf("*%s*\n%s", m.Fields[j].Name, m.Fields[j].Value),
+			})
+		}
+		blocks = append(blocks, section)
+	}
+
+	if err := s.postJSON(ctx, s.WebhookURL, nil, slackPayload{Text: m.Title, Blocks: blocks}); err != nil {
+		return fmt.Errorf("slack: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/k-x7/eventt"
+)
+
+func TestSlackSend(t *testing.T) {
+	srv, requests := newService(t, http.StatusOK)
+	s := &Slack{WebhookURL: srv.URL + "/services/T/B/X"}
+	if err := s.Send(context.Background(), grabEvent(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	r := only(t, requests())
+	if r.path != "/services/T/B/X" {
+		t.Errorf("path = %s", r.path)
+	}
+	var body slackPayload
+	r.decode(t, &body)
+	if body.Text != "Grabbed: Mob Psycho 100 S03E01-E02" {
+		t.Errorf("text = %q", body.Text)
+	}
+	if len(body.Blocks) != 3 {
+		t.Fatalf("got %d blocks, want header, body and fields: %+v", len(body.Blocks), body.Blocks)
+	}
+	if b := body.Blocks[0]; b.Type != "header" || b.Text.Type != "plain_text" || b.Text.Text != body.Text {
+		t.Errorf("header block = %+v", b)
+	}
+	if b := body.Blocks[1]; b.Type != "section" || b.Text.Text != "Mob.Psycho.100.S03E01E02.1080p.WEB-DL-GROUP" {
+		t.Errorf("body block = %+v", b)
+	}
+	if b := body.Blocks[2]; len(b.Fields) != 3 || b.Fields[0].Text != "*Quality*\nWEBDL-1080p" {
+		t.Errorf("fields block = %+v", b)
+	}
+}
+
+func TestSlackFieldsSplit(t *testing.T) {
+	srv, requests := newService(t, http.StatusOK)
+	format := func(event eventt.Event) (Message, bool) {
+		m := Message{Title: "many fields"}
+		for i := 0; i < slackMaxFields+1; i++ {
+			m.Fields = append(m.Fields, Field{Name: fmt.Sprint(i), Value: "v"})
+		}
+		return m, true
+	}
+	s := &Slack{WebhookURL: srv.URL, Options: Options{Format: format}}
+	if err := s.Send(context.Background(), grabEvent(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var body slackPayload
+	only(t, requests()).decode(t, &body)
+	if len(body.Blocks) != 3 || len(body.Blocks[1].Fields) != slackMaxFields || len(body.Blocks[2].Fields) != 1 {
+		t.Errorf("fields should be split in sections of %d: %+v", slackMaxFields, body.Blocks)
+	}
+}
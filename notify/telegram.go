@@ -0,0 +1,86 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+
+	"github.com/k-x7/eventt"
+)
+
+// DefaultTelegramAPI Telegram Bot API base URL.
+const DefaultTelegramAPI = "https://api.telegram.org"
+
+// Telegram sink which send messages using a Telegram bot.
+// see: https://core.telegram.org/bots/api#sendmessage
+type Telegram struct {
+	// Token bot token from @BotFather.
+	Token string
+	// ChatID target chat, user or channel id, e.g. "-1001234567890" or "@channel".
+	ChatID string
+	// APIURL Bot API base URL, if empty DefaultTelegramAPI is used.
+	APIURL string
+	// Silent send messages without notification sound.
+	Silent bool
+	Options
+}
+
+type telegramPayload struct {
+	ChatID                string `json:"chat_id"`
+	Text                  string `json:"text"`
+	ParseMode             string `json:"parse_mode"`
+	DisableWebPagePreview bool   `json:"disable_web_page_preview"`
+	DisableNotification   bool   `json:"disable_notification"`
+}
+
+// Send implements eventt.Sink.
+func (t *Telegram) Send(ctx context.Context, event eventt.Event, payload []byte) error {
+	return t.send(ctx, t, event, t.post)
+}
+
+// Close send the messages held by Options.Group for this sink.
+func (t *Telegram) Close(ctx context.Context) error {
+	return t.Group.flush(ctx, t)
+}
+
+// post send m as Telegram message.
+func (t *Telegram) post(ctx context.Context, m Message) error {
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<b>%s</b>", html.EscapeString(m.Title))
+	if m.Body != "" {
+		fmt.Fprintf(&b, "\n%s", html.EscapeString(m.Body))
+	}
+	for _, f := range m.Fields {
+		fmt.Fprintf(&b, "\n<b>%s:</b> %s", html.EscapeString(f.Name), html.EscapeString(f.Value))
+	}
+	if m.URL != "" {
+		fmt.Fprintf(&b, "\n<a href=\"%s\">More details</a>", html.EscapeString(m.URL))
+	}
+
+	api := t.APIURL
+	if api == "" {
+		api = DefaultTelegramAPI
+	}
+	endpoint := fmt.Sprintf("%s/bot%s/sendMessage", strings.TrimSuffix(api, "/"), t.Token)
+
+	err := t.postJSON(ctx, endpoint, nil, telegramPayload{
+		ChatID:                t.ChatID,
+		Text:                  b.String(),
+		ParseMode:             "HTML",
+		DisableWebPagePreview: true,
+		DisableNotification:   t.Silent,
+	})
+	if err != nil {
+		// avoid leaking the bot token from the url in the error.
+		var uerr *url.Error
+		if errors.As(err, &uerr) && t.Token != "" {
+			uerr.URL = strings.ReplaceAll(uerr.URL, t.Token, "<token>")
+		}
+		return fmt.Errorf("telegram: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestTelegramSend(t *testing.T) {
+	srv, requests := newService(t, http.StatusOK)
+	tg := &Telegram{Token: "123:secret", ChatID: "-1001234567890", APIURL: srv.URL + "/", Silent: true}
+	if err := tg.Send(context.Background(), healthEvent(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	r := only(t, requests())
+	if r.path != "/bot123:secret/sendMessage" {
+		t.Errorf("path = %s", r.path)
+	}
+	var body telegramPayload
+	r.decode(t, &body)
+	want := telegramPayload{
+		ChatID: "-1001234567890",
+		Text: "<b>Health Issue: IndexerStatusCheck</b>\n" +
+			"Indexers unavailable &lt;all&gt;\n" +
+			"<a href=\"https://wiki.servarr.com/sonarr/system#indexers\">More details</a>",
+		ParseMode:             "HTML",
+		DisableWebPagePreview: true,
+		DisableNotification:   true,
+	}
+	if body != want {
+		t.Errorf("body = %+v\nwant %+v", body, want)
+	}
+}
+
+func TestTelegramErrorHidesToken(t *testing.T) {
+	srv, _ := newService(t, http.StatusUnauthorized)
+	url := srv.URL
+	srv.Close()
+
+	tg := &Telegram{Token: "123:secret", ChatID: "42", APIURL: url}
+	err := tg.Send(context.Background(), grabEvent(), nil)
+	if err == nil {
+		t.Fatal("expected error when the service is down")
+	}
+	if strings.Contains(err.Error(), "123:secret") || !strings.Contains(err.Error(), "<token>") {
+		t.Errorf("error should hide the bot token: %v", err)
+	}
+}
@@ -0,0 +1,308 @@
+package eventt
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// QualitySource source of a quality as named by Sonarr, raw sources like blurayRaw
+// are split into the source and the Quality.Modifier.
+type QualitySource string
+
+// Quality sources.
+const (
+	SourceUnknown    QualitySource = "unknown"
+	SourceTelevision QualitySource = "television"
+	SourceWeb        QualitySource = "web"
+	SourceWebRip     QualitySource = "webRip"
+	SourceDVD        QualitySource = "dvd"
+	SourceBluray     QualitySource = "bluray"
+)
+
+// Quality modifiers of raw sources.
+const (
+	ModifierRaw   = "raw"
+	ModifierRemux = "remux"
+)
+
+// Quality parsed quality of a release or episode file, Sonarr sends it as a name and
+// version, e.g. "WEBDL-1080p" in GrabEvent.Release, or as an object with the source,
+// resolution and revision in the v3 episode file, both are decoded by UnmarshalJSON.
+// qualities are ordered by Sonarr default ranking, see Compare.
+type Quality struct {
+	Name       string
+	Source     QualitySource
+	Resolution int
+	// Modifier ModifierRaw or ModifierRemux for raw sources, empty otherwise.
+	Modifier string
+	Revision Revision
+}
+
+// Revision of a release, Version is 2 or more for propers and repacks, 3 for a repack
+// of a proper, and Real is set for REAL releases.
+type Revision struct {
+	Version  int  `json:"version"`
+	Real     int  `json:"real"`
+	IsRepack bool `json:"isRepack"`
+}
+
+// newRevision return the revision of a release with the proper, repack and REAL tags.
+func newRevision(proper, repack, real bool) Revision {
+	r := Revision{Version: 1, IsRepack: repack}
+	if proper {
+		r.Version++
+	}
+	if repack {
+		r.Version++
+	}
+	if real {
+		r.Real = 1
+	}
+	return r
+}
+
+// IsProper report whether the revision is a proper or a repack of a proper.
+func (r Revision) IsProper() bool {
+	return r.Version > 1 && !r.IsRepack || r.Version > 2
+}
+
+// Sonarr default qualities.
+var (
+	QualityUnknown   = Quality{Name: "Unknown", Source: SourceUnknown}
+	SDTV             = Quality{Name: "SDTV", Source: SourceTelevision, Resolution: 480}
+	WEBRip480p       = Quality{Name: "WEBRip-480p", Source: SourceWebRip, Resolution: 480}
+	WEBDL480p        = Quality{Name: "WEBDL-480p", Source: SourceWeb, Resolution: 480}
+	DVD              = Quality{Name: "DVD", Source: SourceDVD, Resolution: 480}
+	Bluray480p       = Quality{Name: "Bluray-480p", Source: SourceBluray, Resolution: 480}
+	Bluray576p       = Quality{Name: "Bluray-576p", Source: SourceBluray, Resolution: 576}
+	HDTV720p         = Quality{Name: "HDTV-720p", Source: SourceTelevision, Resolution: 720}
+	HDTV1080p        = Quality{Name: "HDTV-1080p", Source: SourceTelevision, Resolution: 1080}
+	RawHD            = Quality{Name: "Raw-HD", Source: SourceTelevision, Resolution: 1080, Modifier: ModifierRaw}
+	WEBRip720p       = Quality{Name: "WEBRip-720p", Source: SourceWebRip, Resolution: 720}
+	WEBDL720p        = Quality{Name: "WEBDL-720p", Source: SourceWeb, Resolution: 720}
+	Bluray720p       = Quality{Name: "Bluray-720p", Source: SourceBluray, Resolution: 720}
+	WEBRip1080p      = Quality{Name: "WEBRip-1080p", Source: SourceWebRip, Resolution: 1080}
+	WEBDL1080p       = Quality{Name: "WEBDL-1080p", Source: SourceWeb, Resolution: 1080}
+	Bluray1080p      = Quality{Name: "Bluray-1080p", Source: SourceBluray, Resolution: 1080}
+	Bluray1080pRemux = Quality{Name: "Bluray-1080p Remux", Source: SourceBluray, Resolution: 1080, Modifier: ModifierRemux}
+	HDTV2160p        = Quality{Name: "HDTV-2160p", Source: SourceTelevision, Resolution: 2160}
+	WEBRip2160p      = Quality{Name: "WEBRip-2160p", Source: SourceWebRip, Resolution: 2160}
+	WEBDL2160p       = Quality{Name: "WEBDL-2160p", Source: SourceWeb, Resolution: 2160}
+	Bluray2160p      = Quality{Name: "Bluray-2160p", Source: SourceBluray, Resolution: 2160}
+	Bluray2160pRemux = Quality{Name: "Bluray-2160p Remux", Source: SourceBluray, Resolution: 2160, Modifier: ModifierRemux}
+)
+
+type qualityDefinition struct {
+	id, weight int
+	quality    Quality
+}
+
+// qualityDefinitions Sonarr id and default weight of the qualities, WEBRip and WEBDL
+// of the same resolution are grouped with the same weight.
+// see: https://github.com/Sonarr/Sonarr/blob/v3.0.9.1549/src/NzbDrone.Core/Qualities/Quality.cs#L110
+var qualityDefinitions = []qualityDefinition{
+	{0, 1, QualityUnknown},
+	{1, 2, SDTV},
+	{12, 3, WEBRip480p},
+	{8, 3, WEBDL480p},
+	{2, 4, DVD},
+	{13, 5, Bluray480p},
+	{22, 6, Bluray576p},
+	{4, 7, HDTV720p},
+	{9, 8, HDTV1080p},
+	{10, 9, RawHD},
+	{14, 10, WEBRip720p},
+	{5, 10, WEBDL720p},
+	{6, 11, Bluray720p},
+	{15, 12, WEBRip1080p},
+	{3, 12, WEBDL1080p},
+	{7, 13, Bluray1080p},
+	{20, 14, Bluray1080pRemux},
+	{16, 15, HDTV2160p},
+	{17, 16, WEBRip2160p},
+	{18, 16, WEBDL2160p},
+	{19, 17, Bluray2160p},
+	{21, 18, Bluray2160pRemux},
+}
+
+// ParseQuality parse a quality name and version as sent by Sonarr, e.g. "WEBDL-1080p"
+// and 2 for a proper, the "Proper", "Repack" and "REAL" suffixes written by String are
+// parsed into the Revision. version 0 is the Sonarr default version 1, propers and
+// repacks are at least version 2. unknown names have SourceUnknown and rank as
+// QualityUnknown.
+func ParseQuality(name string, version int) Quality {
+	var proper, repack, real bool
+	words := strings.Fields(name)
+suffixes:
+	for len(words) > 1 {
+		switch strings.ToLower(words[len(words)-1]) {
+		case "proper":
+			proper = true
+		case "repack":
+			repack = true
+		case "real":
+			real = true
+		default:
+			break suffixes
+		}
+		words = words[:len(words)-1]
+	}
+	rev := newRevision(proper, repack, real)
+	if version > rev.Version {
+		rev.Version = version
+	}
+
+	name = strings.Join(words, " ")
+	q := Quality{Name: name, Source: SourceUnknown}
+	for _, def := range qualityDefinitions {
+		if strings.EqualFold(def.quality.Name, name) {
+			q = def.quality
+			break
+		}
+	}
+	q.Revision = rev
+	return q
+}
+
+// String return the quality name with the revision, e.g. "WEBDL-1080p Proper".
+func (q Quality) String() string {
+	s := q.Name
+	if q.Revision.IsProper() {
+		s += " Proper"
+	}
+	if q.Revision.IsRepack {
+		s += " Repack"
+	}
+	if q.Revision.Real > 0 {
+		s += " REAL"
+	}
+	return s
+}
+
+// Rank return the Sonarr default weight of the quality, higher is better.
+func (q Quality) Rank() int {
+	if def, ok := q.definition(); ok {
+		return def.weight
+	}
+	return qualityDefinitions[0].weight
+}
+
+// Compare return -1, 0 or 1 if q is worse, equal or better than o, the quality rank
+// is compared then the revision version and real, like Sonarr does.
+func (q Quality) Compare(o Quality) int {
+	switch {
+	case q.Rank() != o.Rank():
+		return sign(q.Rank() - o.Rank())
+	case q.Revision.Version != o.Revision.Version:
+		return sign(q.Revision.Version - o.Revision.Version)
+	}
+	return sign(q.Revision.Real - o.Revision.Real)
+}
+
+// AtLeast report whether q rank is the same or better than o, revisions are ignored.
+func (q Quality) AtLeast(o Quality) bool {
+	return q.Rank() >= o.Rank()
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+// definition return the Sonarr definition of q by source, resolution and modifier.
+func (q Quality) definition() (qualityDefinition, bool) {
+	for _, def := range qualityDefinitions {
+		d := def.quality
+		if d.Source == q.Source && d.Resolution == q.Resolution && d.Modifier == q.Modifier {
+			return def, true
+		}
+	}
+	return qualityDefinition{}, false
+}
+
+// sonarrQuality quality object sent by Sonarr, see sonarr.QualityModel.
+type sonarrQuality struct {
+	Quality struct {
+		ID         int    `json:"id"`
+		Name       string `json:"name"`
+		Source     string `json:"source"`
+		Resolution int    `json:"resolution"`
+	} `json:"quality"`
+	Revision Revision `json:"revision"`
+}
+
+// qualityFromSonarr convert the Sonarr quality source, e.g. blurayRaw, resolution
+// and revision.
+func qualityFromSonarr(name, source string, resolution int, rev Revision) Quality {
+	q := Quality{Name: name, Source: QualitySource(source), Resolution: resolution, Revision: rev}
+	switch source {
+	case "blurayRaw":
+		q.Source, q.Modifier = SourceBluray, ModifierRemux
+	case "televisionRaw":
+		q.Source, q.Modifier = SourceTelevision, ModifierRaw
+	case "":
+		q.Source = SourceUnknown
+	}
+	return q
+}
+
+// UnmarshalJSON decode a quality name, e.g. "WEBDL-1080p", or a Sonarr quality object
+// with the quality and revision.
+func (q *Quality) UnmarshalJSON(b []byte) error {
+	var name string
+	if err := json.Unmarshal(b, &name); err == nil {
+		*q = ParseQuality(name, 0)
+		return nil
+	}
+	var sq sonarrQuality
+	if err := json.Unmarshal(b, &sq); err != nil {
+		return fmt.Errorf("error parsing quality: %w", err)
+	}
+	*q = qualityFromSonarr(sq.Quality.Name, sq.Quality.Source, sq.Quality.Resolution, sq.Revision)
+	return nil
+}
+
+// MarshalJSON encode the quality as a Sonarr quality object.
+func (q Quality) MarshalJSON() ([]byte, error) {
+	var sq sonarrQuality
+	sq.Quality.Name = q.Name
+	sq.Quality.Source = string(q.Source)
+	sq.Quality.Resolution = q.Resolution
+	switch q.Modifier {
+	case ModifierRemux:
+		sq.Quality.Source = "blurayRaw"
+	case ModifierRaw:
+		sq.Quality.Source = "televisionRaw"
+	}
+	if def, ok := q.definition(); ok {
+		sq.Quality.ID = def.id
+	}
+	sq.Revision = q.Revision
+	return json.Marshal(sq)
+}
+
+// Quality return the release quality.
+func (e GrabEvent) Quality() Quality {
+	return ParseQuality(e.Release.Quality, e.Release.QualityVersion)
+}
+
+// Quality return the imported episode file quality.
+func (e DownloadEvent) Quality() Quality {
+	return ParseQuality(e.EpisodeFile.Quality, e.EpisodeFile.QualityVersion)
+}
+
+// Quality return the deleted episode file quality, including the full revision sent
+// by Sonarr v3.
+func (e EpisodeFileDeleteEvent) Quality() Quality {
+	if v3, ok := e.Source.Payload.(*V3EpisodeFileDeleteEvent); ok {
+		q := v3.EpisodeFile.Quality
+		return qualityFromSonarr(q.Quality.Name, q.Quality.Source, q.Quality.Resolution, Revision(q.Revision))
+	}
+	return ParseQuality(e.EpisodeFile.Quality, e.EpisodeFile.QualityVersion)
+}
@@ -0,0 +1,67 @@
+package eventt
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseQuality(t *testing.T) {
+	tests := []struct {
+		name    string
+		version int
+		want    Quality
+		str     string
+	}{
+		{"WEBDL-1080p", 0, withRevision(WEBDL1080p, Revision{Version: 1}), "WEBDL-1080p"},
+		{"webdl-1080p", 1, withRevision(WEBDL1080p, Revision{Version: 1}), "WEBDL-1080p"},
+		{"WEBDL-1080p", 2, withRevision(WEBDL1080p, Revision{Version: 2}), "WEBDL-1080p Proper"},
+		{"WEBDL-1080p Proper", 0, withRevision(WEBDL1080p, Revision{Version: 2}), "WEBDL-1080p Proper"},
+		{"HDTV-720p Repack", 1, withRevision(HDTV720p, Revision{Version: 2, IsRepack: true}), "HDTV-720p Repack"},
+		{"Bluray-1080p Remux Proper REAL", 0, withRevision(Bluray1080pRemux, Revision{Version: 2, Real: 1}), "Bluray-1080p Remux Proper REAL"},
+		{"Bluray-2160p REAL", 3, withRevision(Bluray2160p, Revision{Version: 3, Real: 1}), "Bluray-2160p Proper REAL"},
+		{"Proper", 0, Quality{Name: "Proper", Source: SourceUnknown, Revision: Revision{Version: 1}}, "Proper"},
+		{"VHS", 0, Quality{Name: "VHS", Source: SourceUnknown, Revision: Revision{Version: 1}}, "VHS"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseQuality(tt.name, tt.version)
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+			if got.String() != tt.str {
+				t.Errorf("String() = %q, want %q", got.String(), tt.str)
+			}
+		})
+	}
+}
+
+func withRevision(q Quality, rev Revision) Quality {
+	q.Revision = rev
+	return q
+}
+
+func TestQualityCompare(t *testing.T) {
+	var decoded Quality
+	if err := json.Unmarshal([]byte(`"WEBDL-1080p"`), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		a, b Quality
+		want int
+	}{
+		{"decoded name equal to version 1", decoded, ParseQuality("WEBDL-1080p", 1), 0},
+		{"proper suffix better", ParseQuality("WEBDL-1080p Proper", 0), decoded, 1},
+		{"real better than proper", ParseQuality("WEBDL-1080p Proper REAL", 0), ParseQuality("WEBDL-1080p Proper", 0), 1},
+		{"rank before revision", ParseQuality("WEBDL-720p Proper", 0), decoded, -1},
+		{"same group", ParseQuality("WEBRip-1080p", 1), decoded, 0},
+		{"suffixed name is not unknown", ParseQuality("WEBDL-1080p Proper", 0), QualityUnknown, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.Compare(tt.b); got != tt.want {
+				t.Errorf("%v.Compare(%v) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package eventt
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// decodeJSON decode b keeping numbers as json.Number, so they are written back as received.
+func decodeJSON(b []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v interface{}
+	err := dec.Decode(&v)
+	return v, err
+}
+
+// jsonFields names of the JSON fields of struct t.
+func jsonFields(t reflect.Type) map[string]bool {
+	fields := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = true
+	}
+	return fields
+}
+
+// unknownFields top level keys of payload which are not fields of struct t, nil if none
+// or if t is not a struct, e.g. UnknownEvent which keep all the fields.
+func unknownFields(payload []byte, t reflect.Type) map[string]json.RawMessage {
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &m); err != nil {
+		return nil
+	}
+	known := jsonFields(t)
+	var extra map[string]json.RawMessage
+	for k, v := range m {
+		if known[k] {
+			continue
+		}
+		if extra == nil {
+			extra = make(map[string]json.RawMessage)
+		}
+		extra[k] = v
+	}
+	return extra
+}
+
+// marshalEvent encode the typed event src, e must be src converted to an alias of the
+// event type without MarshalJSON, using its received payload Raw and unknown fields
+// Extra: unchanged events are encoded as Raw, otherwise only the modified fields are
+// written over Raw so nested fields which are not modelled are kept, the unknown top
+// level fields are taken from Extra. The fields set by the library, see
+// withLibraryFields, are not modifications.
+func marshalEvent[T any](e T, src Event) ([]byte, error) {
+	var raw json.RawMessage
+	var extra map[string]json.RawMessage
+	updateEvent(src, func(f eventFields) { raw, extra = *f.raw, *f.extra })
+
+	b, err := json.Marshal(e)
+	if err != nil || (len(raw) == 0 && len(extra) == 0) {
+		return b, err
+	}
+	cur, err := decodeJSON(b)
+	if err != nil {
+		return nil, err
+	}
+
+	out := cur
+	if len(raw) > 0 {
+		var orig T
+		received, err := decodeJSON(raw)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(raw, &orig); err == nil {
+			t := reflect.TypeOf(src)
+			lib := withLibraryFields(src, reflect.ValueOf(orig).Convert(t).Interface().(Event))
+			orig = reflect.ValueOf(lib).Convert(reflect.TypeOf(orig)).Interface().(T)
+			ob, err := json.Marshal(orig)
+			if err != nil {
+				return nil, err
+			}
+			prev, err := decodeJSON(ob)
+			if err != nil {
+				return nil, err
+			}
+			if reflect.DeepEqual(prev, cur) && reflect.DeepEqual(extra, unknownFields(raw, reflect.TypeOf(e))) {
+				return append([]byte(nil), raw...), nil
+			}
+			out = patchJSON(received, prev, cur)
+		}
+	}
+
+	obj, ok := out.(map[string]interface{})
+	if !ok {
+		return b, nil
+	}
+	known := jsonFields(reflect.TypeOf(e))
+	for k := range obj {
+		if _, ok := extra[k]; !known[k] && !ok {
+			delete(obj, k)
+		}
+	}
+	for k, v := range extra {
+		if !known[k] {
+			obj[k] = v
+		}
+	}
+	return json.Marshal(obj)
+}
+
+// patchJSON apply the changes from prev to cur on the received value, objects are
+// patched by key and arrays of the same length by index, other changes replace it.
+func patchJSON(received, prev, cur interface{}) interface{} {
+	if reflect.DeepEqual(prev, cur) {
+		return received
+	}
+	switch c := cur.(type) {
+	case map[string]interface{}:
+		r, rok := received.(map[string]interface{})
+		p, pok := prev.(map[string]interface{})
+		if !rok || !pok {
+			return cur
+		}
+		out := make(map[string]interface{}, len(r))
+		for k, v := range r {
+			out[k] = v
+		}
+		for k, v := range c {
+			if rv, ok := r[k]; ok {
+				out[k] = patchJSON(rv, p[k], v)
+				continue
+			}
+			if !reflect.DeepEqual(p[k], v) {
+				out[k] = v
+			}
+		}
+		return out
+	case []interface{}:
+		r, rok := received.([]interface{})
+		p, pok := prev.([]interface{})
+		if !rok || !pok || len(r) != len(c) || len(p) != len(c) {
+			return cur
+		}
+		out := make([]interface{}, len(c))
+		for i := range c {
+			out[i] = patchJSON(r[i], p[i], c[i])
+		}
+		return out
+	}
+	return cur
+}
+
+// withLibraryFields return received, the event decoded from the payload received for
+// e, with the fields set on e by the library: the instance name set by withInstance
+// and the absolute episode numbers set by withEnrichment.
+func withLibraryFields(e, received Event) Event {
+	var instance string
+	updateEvent(e, func(f eventFields) { instance = f.source.instance })
+	if instance != "" {
+		received = withInstance(received, instance)
+	}
+	if enr := enrichmentOf(e); enr != nil {
+		received = withEnrichment(received, enr)
+	}
+	return received
+}
+
+// withRaw set the Raw and Extra fields of the typed event e.
+func withRaw(e Event, raw json.RawMessage, extra map[string]json.RawMessage) Event {
+	return updateEvent(e, func(f eventFields) { *f.raw, *f.extra = raw, extra })
+}
+
+// MarshalJSON encode the event as received from Sonarr in Raw with the changes made
+// to the event and its Extra fields, events without Raw are encoded from their fields.
+// The InstanceName and absolute episode numbers set by Mux and Enricher are not
+// changes, they are only written when modified after.
+func (e GrabEvent) MarshalJSON() ([]byte, error) {
+	type event GrabEvent
+	return marshalEvent(event(e), e)
+}
+
+// MarshalJSON see GrabEvent.MarshalJSON.
+func (e DownloadEvent) MarshalJSON() ([]byte, error) {
+	type event DownloadEvent
+	return marshalEvent(event(e), e)
+}
+
+// MarshalJSON see GrabEvent.MarshalJSON.
+func (e RenameEvent) MarshalJSON() ([]byte, error) {
+	type event RenameEvent
+	return marshalEvent(event(e), e)
+}
+
+// MarshalJSON see GrabEvent.MarshalJSON.
+func (e EpisodeFileDeleteEvent) MarshalJSON() ([]byte, error) {
+	type event EpisodeFileDeleteEvent
+	return marshalEvent(event(e), e)
+}
+
+// MarshalJSON see GrabEvent.MarshalJSON.
+func (e SeriesDeleteEvent) MarshalJSON() ([]byte, error) {
+	type event SeriesDeleteEvent
+	return marshalEvent(event(e), e)
+}
+
+// MarshalJSON see GrabEvent.MarshalJSON.
+func (e HealthEvent) MarshalJSON() ([]byte, error) {
+	type event HealthEvent
+	return marshalEvent(event(e), e)
+}
+
+// MarshalJSON see GrabEvent.MarshalJSON.
+func (e ApplicationUpdateEvent) MarshalJSON() ([]byte, error) {
+	type event ApplicationUpdateEvent
+	return marshalEvent(event(e), e)
+}
+
+// MarshalJSON see GrabEvent.MarshalJSON.
+func (e TestEvent) MarshalJSON() ([]byte, error) {
+	type event TestEvent
+	return marshalEvent(event(e), e)
+}
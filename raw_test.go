@@ -0,0 +1,209 @@
+package eventt
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/k-x7/eventt/sonarr"
+)
+
+// jsonEqual report whether a and b encode the same JSON value.
+func jsonEqual(t *testing.T, a, b []byte) bool {
+	t.Helper()
+	var va, vb interface{}
+	if err := json.Unmarshal(a, &va); err != nil {
+		t.Fatalf("invalid JSON %s: %v", a, err)
+	}
+	if err := json.Unmarshal(b, &vb); err != nil {
+		t.Fatalf("invalid JSON %s: %v", b, err)
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestRoundTrip(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "v*", "*.json"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no fixtures: %v", err)
+	}
+	for _, file := range files {
+		name, _ := filepath.Rel("testdata", file)
+		t.Run(filepath.ToSlash(name), func(t *testing.T) {
+			payload := readFixture(t, name)
+			e, err := Parse(payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.HasSuffix(name, "ManualInteractionRequired.json") {
+				if _, ok := e.(UnknownEvent); !ok {
+					t.Fatalf("got %T, want UnknownEvent", e)
+				}
+			} else {
+				var src Source
+				updateEvent(e, func(f eventFields) { src = *f.source })
+				if want := "v" + filepath.Base(filepath.Dir(file))[1:]; src.Version.String() != want {
+					t.Errorf("version = %v, want %s", src.Version, want)
+				}
+			}
+
+			b, err := json.Marshal(e)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !jsonEqual(t, b, payload) {
+				t.Errorf("round trip changed the payload:\n got %s\nwant %s", b, payload)
+			}
+			if _, ok := e.(UnknownEvent); ok {
+				return
+			}
+
+			// the fields set by the library are not modifications of the event
+			var episodes Episodes
+			updateEvent(e, func(f eventFields) {
+				if f.episodes != nil {
+					episodes = *f.episodes
+				}
+			})
+			enr := &Enrichment{Series: &sonarr.Series{Title: "Enriched"}}
+			for i, ep := range episodes {
+				enr.Episodes = append(enr.Episodes, sonarr.Episode{ID: ep.ID, AbsoluteEpisodeNumber: 100 + i})
+			}
+			e = withEnrichment(withInstance(e, "library"), enr)
+			b, err = json.Marshal(e)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !jsonEqual(t, b, payload) {
+				t.Errorf("instance and enrichment changed the payload:\n got %s\nwant %s", b, payload)
+			}
+		})
+	}
+}
+
+func TestRoundTripModified(t *testing.T) {
+	tests := []struct {
+		file   string
+		modify func(Event) Event
+		want   map[string]string
+	}{
+		{
+			file: "v4/Grab.json",
+			modify: func(e Event) Event {
+				g := e.(GrabEvent)
+				g.Series.Title = "Mob Psycho 100 II"
+				g.Release.Quality = "WEBRip-1080p"
+				return g
+			},
+			want: map[string]string{
+				"series.title":           `"Mob Psycho 100 II"`,
+				"series.genres":          `["Action","Animation","Comedy"]`,
+				"release.quality":        `"WEBRip-1080p"`,
+				"release.languages":      `[{"id":8,"name":"Japanese"}]`,
+				"customFormatInfo":       `{"customFormats":[{"id":4,"name":"SubsPlease"}],"customFormatScore":10}`,
+				"episodes.0.overview":    `"Shigeo Kageyama is an average middle school boy."`,
+				"release.customFormats":  `["SubsPlease"]`,
+				"release.qualityVersion": `1`,
+			},
+		},
+		{
+			file: "v3/EpisodeFileDelete.json",
+			modify: func(e Event) Event {
+				d := e.(EpisodeFileDeleteEvent)
+				d.Series.Title = "Mob Psycho 100 II"
+				return d
+			},
+			want: map[string]string{
+				"series.title":                      `"Mob Psycho 100 II"`,
+				"episodeFile.quality.quality.name":  `"WEBDL-1080p"`,
+				"episodeFile.mediaInfo.videoFormat": `"AVC"`,
+				"episodeFile.language.name":         `"Japanese"`,
+			},
+		},
+		{
+			file: "v4/Download.json",
+			modify: func(e Event) Event {
+				d := e.(DownloadEvent)
+				d.Episodes[0].Title = "Reigen Arataka"
+				return d
+			},
+			want: map[string]string{
+				"episodes.0.title":                   `"Reigen Arataka"`,
+				"episodes.0.tvdbId":                  `5638563`,
+				"deletedFiles.0.recycleBinPath":      `"/recycle/Mob Psycho 100 - S01E01 [WEBDL-1080p].mkv"`,
+				"episodeFile.languages.0.name":       `"Japanese"`,
+				"release.releaseType":                `"singleEpisode"`,
+				"customFormatInfo.customFormatScore": `10`,
+			},
+		},
+		{
+			file: "v3/Grab.json",
+			modify: func(e Event) Event {
+				g := withInstance(e, "library").(GrabEvent)
+				g.InstanceName = "anime"
+				return g
+			},
+			want: map[string]string{
+				"instanceName": `"anime"`,
+				"series.title": `"Mob Psycho 100"`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			e, err := Parse(readFixture(t, tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := json.Marshal(tt.modify(e))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out interface{}
+			if err := json.Unmarshal(b, &out); err != nil {
+				t.Fatal(err)
+			}
+			for path, want := range tt.want {
+				got, err := json.Marshal(lookup(out, path))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !jsonEqual(t, got, []byte(want)) {
+					t.Errorf("%s = %s, want %s", path, got, want)
+				}
+			}
+		})
+	}
+}
+
+// lookup return the value at the dotted path in the decoded JSON v, array indexes are
+// numbers, e.g. "episodes.0.title".
+func lookup(v interface{}, path string) interface{} {
+	for _, key := range strings.Split(path, ".") {
+		switch c := v.(type) {
+		case map[string]interface{}:
+			v = c[key]
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i >= len(c) {
+				return nil
+			}
+			v = c[i]
+		default:
+			return nil
+		}
+	}
+	return v
+}
@@ -0,0 +1,200 @@
+package eventt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/k-x7/eventt/sonarr"
+)
+
+// DefaultWebhookName used when Webhook.Name is not set.
+const DefaultWebhookName = "eventt"
+
+// ErrTestNotReceived returned by Register when Webhook.Verify is set and the Test event
+// sent by Sonarr was not received by the triggers.
+var ErrTestNotReceived = errors.New("test event not received")
+
+// Webhook connection created in Sonarr by SonarrTriggers.Register.
+type Webhook struct {
+	// Name of the connection, an existing webhook with the same name is updated.
+	// default DefaultWebhookName.
+	Name string
+	// URL of eventt endpoint as reachable from Sonarr, e.g. http://eventt:8281/events.
+	URL string
+	// Username and Password basic auth credentials sent by Sonarr, optional.
+	Username string
+	Password string
+	// Tags labels of the series which trigger the connection, missing tags are
+	// created, empty means all series.
+	Tags []string
+	// Types triggers enabled in the connection, if empty they are chosen from the
+	// triggers, see SonarrTriggers.Triggers.
+	Types []EventType
+	// IncludeHealthWarnings notify health warnings and not only errors.
+	IncludeHealthWarnings bool
+	// SkipTest do not ask Sonarr to send a Test event before saving the connection.
+	SkipTest bool
+	// Verify if set, wait up to Verify for the Test event to be received by the
+	// triggers, it requires Monitor to be served while Register is running.
+	Verify time.Duration
+}
+
+// Triggers return the event types with non-nil On... callbacks, all event types if
+// there are sinks, since they receive every event.
+func (s *SonarrTriggers) Triggers() []EventType {
+	if len(s.Sinks) > 0 {
+		return []EventType{Grab, Download, Rename, EpisodeFileDelete, SeriesDelete, Health, ApplicationUpdate}
+	}
+	var types []EventType
+	add := func(ok bool, t EventType) {
+		if ok {
+			types = append(types, t)
+		}
+	}
+	add(s.OnGrab != nil, Grab)
+	add(s.OnDownload != nil, Download)
+	add(s.OnRename != nil, Rename)
+	add(s.OnEpisodeFileDelete != nil, EpisodeFileDelete)
+	add(s.OnSeriesDelete != nil, SeriesDelete)
+	add(s.OnHealth != nil, Health)
+	add(s.OnApplicationUpdate != nil, ApplicationUpdate)
+	return types
+}
+
+// Register create or update the webhook connection in Sonarr pointing at wh.URL, so
+// deployments do not need to set it by hand in Settings > Connect:
+//
+//	client := &sonarr.Client{BaseURL: "http://localhost:8989", APIKey: "..."}
+//	_, err := events.Register(ctx, client, eventt.Webhook{URL: "http://eventt:8281/events"})
+//
+// unless SkipTest is set, Sonarr sends a Test event to wh.URL before the connection
+// is saved and Register fails if the endpoint did not accept it.
+func (s *SonarrTriggers) Register(ctx context.Context, client *sonarr.Client, wh Webhook) (*sonarr.Notification, error) {
+	if wh.URL == "" {
+		return nil, errors.New("register webhook: url is required")
+	}
+	if wh.Name == "" {
+		wh.Name = DefaultWebhookName
+	}
+	types := wh.Types
+	if len(types) == 0 {
+		types = s.Triggers()
+	}
+	if len(types) == 0 {
+		return nil, errors.New("register webhook: no triggers, set On... callbacks, sinks or Types")
+	}
+
+	existing, err := client.Notifications(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("register webhook: %w", err)
+	}
+	n := &sonarr.Notification{}
+	for _, e := range existing {
+		if e.Name == wh.Name && e.Implementation == sonarr.WebhookImplementation {
+			// the current resource is updated with the fields set below only, so
+			// settings of the connection eventt does not know about are kept.
+			if n, err = client.Notification(ctx, e.ID); err != nil {
+				return nil, fmt.Errorf("register webhook: %w", err)
+			}
+			break
+		}
+	}
+	n.Name = wh.Name
+	n.Implementation = sonarr.WebhookImplementation
+	n.ConfigContract = sonarr.WebhookConfigContract
+	n.SetField("url", wh.URL)
+	n.SetField("method", sonarr.WebhookMethodPost)
+	n.SetField("username", wh.Username)
+	n.SetField("password", wh.Password)
+	n.IncludeHealthWarnings = wh.IncludeHealthWarnings
+	setTriggers(n, Types(types...))
+
+	if n.Tags, err = tagIDs(ctx, client, wh.Tags); err != nil {
+		return nil, fmt.Errorf("register webhook: %w", err)
+	}
+
+	if !wh.SkipTest {
+		if err := s.testWebhook(ctx, client, n, wh.Verify); err != nil {
+			return nil, fmt.Errorf("register webhook: %w", err)
+		}
+	}
+
+	if n.ID == 0 {
+		n, err = client.CreateNotification(ctx, n)
+	} else {
+		n, err = client.UpdateNotification(ctx, n)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("register webhook: %w", err)
+	}
+	return n, nil
+}
+
+// testWebhook ask Sonarr to send a Test event, if verify is set it waits for the
+// event to be received by s.
+func (s *SonarrTriggers) testWebhook(ctx context.Context, client *sonarr.Client, n *sonarr.Notification, verify time.Duration) error {
+	if verify <= 0 {
+		if err := client.TestNotification(ctx, n); err != nil {
+			return fmt.Errorf("test event: %w", err)
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, verify)
+	defer cancel()
+	received := s.Subscribe(ctx, Types(Test), 1, WithOverflow(OverflowDropNewest))
+	if err := client.TestNotification(ctx, n); err != nil {
+		return fmt.Errorf("test event: %w", err)
+	}
+	select {
+	case <-received:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%w after %s", ErrTestNotReceived, verify)
+	}
+}
+
+func setTriggers(n *sonarr.Notification, types TypeFilter) {
+	n.OnGrab = types.Match(Grab)
+	n.OnDownload = types.Match(Download)
+	n.OnUpgrade = types.Match(Download)
+	n.OnRename = types.Match(Rename)
+	n.OnEpisodeFileDelete = types.Match(EpisodeFileDelete)
+	n.OnEpisodeFileDeleteForUpgrade = types.Match(EpisodeFileDelete)
+	n.OnSeriesDelete = types.Match(SeriesDelete)
+	n.OnHealthIssue = types.Match(Health)
+	n.OnApplicationUpdate = types.Match(ApplicationUpdate)
+}
+
+// tagIDs return the ids of labels, missing tags are created.
+func tagIDs(ctx context.Context, client *sonarr.Client, labels []string) ([]int, error) {
+	ids := []int{}
+	if len(labels) == 0 {
+		return ids, nil
+	}
+	tags, err := client.Tags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, label := range labels {
+		id := -1
+		for _, t := range tags {
+			if strings.EqualFold(t.Label, label) {
+				id = t.ID
+				break
+			}
+		}
+		if id == -1 {
+			t, err := client.CreateTag(ctx, label)
+			if err != nil {
+				return nil, fmt.Errorf("error creating tag '%s': %w", label, err)
+			}
+			id = t.ID
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
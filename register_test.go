@@ -0,0 +1,90 @@
+package eventt
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/k-x7/eventt/sonarr"
+)
+
+// existingWebhook webhook connection as returned by Sonarr v4, with triggers and
+// fields which are not modelled by sonarr.Notification.
+const existingWebhook = `{
+	"id": 3,
+	"name": "eventt",
+	"implementation": "Webhook",
+	"configContract": "WebhookSettings",
+	"fields": [
+		{"order": 0, "name": "url", "label": "URL", "value": "http://old:8281/events", "type": "url"},
+		{"order": 1, "name": "method", "label": "Method", "value": 1, "type": "select"},
+		{"order": 4, "name": "headers", "label": "Headers", "value": [{"key": "X-Token", "value": "abc"}], "type": "keyValueList"}
+	],
+	"tags": [],
+	"onGrab": true,
+	"onDownload": false,
+	"onManualInteractionRequired": true,
+	"onSeriesAdd": true,
+	"includeHealthWarnings": false
+}`
+
+func TestRegisterUpdateKeepsUnknownFields(t *testing.T) {
+	var put map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/v3/notification":
+			_, _ = io.WriteString(w, "["+existingWebhook+"]")
+		case "GET /api/v3/notification/3":
+			_, _ = io.WriteString(w, existingWebhook)
+		case "PUT /api/v3/notification/3":
+			body, _ := io.ReadAll(r.Body)
+			if err := json.Unmarshal(body, &put); err != nil {
+				t.Errorf("invalid PUT body: %v", err)
+			}
+			_, _ = w.Write(body)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	events := &SonarrTriggers{OnDownload: func(DownloadEvent) {}}
+	client := &sonarr.Client{BaseURL: srv.URL, APIKey: "key"}
+	_, err := events.Register(context.Background(), client, Webhook{URL: "http://eventt:8281/events", SkipTest: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if put == nil {
+		t.Fatal("notification was not updated")
+	}
+	for k, want := range map[string]interface{}{
+		"onManualInteractionRequired": true,
+		"onSeriesAdd":                 true,
+		"onGrab":                      false,
+		"onDownload":                  true,
+	} {
+		if put[k] != want {
+			t.Errorf("%s = %v, want %v", k, put[k], want)
+		}
+	}
+
+	fields := map[string]map[string]interface{}{}
+	for _, f := range put["fields"].([]interface{}) {
+		f := f.(map[string]interface{})
+		fields[f["name"].(string)] = f
+	}
+	if url := fields["url"]; url["value"] != "http://eventt:8281/events" || url["label"] != "URL" {
+		t.Errorf("url field = %v", url)
+	}
+	if headers, ok := fields["headers"]["value"].([]interface{}); !ok || len(headers) != 1 {
+		t.Errorf("headers field not kept: %v", fields["headers"])
+	}
+	if _, ok := fields["username"]; !ok {
+		t.Error("username field not added")
+	}
+}
@@ -0,0 +1,350 @@
+// Package relay re-post Sonarr webhook events received by eventt to other services,
+// with per target event filters, retries and HMAC-SHA256 signatures.
+package relay
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	mrand "math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/k-x7/eventt"
+)
+
+// Headers added to every delivery.
+const (
+	// HeaderEvent the event type, e.g. Grab.
+	HeaderEvent = "X-Eventt-Event"
+	// HeaderDelivery unique id of the delivery, it is the same for all retries.
+	HeaderDelivery = "X-Eventt-Delivery"
+	// HeaderTimestamp unix time in seconds when the delivery was signed.
+	HeaderTimestamp = "X-Eventt-Timestamp"
+	// HeaderSignature HMAC-SHA256 signature, only set when Target.Secret is set.
+	// see Sign for details.
+	HeaderSignature = "X-Eventt-Signature"
+)
+
+// Default retry settings used when Relay fields are not set.
+const (
+	DefaultMaxAttempts = 5
+	DefaultMinBackoff  = time.Second
+	DefaultMaxBackoff  = time.Minute
+	DefaultTimeout     = 10 * time.Second
+)
+
+// Target a service which receive the relayed events.
+type Target struct {
+	// Name used in delivery status, if empty URL is used.
+	Name string
+	// URL where events are posted.
+	URL string
+	// Types events to relay to this target, empty means all events.
+	Types eventt.TypeFilter
+	// Secret HMAC-SHA256 key used to sign the deliveries, empty disable signing.
+	Secret string
+	// Header extra headers added to each delivery, e.g. Authorization.
+	Header http.Header
+}
+
+func (t Target) name() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return t.URL
+}
+
+// Delivery result of a single delivery attempt to a target.
+type Delivery struct {
+	// Target name of the target.
+	Target string
+	// ID delivery id, same as HeaderDelivery.
+	ID string
+	// Event type of the relayed event.
+	Event eventt.EventType
+	// Attempt number starting from 1.
+	Attempt int
+	// StatusCode http status returned by the target, zero on network errors.
+	StatusCode int
+	// Err nil when the delivery succeeded.
+	Err error
+	// Final true when no more attempts will be done for this delivery.
+	Final bool
+}
+
+// TargetStatus delivery counters of a single target.
+type TargetStatus struct {
+	Target      string
+	Delivered   uint64
+	Failed      uint64
+	Retries     uint64
+	Pending     int
+	LastError   error
+	LastAttempt time.Time
+	LastSuccess time.Time
+}
+
+// Relay eventt.Sink which re-post the original Sonarr payload to all matching targets.
+// deliveries run in the background, so Send never block the response to Sonarr,
+// use Status or OnDelivery to follow them and Close to wait for pending deliveries.
+type Relay struct {
+	// Targets services receiving the events.
+	Targets []Target
+	// Client used to post events, if nil a client with DefaultTimeout is used.
+	Client *http.Client
+	// MaxAttempts maximum number of attempts for each delivery, default DefaultMaxAttempts.
+	MaxAttempts int
+	// MinBackoff wait before the first retry, it is doubled after each retry with
+	// random jitter, default DefaultMinBackoff.
+	MinBackoff time.Duration
+	// MaxBackoff maximum wait between retries, default DefaultMaxBackoff.
+	MaxBackoff time.Duration
+	// OnDelivery optional callback invoked after each delivery attempt.
+	OnDelivery func(d Delivery)
+
+	once   sync.Once
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu     sync.Mutex
+	closed bool
+	// status of each target by index in Targets, names may not be unique.
+	status []*TargetStatus
+}
+
+func (r *Relay) init() {
+	r.once.Do(func() {
+		r.ctx, r.cancel = context.WithCancel(context.Background())
+		r.status = make([]*TargetStatus, len(r.Targets))
+		for i, t := range r.Targets {
+			r.status[i] = &TargetStatus{Target: t.name()}
+		}
+	})
+}
+
+// Send schedule the delivery of payload to all targets matching the event type.
+func (r *Relay) Send(ctx context.Context, event eventt.Event, payload []byte) error {
+	r.init()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return fmt.Errorf("relay is closed")
+	}
+
+	eventType := eventt.TypeOf(event)
+	if unknown, ok := event.(eventt.UnknownEvent); ok {
+		if t, ok := unknown["eventType"].(string); ok {
+			eventType = eventt.EventType(t)
+		}
+	}
+
+	for i, t := range r.Targets {
+		if !t.Types.Match(eventType) {
+			continue
+		}
+		r.status[i].Pending++
+		r.wg.Add(1)
+		go r.deliver(i, eventType, newID(), payload)
+	}
+	return nil
+}
+
+// Status return delivery counters for each target in the same order of Targets.
+func (r *Relay) Status() []TargetStatus {
+	r.init()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	status := make([]TargetStatus, 0, len(r.status))
+	for _, st := range r.status {
+		status = append(status, *st)
+	}
+	return status
+}
+
+// HealthCheck implements eventt.HealthChecker, it fails when the relay is closed or
+// when the last delivery attempt to a target failed.
+func (r *Relay) HealthCheck(ctx context.Context) error {
+	r.init()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return fmt.Errorf("relay is closed")
+	}
+	for _, st := range r.status {
+		if st.LastError != nil && st.LastSuccess.Before(st.LastAttempt) {
+			return fmt.Errorf("target '%s' is failing, %d pending: %w", st.Target, st.Pending, st.LastError)
+		}
+	}
+	return nil
+}
+
+// Close stop accepting new events and wait for pending deliveries until ctx is done,
+// then the remaining deliveries are canceled.
+func (r *Relay) Close(ctx context.Context) error {
+	r.init()
+
+	r.mu.Lock()
+	r.closed = true
+	r.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		r.cancel()
+		return nil
+	case <-ctx.Done():
+		r.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (r *Relay) deliver(target int, eventType eventt.EventType, id string, payload []byte) {
+	defer r.wg.Done()
+
+	t := r.Targets[target]
+	maxAttempts := r.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+
+	for attempt := 1; ; attempt++ {
+		code, err := r.post(t, eventType, id, payload)
+		retry := err != nil && retryable(code) && attempt < maxAttempts && r.ctx.Err() == nil
+
+		r.record(target, err, !retry)
+		if r.OnDelivery != nil {
+			r.OnDelivery(Delivery{
+				Target:     t.name(),
+				ID:         id,
+				Event:      eventType,
+				Attempt:    attempt,
+				StatusCode: code,
+				Err:        err,
+				Final:      !retry,
+			})
+		}
+		if !retry {
+			return
+		}
+
+		timer := time.NewTimer(r.backoff(attempt))
+		select {
+		case <-timer.C:
+		case <-r.ctx.Done():
+			timer.Stop()
+		}
+	}
+}
+
+func (r *Relay) post(t Target, eventType eventt.EventType, id string, payload []byte) (int, error) {
+	req, err := http.NewRequestWithContext(r.ctx, http.MethodPost, t.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	for k, v := range t.Header {
+		req.Header[k] = v
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "eventt-relay")
+	req.Header.Set(HeaderEvent, string(eventType))
+	req.Header.Set(HeaderDelivery, id)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	if t.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign([]byte(t.Secret), timestamp, payload))
+	}
+
+	client := r.Client
+	if client == nil {
+		client = &http.Client{Timeout: DefaultTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+func (r *Relay) record(target int, err error, final bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	st := r.status[target]
+	st.LastAttempt = time.Now()
+	switch {
+	case err == nil:
+		st.Delivered++
+		st.LastSuccess = st.LastAttempt
+	case final:
+		st.Failed++
+	default:
+		st.Retries++
+	}
+	if err != nil {
+		st.LastError = err
+	}
+	if final {
+		st.Pending--
+	}
+}
+
+// backoff return the wait before the next attempt, exponential with jitter
+// between half and the full duration.
+func (r *Relay) backoff(attempt int) time.Duration {
+	min, max := r.MinBackoff, r.MaxBackoff
+	if min <= 0 {
+		min = DefaultMinBackoff
+	}
+	if max <= 0 {
+		max = DefaultMaxBackoff
+	}
+
+	d := min
+	for i := 1; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	half := int64(d / 2)
+	return time.Duration(half + mrand.Int63n(half+1))
+}
+
+// retryable report whether a failed delivery with the given status should be retried,
+// network errors, 408, 429 and 5xx are retried, other client errors are not.
+func retryable(code int) bool {
+	return code == 0 ||
+		code == http.StatusRequestTimeout ||
+		code == http.StatusTooManyRequests ||
+		code >= 500
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
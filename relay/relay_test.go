@@ -0,0 +1,231 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/k-x7/eventt"
+)
+
+// target stand-in service answering with the given statuses in order, the last one
+// is repeated, it records the received requests.
+type target struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newTarget(t *testing.T, statuses ...int) (*target, string) {
+	t.Helper()
+	tg := &target{statuses: statuses}
+	srv := httptest.NewServer(tg)
+	t.Cleanup(srv.Close)
+	return tg, srv.URL
+}
+
+func (tg *target) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	tg.mu.Lock()
+	defer tg.mu.Unlock()
+	tg.requests = append(tg.requests, r)
+	tg.bodies = append(tg.bodies, body)
+	status := tg.statuses[len(tg.statuses)-1]
+	if n := len(tg.requests); n <= len(tg.statuses) {
+		status = tg.statuses[n-1]
+	}
+	w.WriteHeader(status)
+}
+
+func (tg *target) count() int {
+	tg.mu.Lock()
+	defer tg.mu.Unlock()
+	return len(tg.requests)
+}
+
+// send relay payload and wait for the pending deliveries.
+func send(t *testing.T, r *Relay, e eventt.Event, payload string) {
+	t.Helper()
+	if err := r.Send(context.Background(), e, []byte(payload)); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := r.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRelayRetryThenSuccess(t *testing.T) {
+	tg, url := newTarget(t, http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK)
+	var mu sync.Mutex
+	var deliveries []Delivery
+	r := &Relay{
+		Targets:    []Target{{Name: "hook", URL: url, Secret: "s3cret", Header: http.Header{"Authorization": {"Bearer t"}}}},
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 2 * time.Millisecond,
+		OnDelivery: func(d Delivery) {
+			mu.Lock()
+			deliveries = append(deliveries, d)
+			mu.Unlock()
+		},
+	}
+	payload := `{"eventType":"Grab"}`
+	send(t, r, eventt.GrabEvent{}, payload)
+
+	if tg.count() != 3 || len(deliveries) != 3 {
+		t.Fatalf("got %d requests and %d deliveries, want 3", tg.count(), len(deliveries))
+	}
+	for i, d := range deliveries {
+		if d.Attempt != i+1 || d.ID != deliveries[0].ID || d.Final != (i == 2) || d.Target != "hook" || d.Event != eventt.Grab {
+			t.Errorf("delivery %d = %+v", i, d)
+		}
+	}
+	if d := deliveries[2]; d.Err != nil || d.StatusCode != http.StatusOK {
+		t.Errorf("last delivery = %+v", d)
+	}
+
+	req := tg.requests[2]
+	if req.Header.Get(HeaderEvent) != "Grab" || req.Header.Get(HeaderDelivery) != deliveries[0].ID ||
+		req.Header.Get("Authorization") != "Bearer t" || string(tg.bodies[2]) != payload {
+		t.Errorf("unexpected request %v %s", req.Header, tg.bodies[2])
+	}
+	if err := Verify([]byte("s3cret"), req.Header, tg.bodies[2], time.Minute); err != nil {
+		t.Errorf("delivery signature: %v", err)
+	}
+
+	st := r.Status()[0]
+	if st.Target != "hook" || st.Delivered != 1 || st.Retries != 2 || st.Failed != 0 || st.Pending != 0 {
+		t.Errorf("status = %+v", st)
+	}
+}
+
+func TestRelayNonRetryable(t *testing.T) {
+	tg, url := newTarget(t, http.StatusBadRequest)
+	r := &Relay{Targets: []Target{{URL: url}}, MinBackoff: time.Millisecond}
+	send(t, r, eventt.HealthEvent{}, `{"eventType":"Health"}`)
+
+	if tg.count() != 1 {
+		t.Errorf("got %d requests, want no retry of 400", tg.count())
+	}
+	st := r.Status()[0]
+	if st.Target != url || st.Failed != 1 || st.Retries != 0 || st.LastError == nil {
+		t.Errorf("status = %+v", st)
+	}
+	if err := (&Relay{Targets: r.Targets}).HealthCheck(context.Background()); err != nil {
+		t.Errorf("new relay unhealthy: %v", err)
+	}
+}
+
+func TestRelayMaxAttempts(t *testing.T) {
+	tg, url := newTarget(t, http.StatusBadGateway)
+	r := &Relay{Targets: []Target{{URL: url}}, MaxAttempts: 2, MinBackoff: time.Millisecond}
+	if err := r.Send(context.Background(), eventt.TestEvent{}, []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for r.Status()[0].Pending > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if err := r.HealthCheck(context.Background()); err == nil {
+		t.Error("failing target reported healthy")
+	}
+	if err := r.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if tg.count() != 2 || r.Status()[0].Failed != 1 || r.Status()[0].Retries != 1 {
+		t.Errorf("got %d requests, status %+v", tg.count(), r.Status()[0])
+	}
+	if err := r.Send(context.Background(), eventt.TestEvent{}, []byte(`{}`)); err == nil {
+		t.Error("closed relay accepted an event")
+	}
+}
+
+func TestRelayTargetTypes(t *testing.T) {
+	grabs, grabURL := newTarget(t, http.StatusOK)
+	manual, manualURL := newTarget(t, http.StatusOK)
+	all, allURL := newTarget(t, http.StatusOK)
+	r := &Relay{Targets: []Target{
+		{Name: "same", URL: grabURL, Types: eventt.Types(eventt.Grab)},
+		{Name: "same", URL: manualURL, Types: eventt.Types("ManualInteractionRequired")},
+		{URL: allURL},
+	}}
+	if err := r.Send(context.Background(), eventt.GrabEvent{}, []byte(`{"eventType":"Grab"}`)); err != nil {
+		t.Fatal(err)
+	}
+	send(t, r, eventt.UnknownEvent{"eventType": "ManualInteractionRequired"}, `{"eventType":"ManualInteractionRequired"}`)
+
+	if grabs.count() != 1 || manual.count() != 1 || all.count() != 2 {
+		t.Errorf("got %d grab, %d manual and %d all requests", grabs.count(), manual.count(), all.count())
+	}
+	if got := manual.requests[0].Header.Get(HeaderEvent); got != "ManualInteractionRequired" {
+		t.Errorf("event header = %q", got)
+	}
+	status := r.Status()
+	if status[0].Delivered != 1 || status[1].Delivered != 1 || status[2].Delivered != 2 {
+		t.Errorf("targets with the same name share status: %+v", status)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	for code, want := range map[int]bool{
+		0: true, 408: true, 429: true, 500: true, 503: true,
+		400: false, 401: false, 404: false, 410: false,
+	} {
+		if retryable(code) != want {
+			t.Errorf("retryable(%d) = %v", code, !want)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	r := &Relay{MinBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	for attempt, max := range map[int]time.Duration{1: 100, 2: 200, 3: 400, 4: 800, 5: 1000, 10: 1000} {
+		max *= time.Millisecond
+		for i := 0; i < 20; i++ {
+			if d := r.backoff(attempt); d < max/2 || d > max {
+				t.Fatalf("backoff(%d) = %v, want between %v and %v", attempt, d, max/2, max)
+			}
+		}
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	secret := []byte("s3cret")
+	payload := []byte(`{"eventType":"Grab"}`)
+	now := time.Now().Unix()
+	header := func(ts int64, sig string) http.Header {
+		h := http.Header{}
+		h.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+		h.Set(HeaderSignature, sig)
+		return h
+	}
+
+	if err := Verify(secret, header(now, Sign(secret, now, payload)), payload, time.Minute); err != nil {
+		t.Errorf("round trip: %v", err)
+	}
+	tests := []struct {
+		name    string
+		header  http.Header
+		payload []byte
+		want    error
+	}{
+		{"tampered body", header(now, Sign(secret, now, payload)), []byte(`{"eventType":"Test"}`), ErrInvalidSignature},
+		{"other secret", header(now, Sign([]byte("other"), now, payload)), payload, ErrInvalidSignature},
+		{"tampered timestamp", header(now+1, Sign(secret, now, payload)), payload, ErrInvalidSignature},
+		{"expired", header(now-120, Sign(secret, now-120, payload)), payload, ErrExpiredSignature},
+		{"missing", http.Header{}, payload, ErrMissingSignature},
+	}
+	for _, tt := range tests {
+		if err := Verify(secret, tt.header, tt.payload, time.Minute); !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
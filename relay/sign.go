@@ -0,0 +1,57 @@
+package relay
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrMissingSignature returned by Verify when the signature or timestamp headers are missing.
+	ErrMissingSignature = errors.New("missing signature")
+	// ErrInvalidSignature returned by Verify when the signature does not match the payload.
+	ErrInvalidSignature = errors.New("invalid signature")
+	// ErrExpiredSignature returned by Verify when the timestamp is older than the allowed age.
+	ErrExpiredSignature = errors.New("expired signature")
+)
+
+// Sign return the value of HeaderSignature for payload, it is "sha256=" followed by
+// the hex encoded HMAC-SHA256 of "<timestamp>.<payload>" using secret as key.
+func Sign(secret []byte, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify check the signature headers of a received delivery, receivers should use it
+// with the raw request body before parsing it. if maxAge is positive deliveries signed
+// before maxAge are rejected to limit replay attacks.
+func Verify(secret []byte, header http.Header, payload []byte, maxAge time.Duration) error {
+	signature := header.Get(HeaderSignature)
+	ts := header.Get(HeaderTimestamp)
+	if signature == "" || ts == "" {
+		return ErrMissingSignature
+	}
+
+	timestamp, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: invalid timestamp '%s'", ErrInvalidSignature, ts)
+	}
+	if maxAge > 0 && time.Since(time.Unix(timestamp, 0)) > maxAge {
+		return ErrExpiredSignature
+	}
+
+	expected := Sign(secret, timestamp, payload)
+	if !strings.HasPrefix(signature, "sha256=") || !hmac.Equal([]byte(signature), []byte(expected)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,454 @@
+package eventt
+
+import (
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReleaseInfo information parsed from a scene or P2P release name, see ParseRelease.
+// fields which are not found in the name are empty.
+type ReleaseInfo struct {
+	// Title series title with separators replaced by spaces, without the year.
+	Title string
+	Year  int
+	// Season and Episodes of standard releases, e.g. S03E04-E06, Episodes is empty
+	// for season packs which have FullSeason set.
+	Season     int
+	Episodes   []int
+	FullSeason bool
+	// AbsoluteEpisodes of anime releases, e.g. [Group] Title - 012.
+	AbsoluteEpisodes []int
+	// AirDate of daily releases formatted like Episode.AirDate, e.g. 2023-01-02.
+	AirDate string
+	// Quality source, resolution, modifier and revision, Quality.Name is set when they
+	// match a Sonarr quality.
+	Quality Quality
+	// Codec video codec, e.g. x264, x265, H.264, H.265 or AV1.
+	Codec string
+	// Audio audio codec, e.g. AAC, AC3, EAC3, DTS-HD MA or TrueHD Atmos, and its
+	// channels, e.g. 5.1.
+	Audio         string
+	AudioChannels string
+	// HDR formats, e.g. DV, HDR10, HDR10+, HDR or HLG.
+	HDR []string
+	// Group release group, e.g. NTb.
+	Group string
+	// Languages languages in the name, e.g. French or Multi, empty for English releases.
+	Languages []string
+}
+
+const (
+	sepStart = `(?:^|[^a-z0-9])`
+	sepEnd   = `(?:[^a-z0-9]|$)`
+)
+
+var (
+	videoExtRe    = regexp.MustCompile(`(?i)\.(mkv|mp4|avi|m4v|ts|wmv|mov)$`)
+	trailingTagRe = regexp.MustCompile(`(?:\[[^\]]*\]|\{[^}]*\})$`)
+
+	animeRe    = regexp.MustCompile(`(?i)^\[([^\]]+)\][ ._]*(.+?)[ ._]+-[ ._]+(\d{1,4})(?:-(\d{1,4}))?(?:v(\d))?(?:[ ._\[\(]|$)`)
+	episodeRe  = regexp.MustCompile(`(?i)` + sepStart + `s(\d{1,4})[ ._-]?(e\d{1,4}(?:-e?\d{1,4}|[ ._-]?e\d{1,4})*)` + sepEnd)
+	crossRe    = regexp.MustCompile(`(?i)` + sepStart + `(\d{1,2})x(\d{2,3})(?:-?x(\d{2,3}))?` + sepEnd)
+	dailyRe    = regexp.MustCompile(`(?:^|[^0-9])((?:19|20)\d{2})[ ._-](\d{2})[ ._-](\d{2})(?:[^0-9]|$)`)
+	seasonRe   = regexp.MustCompile(`(?i)` + sepStart + `(?:s|season[ ._]?)(\d{1,3})` + sepEnd)
+	episodeNum = regexp.MustCompile(`(?i)(-)?e?(\d{1,4})`)
+	yearRe     = regexp.MustCompile(`^(.+?)[ ]\(?((?:19|20)\d{2})\)?$`)
+	groupRe    = regexp.MustCompile(`-([a-zA-Z0-9]+)$`)
+
+	resolutionRe = regexp.MustCompile(`(?i)` + sepStart + `(2160|1080|720|576|480|360)[pi]` + sepEnd)
+	uhdRe        = regexp.MustCompile(`(?i)` + sepStart + `(4k|uhd)` + sepEnd)
+	properRe     = regexp.MustCompile(`(?i)` + sepStart + `proper` + sepEnd)
+	repackRe     = regexp.MustCompile(`(?i)` + sepStart + `(repack|rerip)` + sepEnd)
+	realRe       = regexp.MustCompile(sepStart + `REAL` + sepEnd)
+	channelsRe   = regexp.MustCompile(`(?:^|[^0-9])([1-9]\.[0-2])(?:[^0-9]|$)`)
+	atmosRe      = regexp.MustCompile(`(?i)` + sepStart + `atmos` + sepEnd)
+)
+
+// releaseToken a value found in a release name when its pattern match.
+type releaseToken struct {
+	re    *regexp.Regexp
+	value string
+	// pattern of the token without the separators, see tagWords.
+	pattern string
+}
+
+func tokens(pairs ...string) []releaseToken {
+	t := make([]releaseToken, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		re := regexp.MustCompile(`(?i)` + sepStart + `(?:` + pairs[i] + `)` + sepEnd)
+		t = append(t, releaseToken{re: re, value: pairs[i+1], pattern: pairs[i]})
+	}
+	return t
+}
+
+// tagWords return a regexp matching the tag words at the end of a string, e.g.
+// ".ITA.DV.HDR" in S01E01.Title.ITA.DV.HDR, the words are the patterns of ts and
+// extra.
+func tagWords(extra []string, ts ...[]releaseToken) *regexp.Regexp {
+	words := extra
+	for _, t := range ts {
+		for _, token := range t {
+			words = append(words, token.pattern)
+		}
+	}
+	return regexp.MustCompile(`(?i)(?:(?:^|[ ._\[(-])(?:` + strings.Join(words, "|") + `))+[ ._\])-]*$`)
+}
+
+// first return the value of the first matching token.
+func first(s string, ts []releaseToken) string {
+	for _, t := range ts {
+		if t.re.MatchString(s) {
+			return t.value
+		}
+	}
+	return ""
+}
+
+var (
+	// sources are checked in order, remux and raw before bluray and television.
+	sourceTokens = tokens(
+		`(?:bd|blu[ ._-]?ray)?[ ._-]?remux`, "remux",
+		`raw[ ._-]?hd`, "raw",
+		`web[ ._-]?rip`, string(SourceWebRip),
+		`web[ ._-]?dl|webhd|web`, string(SourceWeb),
+		`blu[ ._-]?ray|bdrip|brrip|bd25|bd50|bd`, string(SourceBluray),
+		`hdtv|pdtv|sdtv|dsr|tvrip`, string(SourceTelevision),
+		`dvdrip|dvdr|dvd5|dvd9|dvd`, string(SourceDVD),
+		`amzn|nf|dsnp|hmax|atvp|hulu|pcok`, string(SourceWeb),
+	)
+	codecTokens = tokens(
+		`x265`, "x265",
+		`x264`, "x264",
+		`h[ .]?265|hevc`, "H.265",
+		`h[ .]?264|avc`, "H.264",
+		`xvid|divx`, "XviD",
+		`av1`, "AV1",
+		`vc[ .-]?1`, "VC-1",
+		`mpeg[ .-]?2`, "MPEG2",
+	)
+	// audio tokens allow the channels after them, e.g. DDP5.1.
+	audioTokens = []releaseToken{
+		{re: regexp.MustCompile(`(?i)` + sepStart + `truehd`), value: "TrueHD"},
+		{re: regexp.MustCompile(`(?i)` + sepStart + `dts[ .-]?hd[ .-]?ma`), value: "DTS-HD MA"},
+		{re: regexp.MustCompile(`(?i)` + sepStart + `dts[ .-]?x` + sepEnd), value: "DTS:X"},
+		{re: regexp.MustCompile(`(?i)` + sepStart + `dts[ .-]?hd`), value: "DTS-HD"},
+		{re: regexp.MustCompile(`(?i)` + sepStart + `dts(?:[^a-z]|$)`), value: "DTS"},
+		{re: regexp.MustCompile(`(?i)` + sepStart + `(?:ddp|dd\+|e-?ac-?3)(?:[^a-z]|$)`), value: "EAC3"},
+		{re: regexp.MustCompile(`(?i)` + sepStart + `(?:dd|ac-?3)(?:[^a-z]|$)`), value: "AC3"},
+		{re: regexp.MustCompile(`(?i)` + sepStart + `aac(?:[^a-z]|$)`), value: "AAC"},
+		{re: regexp.MustCompile(`(?i)` + sepStart + `flac(?:[^a-z]|$)`), value: "FLAC"},
+		{re: regexp.MustCompile(`(?i)` + sepStart + `opus(?:[^a-z]|$)`), value: "Opus"},
+		{re: regexp.MustCompile(`(?i)` + sepStart + `mp3(?:[^a-z]|$)`), value: "MP3"},
+	}
+	hdrTokens = tokens(
+		`dv|dovi|dolby[ ._]?vision`, "DV",
+		`hdr10\+|hdr10plus`, "HDR10+",
+		`hdr10`, "HDR10",
+		`hlg`, "HLG",
+	)
+	hdrRe          = regexp.MustCompile(`(?i)` + sepStart + `hdr` + sepEnd)
+	languageTokens = tokens(
+		`multi|dual[ ._-]?audio`, "Multi",
+		`french|truefrench`, "French",
+		`german`, "German",
+		`italian`, "Italian",
+		`spanish|castellano|latino`, "Spanish",
+		`japanese`, "Japanese",
+		`russian`, "Russian",
+		`dutch|flemish`, "Dutch",
+		`portuguese`, "Portuguese",
+		`korean`, "Korean",
+		`chinese`, "Chinese",
+		`polish`, "Polish",
+		`swedish`, "Swedish",
+		`norwegian`, "Norwegian",
+		`danish`, "Danish",
+		`finnish`, "Finnish",
+		`hindi`, "Hindi",
+		`turkish`, "Turkish",
+		`arabic`, "Arabic",
+	)
+	// languageCodeTokens short language codes, they are common words of episode titles
+	// so they are only searched in the tags of the name like the other tokens, see
+	// tagStart, and they are not enough to start the tags.
+	languageCodeTokens = tokens(
+		`vff|vf2?`, "French",
+		`ita`, "Italian",
+		`esp`, "Spanish",
+		`jap`, "Japanese",
+		`rus`, "Russian",
+		`por`, "Portuguese",
+		`kor`, "Korean",
+		`chs|cht`, "Chinese",
+	)
+	// tagWordsRe tags right before the resolution, e.g. ".ITA.DV.HDR" in
+	// S01E01.ITA.DV.HDR.2160p, or before the last source or codec of names without
+	// resolution.
+	tagWordsRe = tagWords([]string{
+		`hdr`, `4k|uhd`, `proper|repack|rerip|real|internal`, `eng|subs?|dubbed|subbed|dl`,
+		`hybrid|remastered|10bit`, `(?:ddp|dd\+?|e-?ac-?3|ac-?3|aac|flac|opus|mp3|truehd|atmos|dts(?:[ .-]?(?:hd|ma|x))*)(?:[ .]?[1-9][ .][0-2])?`,
+	}, sourceTokens, codecTokens, hdrTokens, languageTokens, languageCodeTokens)
+	// groupExclude words after the last dash which are not release groups.
+	groupExclude = map[string]bool{"dl": true, "rip": true, "hd": true, "ray": true, "x264": true, "x265": true}
+)
+
+// ParseRelease parse a scene or P2P release name, e.g. GrabEvent.Release.ReleaseTitle
+// or EpisodeFile.SceneName, it supports standard (S01E02, 1x02), multi episodes
+// (S01E02-E04), season packs, daily (2023.01.02) and anime ([Group] Title - 012)
+// names. the video extension is ignored, so file names can be parsed too.
+func ParseRelease(name string) ReleaseInfo {
+	var r ReleaseInfo
+	name = strings.TrimSpace(videoExtRe.ReplaceAllString(path.Base(strings.ReplaceAll(name, `\`, "/")), ""))
+
+	// tail is the part after the title, quality tokens are only searched in it so
+	// words of the title are not mistaken for them.
+	tail := name
+	if m := animeRe.FindStringSubmatchIndex(name); m != nil {
+		r.Group = name[m[2]:m[3]]
+		r.Title = cleanTitle(name[m[4]:m[5]])
+		start, _ := strconv.Atoi(name[m[6]:m[7]])
+		end := start
+		if m[8] >= 0 {
+			end, _ = strconv.Atoi(name[m[8]:m[9]])
+		}
+		r.AbsoluteEpisodes = episodeRange(start, end)
+		if m[10] >= 0 {
+			r.Quality.Revision.Version, _ = strconv.Atoi(name[m[10]:m[11]])
+		}
+		tail = name[m[7]:]
+	} else {
+		tail = r.parseNumbering(name)
+		r.Group = releaseGroup(name)
+	}
+
+	// the episode title may contain tokens, e.g. Web of Lies, so they are only
+	// searched in the tags.
+	tags := tail[tagStart(tail):]
+	r.parseQuality(tail, tags)
+	r.Codec = first(tags, codecTokens)
+	r.Audio = first(tags, audioTokens)
+	if r.Audio != "" {
+		if m := channelsRe.FindStringSubmatch(tags); m != nil {
+			r.AudioChannels = m[1]
+		}
+		if atmosRe.MatchString(tags) {
+			r.Audio += " Atmos"
+		}
+	}
+	for _, t := range hdrTokens {
+		if t.re.MatchString(tags) && !(t.value == "HDR10" && containsString(r.HDR, "HDR10+")) {
+			r.HDR = append(r.HDR, t.value)
+		}
+	}
+	if len(r.HDR) == 0 && hdrRe.MatchString(tags) {
+		r.HDR = []string{"HDR"}
+	}
+	for _, ts := range [][]releaseToken{languageTokens, languageCodeTokens} {
+		for _, t := range ts {
+			if t.re.MatchString(tags) && !containsString(r.Languages, t.value) {
+				r.Languages = append(r.Languages, t.value)
+			}
+		}
+	}
+	return r
+}
+
+// tagStart index of the tags in tail: the first resolution, or the last source or
+// codec token of names without resolution, with the tag words right before it, see
+// tagWordsRe. it is the end of tail if there is none, so only trailing tag words are
+// tags.
+func tagStart(tail string) int {
+	start := -1
+	for _, re := range []*regexp.Regexp{resolutionRe, uhdRe} {
+		if m := re.FindStringIndex(tail); m != nil && (start < 0 || m[0] < start) {
+			start = m[0]
+		}
+	}
+	if start < 0 {
+		for _, ts := range [][]releaseToken{sourceTokens, codecTokens} {
+			for _, t := range ts {
+				for _, m := range t.re.FindAllStringIndex(tail, -1) {
+					if m[0] > start {
+						start = m[0]
+					}
+				}
+			}
+		}
+	}
+	if start < 0 {
+		start = len(tail)
+	}
+	if m := tagWordsRe.FindStringIndex(tail[:start]); m != nil {
+		start = m[0]
+	}
+	return start
+}
+
+// parseNumbering parse the title and the episode numbering, season pack or air date
+// of standard and daily releases, it returns the rest of the name after the title.
+func (r *ReleaseInfo) parseNumbering(name string) string {
+	title := func(end int) {
+		r.Title = cleanTitle(name[:end])
+		if m := yearRe.FindStringSubmatch(r.Title); m != nil {
+			r.Title = m[1]
+			r.Year, _ = strconv.Atoi(m[2])
+		}
+	}
+
+	if m := episodeRe.FindStringSubmatchIndex(name); m != nil {
+		r.Season, _ = strconv.Atoi(name[m[2]:m[3]])
+		prev := 0
+		for _, n := range episodeNum.FindAllStringSubmatch(name[m[4]:m[5]], -1) {
+			ep, _ := strconv.Atoi(n[2])
+			if n[1] == "-" && ep > prev {
+				r.Episodes = append(r.Episodes, episodeRange(prev+1, ep)...)
+			} else {
+				r.Episodes = append(r.Episodes, ep)
+			}
+			prev = ep
+		}
+		title(m[0])
+		return name[m[5]:]
+	}
+	if m := crossRe.FindStringSubmatchIndex(name); m != nil {
+		r.Season, _ = strconv.Atoi(name[m[2]:m[3]])
+		start, _ := strconv.Atoi(name[m[4]:m[5]])
+		end := start
+		if m[6] >= 0 {
+			end, _ = strconv.Atoi(name[m[6]:m[7]])
+		}
+		r.Episodes = episodeRange(start, end)
+		title(m[0])
+		return name[m[5]:]
+	}
+	if m := dailyRe.FindStringSubmatchIndex(name); m != nil {
+		date := name[m[2]:m[3]] + "-" + name[m[4]:m[5]] + "-" + name[m[6]:m[7]]
+		if _, err := time.Parse("2006-01-02", date); err == nil {
+			r.AirDate = date
+			title(m[0])
+			return name[m[7]:]
+		}
+	}
+	if m := seasonRe.FindStringSubmatchIndex(name); m != nil {
+		r.Season, _ = strconv.Atoi(name[m[2]:m[3]])
+		r.FullSeason = true
+		title(m[0])
+		return name[m[3]:]
+	}
+
+	// no numbering, the title ends before the first quality token.
+	end := len(name)
+	for _, re := range []*regexp.Regexp{resolutionRe, uhdRe} {
+		if m := re.FindStringIndex(name); m != nil && m[0] < end {
+			end = m[0]
+		}
+	}
+	for _, t := range sourceTokens {
+		if m := t.re.FindStringIndex(name); m != nil && m[0] < end {
+			end = m[0]
+		}
+	}
+	title(end)
+	return name[end:]
+}
+
+// parseQuality parse the resolution and source of the tags and the revision of the
+// tail into r.Quality.
+func (r *ReleaseInfo) parseQuality(tail, tags string) {
+	q := Quality{Source: SourceUnknown, Revision: r.Quality.Revision}
+	if m := resolutionRe.FindStringSubmatch(tags); m != nil {
+		q.Resolution, _ = strconv.Atoi(m[1])
+	} else if uhdRe.MatchString(tags) {
+		q.Resolution = 2160
+	}
+
+	switch source := first(tags, sourceTokens); source {
+	case "remux":
+		q.Source, q.Modifier = SourceBluray, ModifierRemux
+	case "raw":
+		q.Source, q.Modifier = SourceTelevision, ModifierRaw
+	case "":
+	default:
+		q.Source = QualitySource(source)
+	}
+
+	// the anime version, e.g. 01v2, is kept when it is higher.
+	rev := newRevision(properRe.MatchString(tail), repackRe.MatchString(tail), realRe.MatchString(tail))
+	if q.Revision.Version > rev.Version {
+		rev.Version = q.Revision.Version
+	}
+	q.Revision = rev
+
+	// like Sonarr, names with a resolution and without source are television.
+	if q.Source == SourceUnknown && q.Resolution > 0 {
+		q.Source = SourceTelevision
+	}
+	switch {
+	case q.Source == SourceTelevision && q.Modifier == "" && q.Resolution <= 576:
+		q.Name = SDTV.Name
+	case q.Source == SourceDVD:
+		q.Name, q.Resolution = DVD.Name, DVD.Resolution
+	case q.Modifier == ModifierRaw:
+		q.Name, q.Resolution = RawHD.Name, RawHD.Resolution
+	default:
+		if def, ok := q.definition(); ok && q.Source != SourceUnknown {
+			q.Name = def.quality.Name
+		}
+	}
+	r.Quality = q
+}
+
+// cleanTitle replace dots and underscores by spaces and trim separators.
+func cleanTitle(s string) string {
+	s = strings.NewReplacer(".", " ", "_", " ").Replace(s)
+	return strings.Trim(strings.Join(strings.Fields(s), " "), " -")
+}
+
+// releaseGroup return the group after the last dash, trailing tags like [eztv] are ignored.
+func releaseGroup(name string) string {
+	for trailingTagRe.MatchString(name) {
+		name = strings.TrimSpace(trailingTagRe.ReplaceAllString(name, ""))
+	}
+	m := groupRe.FindStringSubmatch(name)
+	if m == nil || groupExclude[strings.ToLower(m[1])] || resolutionRe.MatchString(m[1]) {
+		return ""
+	}
+	return m[1]
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func episodeRange(start, end int) []int {
+	if end < start || end-start > 100 {
+		return []int{start}
+	}
+	episodes := make([]int, 0, end-start+1)
+	for n := start; n <= end; n++ {
+		episodes = append(episodes, n)
+	}
+	return episodes
+}
+
+// ReleaseInfo parse the release title, see ParseRelease.
+func (e GrabEvent) ReleaseInfo() ReleaseInfo {
+	return ParseRelease(e.Release.ReleaseTitle)
+}
+
+// ReleaseInfo parse the scene name of the file, or its file name when Sonarr does
+// not know the scene name, see ParseRelease.
+func (f EpisodeFile) ReleaseInfo() ReleaseInfo {
+	if f.SceneName != "" {
+		return ParseRelease(f.SceneName)
+	}
+	return ParseRelease(f.RelativePath)
+}
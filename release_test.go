@@ -0,0 +1,319 @@
+package eventt
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseRelease(t *testing.T) {
+	tests := []struct {
+		name string
+		// want ReleaseInfo fields formatted by summary.
+		want string
+	}{
+		// scene
+		{
+			"The.Mandalorian.S02E05.1080p.WEB.H264-GLHF",
+			"The Mandalorian|0|S2[5]|||WEBDL-1080p|H.264||[]|GLHF|[]",
+		},
+		{
+			"Breaking.Bad.S05.720p.BluRay.x264-DEMAND",
+			"Breaking Bad|0|S5[] pack|||Bluray-720p|x264||[]|DEMAND|[]",
+		},
+		{
+			"Friends.S01E01-E03.1080p.BluRay.x265-RARBG",
+			"Friends|0|S1[1 2 3]|||Bluray-1080p|x265||[]|RARBG|[]",
+		},
+		{
+			"Doctor.Who.1x02.The.End.of.the.World.DVDRip.XviD-FoV",
+			"Doctor Who|0|S1[2]|||DVD|XviD||[]|FoV|[]",
+		},
+		{
+			"Show.S02E03.REAL.720p.HDTV.x264-GRP",
+			"Show|0|S2[3]|||HDTV-720p REAL|x264||[]|GRP|[]",
+		},
+		{
+			"Show.S01E01.PROPER.REPACK.1080p.WEB.h264-GRP",
+			"Show|0|S1[1]|||WEBDL-1080p Proper Repack|H.264||[]|GRP|[]",
+		},
+		{
+			"The.Simpsons.S34E01.720p.HDTV.x264-SYNCOPY",
+			"The Simpsons|0|S34[1]|||HDTV-720p|x264||[]|SYNCOPY|[]",
+		},
+		{
+			"Modern.Family.S11E18.HDTV.x264-SVA",
+			"Modern Family|0|S11[18]|||SDTV|x264||[]|SVA|[]",
+		},
+		{
+			"Blue.Bloods.S13E01.Loyalty.Test.720p.HDTV.x265-MiNX",
+			"Blue Bloods|0|S13[1]|||HDTV-720p|x265||[]|MiNX|[]",
+		},
+		{
+			"Fargo.S05E01.The.Tragedy.of.the.Commons.720p.HDTV.x264-SYNCOPY[eztv.re].mkv",
+			"Fargo|0|S5[1]|||HDTV-720p|x264||[]|SYNCOPY|[]",
+		},
+		{
+			"Shameless.US.S01E01E02.720p.BluRay.x264-DEMAND",
+			"Shameless US|0|S1[1 2]|||Bluray-720p|x264||[]|DEMAND|[]",
+		},
+		{
+			"Lost.S01E01E02.Pilot.1080p.BluRay.x264-ROVERS",
+			"Lost|0|S1[1 2]|||Bluray-1080p|x264||[]|ROVERS|[]",
+		},
+		{
+			"Lost.1x02-x03.Pilot.Part.2.DVDRip.XviD-SAiNTS",
+			"Lost|0|S1[2 3]|||DVD|XviD||[]|SAiNTS|[]",
+		},
+		{
+			"Seinfeld.S09E23-E24.The.Finale.1080p.WEB.H264-GRP",
+			"Seinfeld|0|S9[23 24]|||WEBDL-1080p|H.264||[]|GRP|[]",
+		},
+		{
+			"The.Sopranos.Season.1.1080p.BluRay.x264-GRP",
+			"The Sopranos|0|S1[] pack|||Bluray-1080p|x264||[]|GRP|[]",
+		},
+		{
+			"Dexter.S01.COMPLETE.720p.BluRay.x264-GRP",
+			"Dexter|0|S1[] pack|||Bluray-720p|x264||[]|GRP|[]",
+		},
+		{
+			"Dark.S01E01.German.DL.1080p.NF.WEBRip.x264-GRP",
+			"Dark|0|S1[1]|||WEBRip-1080p|x264||[]|GRP|[German]",
+		},
+		{
+			"Money.Heist.S05E01.SPANISH.720p.WEBRip.x264-GRP",
+			"Money Heist|0|S5[1]|||WEBRip-720p|x264||[]|GRP|[Spanish]",
+		},
+		// P2P
+		{
+			"The.Boys.S03E01.Payback.2160p.AMZN.WEB-DL.DDP5.1.HDR.HEVC-NTb",
+			"The Boys|0|S3[1]|||WEBDL-2160p|H.265|EAC3 5.1|[HDR]|NTb|[]",
+		},
+		{
+			"Doctor.Who.2005.S13E01.1080p.BluRay.TrueHD.Atmos.7.1.DV.HDR10.x265-GRP.mkv",
+			"Doctor Who|2005|S13[1]|||Bluray-1080p|x265|TrueHD Atmos 7.1|[DV HDR10]|GRP|[]",
+		},
+		{
+			"La.Casa.De.Papel.S05E01.ITA.ENG.1080p.NF.WEB-DLMux.DDP5.1.H.264-MeM",
+			"La Casa De Papel|0|S5[1]|||WEBDL-1080p|H.264|EAC3 5.1|[]|MeM|[Italian]",
+		},
+		{
+			"Lupin.S01E01.MULTi.1080p.NF.WEB-DL.x264-FRATERNiTY",
+			"Lupin|0|S1[1]|||WEBDL-1080p|x264||[]|FRATERNiTY|[Multi]",
+		},
+		{
+			"Show.S01E01.VFF.1080p.WEB.x264-GRP",
+			"Show|0|S1[1]|||WEBDL-1080p|x264||[]|GRP|[French]",
+		},
+		{
+			"Game.of.Thrones.S08E03.The.Long.Night.1080p.AMZN.WEB-DL.DDP5.1.H.264-GoT",
+			"Game of Thrones|0|S8[3]|||WEBDL-1080p|H.264|EAC3 5.1|[]|GoT|[]",
+		},
+		{
+			"The.Office.US.S05E13.Lecture.Circuit.Part.1.720p.NF.WEB-DL.DD5.1.x264-NTb",
+			"The Office US|0|S5[13]|||WEBDL-720p|x264|AC3 5.1|[]|NTb|[]",
+		},
+		{
+			"Brooklyn.Nine-Nine.S06E01.Honeymoon.720p.AMZN.WEB-DL.DDP5.1.H.264-NTb",
+			"Brooklyn Nine-Nine|0|S6[1]|||WEBDL-720p|H.264|EAC3 5.1|[]|NTb|[]",
+		},
+		{
+			"The.Expanse.S06E01.Strange.Dogs.REPACK.1080p.AMZN.WEB-DL.DDP5.1.H.264-NTb",
+			"The Expanse|0|S6[1]|||WEBDL-1080p Repack|H.264|EAC3 5.1|[]|NTb|[]",
+		},
+		{
+			"House.of.the.Dragon.S01E01.The.Heirs.of.the.Dragon.2160p.HMAX.WEB-DL.DDP5.1.Atmos.DV.HDR.H.265-NTb",
+			"House of the Dragon|0|S1[1]|||WEBDL-2160p|H.265|EAC3 Atmos 5.1|[DV]|NTb|[]",
+		},
+		{
+			"Severance.S01E01.Good.News.About.Hell.2160p.ATVP.WEB-DL.DDP5.1.Atmos.HDR.H.265-TOMMY",
+			"Severance|0|S1[1]|||WEBDL-2160p|H.265|EAC3 Atmos 5.1|[HDR]|TOMMY|[]",
+		},
+		{
+			"The.Last.of.Us.S01E03.Long.Long.Time.DV.HDR.2160p.WEB.H265-GGEZ",
+			"The Last of Us|0|S1[3]|||WEBDL-2160p|H.265||[DV]|GGEZ|[]",
+		},
+		{
+			"Westworld.S04E02.Well.Enough.Alone.2160p.HMAX.WEB-DL.x265.10bit.HDR.DDP5.1-SMURF",
+			"Westworld|0|S4[2]|||WEBDL-2160p|x265|EAC3 5.1|[HDR]|SMURF|[]",
+		},
+		{
+			"The.Wire.S03.1080p.BluRay.x265.10bit.AAC.5.1-Joy",
+			"The Wire|0|S3[] pack|||Bluray-1080p|x265|AAC 5.1|[]|Joy|[]",
+		},
+		{
+			"Chernobyl.S01.2160p.UHD.BluRay.Remux.HDR.HEVC.DTS-HD.MA.5.1-GRP",
+			"Chernobyl|0|S1[] pack|||Bluray-2160p Remux|H.265|DTS-HD MA 5.1|[HDR]|GRP|[]",
+		},
+		{
+			"Narcos.S01E01.MULTi.1080p.WEBRip.x265-GRP",
+			"Narcos|0|S1[1]|||WEBRip-1080p|x265||[]|GRP|[Multi]",
+		},
+		// source, codec, HDR and language words in episode titles are not tags.
+		{
+			"Show.S01E05.Web.of.Lies.720p.HDTV.x264-GRP",
+			"Show|0|S1[5]|||HDTV-720p|x264||[]|GRP|[]",
+		},
+		{
+			"Show.S01E05.Web.of.Lies.HDTV.x264-GRP",
+			"Show|0|S1[5]|||SDTV|x264||[]|GRP|[]",
+		},
+		{
+			"Show.S01E05.Bd.Party.720p.HDTV.x264-GRP",
+			"Show|0|S1[5]|||HDTV-720p|x264||[]|GRP|[]",
+		},
+		{
+			"Show.S01E05.DV.Is.Here.720p.HDTV.x264-GRP",
+			"Show|0|S1[5]|||HDTV-720p|x264||[]|GRP|[]",
+		},
+		{
+			"Show.S01E01.Hlg.Party.1080p.WEB.H264-GRP",
+			"Show|0|S1[1]|||WEBDL-1080p|H.264||[]|GRP|[]",
+		},
+		{
+			"Show.S01E02.AVC.Explained.720p.HDTV.x264-GRP",
+			"Show|0|S1[2]|||HDTV-720p|x264||[]|GRP|[]",
+		},
+		{
+			"Show.S01E03.The.Dvd.720p.HDTV.x264-GRP",
+			"Show|0|S1[3]|||HDTV-720p|x264||[]|GRP|[]",
+		},
+		{
+			"Show.S01E05.French.Kiss.720p.HDTV.x264-GRP",
+			"Show|0|S1[5]|||HDTV-720p|x264||[]|GRP|[]",
+		},
+		{
+			"Show.S02E01.Hdtv.Killed.The.Radio.Star.1080p.AMZN.WEB-DL.DDP5.1.H.264-NTb",
+			"Show|0|S2[1]|||WEBDL-1080p|H.264|EAC3 5.1|[]|NTb|[]",
+		},
+		// short language codes in episode titles are not languages.
+		{
+			"Taskmaster.S15E03.Por.Favor.1080p.WEB.h264-GRP",
+			"Taskmaster|0|S15[3]|||WEBDL-1080p|H.264||[]|GRP|[]",
+		},
+		{
+			"Show.S01E04.The.Rus.Connection.720p.HDTV.x264-KILLERS",
+			"Show|0|S1[4]|||HDTV-720p|x264||[]|KILLERS|[]",
+		},
+		// anime
+		{
+			"[SubsPlease] Jujutsu Kaisen - 24 (1080p) [A1B2C3D4].mkv",
+			"Jujutsu Kaisen|0|S0[]|[24]||HDTV-1080p|||[]|SubsPlease|[]",
+		},
+		{
+			"[Erai-raws] Spy x Family - 05v2 [1080p][Multiple Subtitle].mkv",
+			"Spy x Family|0|S0[]|[5]||HDTV-1080p Proper|||[]|Erai-raws|[]",
+		},
+		{
+			"[SubsPlease] One Piece - 1080 (1080p) [ABCD1234].mkv",
+			"One Piece|0|S0[]|[1080]||HDTV-1080p|||[]|SubsPlease|[]",
+		},
+		{
+			"[HorribleSubs] Boku no Hero Academia - 88 [720p].mkv",
+			"Boku no Hero Academia|0|S0[]|[88]||HDTV-720p|||[]|HorribleSubs|[]",
+		},
+		{
+			"[Erai-raws] Kimetsu no Yaiba - Katanakaji no Sato-hen - 01 [1080p][Multiple Subtitle][ENG][POR-BR].mkv",
+			"Kimetsu no Yaiba - Katanakaji no Sato-hen|0|S0[]|[1]||HDTV-1080p|||[]|Erai-raws|[Portuguese]",
+		},
+		{
+			"[Golumpa] Fairy Tail - 214 [FuniDub 720p x264 AAC] [5E46AC39].mkv",
+			"Fairy Tail|0|S0[]|[214]||HDTV-720p|x264|AAC|[]|Golumpa|[]",
+		},
+		{
+			"[SubsPlease] Bocchi the Rock! - 12v2 (720p) [E1A2B3C4].mkv",
+			"Bocchi the Rock!|0|S0[]|[12]||HDTV-720p Proper|||[]|SubsPlease|[]",
+		},
+		{
+			"[ASW] Mushoku Tensei S2 - 01 [1080p HEVC x265 10Bit][AAC]",
+			"Mushoku Tensei S2|0|S0[]|[1]||HDTV-1080p|x265|AAC|[]|ASW|[]",
+		},
+		{
+			"[SubsPlease] Frieren - 05-06 (1080p) [Batch]",
+			"Frieren|0|S0[]|[5 6]||HDTV-1080p|||[]|SubsPlease|[]",
+		},
+		{
+			"[Judas] Vinland Saga - 01-24 (1080p) [HEVC x265 10bit] [Dual-Audio]",
+			"Vinland Saga|0|S0[]|[1 2 3 4 5 6 7 8 9 10 11 12 13 14 15 16 17 18 19 20 21 22 23 24]||HDTV-1080p|x265||[]|Judas|[Multi]",
+		},
+		{
+			"[EMBER] Jujutsu Kaisen - 01-24 [1080p] [HEVC WEBRip] (Season 1)",
+			"Jujutsu Kaisen|0|S0[]|[1 2 3 4 5 6 7 8 9 10 11 12 13 14 15 16 17 18 19 20 21 22 23 24]||WEBRip-1080p|H.265||[]|EMBER|[]",
+		},
+		// daily
+		{
+			"The.Daily.Show.2023.01.02.Guest.Name.720p.WEB.h264-EDITH",
+			"The Daily Show|0|S0[]||2023-01-02|WEBDL-720p|H.264||[]|EDITH|[]",
+		},
+		{
+			"The.Tonight.Show.Starring.Jimmy.Fallon.2023.10.17.Pedro.Pascal.1080p.WEB.h264-EDITH",
+			"The Tonight Show Starring Jimmy Fallon|0|S0[]||2023-10-17|WEBDL-1080p|H.264||[]|EDITH|[]",
+		},
+		{
+			"Jeopardy.2023.10.17.720p.HDTV.x264-NTb",
+			"Jeopardy|0|S0[]||2023-10-17|HDTV-720p|x264||[]|NTb|[]",
+		},
+		{
+			"Last.Week.Tonight.with.John.Oliver.2023.10.15.1080p.WEB.h264-GRP",
+			"Last Week Tonight with John Oliver|0|S0[]||2023-10-15|WEBDL-1080p|H.264||[]|GRP|[]",
+		},
+		{
+			"WWE.Raw.2023.10.16.720p.HDTV.x264-Star",
+			"WWE Raw|0|S0[]||2023-10-16|HDTV-720p|x264||[]|Star|[]",
+		},
+		{
+			"The.Late.Show.with.Stephen.Colbert.2023.01.02.Bluray.Guest.720p.WEB.h264-KOGi",
+			"The Late Show with Stephen Colbert|0|S0[]||2023-01-02|WEBDL-720p|H.264||[]|KOGi|[]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := summary(ParseRelease(tt.name)); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// summary format the fields of r checked by TestParseRelease separated by |.
+func summary(r ReleaseInfo) string {
+	numbering := fmt.Sprintf("S%d%v", r.Season, r.Episodes)
+	if r.FullSeason {
+		numbering += " pack"
+	}
+	absolute := ""
+	if len(r.AbsoluteEpisodes) > 0 {
+		absolute = fmt.Sprint(r.AbsoluteEpisodes)
+	}
+	audio := strings.TrimSpace(r.Audio + " " + r.AudioChannels)
+	return strings.Join([]string{
+		r.Title, fmt.Sprint(r.Year), numbering, absolute, r.AirDate, r.Quality.String(),
+		r.Codec, audio, fmt.Sprint(r.HDR), r.Group, fmt.Sprint(r.Languages),
+	}, "|")
+}
+
+func TestParseReleaseRevision(t *testing.T) {
+	tests := []struct {
+		name   string
+		want   Revision
+		proper bool
+	}{
+		{"Show.S01E01.1080p.WEB.h264-GRP", Revision{Version: 1}, false},
+		{"Show.S01E01.PROPER.1080p.WEB.h264-GRP", Revision{Version: 2}, true},
+		{"Show.S01E01.REPACK.1080p.WEB.h264-GRP", Revision{Version: 2, IsRepack: true}, false},
+		{"Show.S01E01.PROPER.REPACK.1080p.WEB.h264-GRP", Revision{Version: 3, IsRepack: true}, true},
+		{"Show.S01E01.REAL.PROPER.1080p.WEB.h264-GRP", Revision{Version: 2, Real: 1}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rev := ParseRelease(tt.name).Quality.Revision
+			if rev != tt.want || rev.IsProper() != tt.proper {
+				t.Errorf("got %+v proper %v, want %+v proper %v", rev, rev.IsProper(), tt.want, tt.proper)
+			}
+		})
+	}
+	if ParseRelease("Show.S01E01.PROPER.REPACK.1080p.WEB.h264-GRP").Quality.Compare(ParseRelease("Show.S01E01.PROPER.1080p.WEB.h264-GRP").Quality) != 1 {
+		t.Error("a repack of a proper should be better than the proper")
+	}
+}
@@ -0,0 +1,268 @@
+package eventt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:generate go run ./cmd/eventt schema -out schema
+
+// SchemaBaseURL base of the schemas $id, each schema is <SchemaBaseURL>/<EventType>.json.
+const SchemaBaseURL = "https://raw.githubusercontent.com/k-x7/eventt/main/schema"
+
+// ErrInvalidPayload matched by errors.Is for ValidationError.
+var ErrInvalidPayload = errors.New("invalid payload")
+
+// SchemaError single validation failure, Path is the location in the payload,
+// e.g. $.episodes[0].airDateUtc.
+type SchemaError struct {
+	Path    string
+	Message string
+}
+
+func (e SchemaError) String() string {
+	return e.Path + ": " + e.Message
+}
+
+// ValidationError returned when a payload does not match its event schema, it is
+// reported to OnError when SonarrTriggers.ValidateSchema is set.
+type ValidationError struct {
+	Event  EventType
+	Errors []SchemaError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, se := range e.Errors {
+		msgs[i] = se.String()
+	}
+	if e.Event == "" {
+		return fmt.Sprintf("%s: %s", ErrInvalidPayload, strings.Join(msgs, "; "))
+	}
+	return fmt.Sprintf("%s: payload does not match '%s' schema: %s", ErrInvalidPayload, e.Event, strings.Join(msgs, "; "))
+}
+
+// Is report whether target is ErrInvalidPayload.
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidPayload
+}
+
+// jsonSchema subset of JSON Schema draft 2020-12 generated from the event structs.
+type jsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	ID                   string                 `json:"$id,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Type                 []string               `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Const                interface{}            `json:"const,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	AdditionalProperties *jsonSchema            `json:"additionalProperties,omitempty"`
+}
+
+var (
+	schemasOnce sync.Once
+	schemas     map[EventType]*jsonSchema
+)
+
+// SchemaTypes event types which have a schema, all except Unknown.
+func SchemaTypes() []EventType {
+	return []EventType{Grab, Download, Rename, EpisodeFileDelete, SeriesDelete, Health, ApplicationUpdate, Test}
+}
+
+// Schema return the JSON Schema of the event type payload generated from its struct,
+// e.g. GrabEvent for Grab. Sonarr may add fields in new versions, so unknown fields
+// are allowed and all fields except eventType are optional and nullable, as they
+// are when decoded into the structs.
+func Schema(t EventType) ([]byte, error) {
+	s, ok := loadSchemas()[t]
+	if !ok {
+		return nil, fmt.Errorf("no schema for event type '%s'", t)
+	}
+	return json.MarshalIndent(s, "", "  ")
+}
+
+// ValidatePayload check the payload against its event schema, it returns a
+// *ValidationError with all failures. payloads without eventType are invalid, events
+// without schema, like the types not known by this library, are not checked.
+func ValidatePayload(payload []byte) error {
+	eventType, err := parseType(payload)
+	if err != nil {
+		return err
+	}
+	if eventType == "" {
+		return &ValidationError{Errors: []SchemaError{{Path: "$.eventType", Message: "required"}}}
+	}
+	s, ok := loadSchemas()[EventType(eventType)]
+	if !ok {
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return fmt.Errorf("error parsing payload: %w", err)
+	}
+	var errs []SchemaError
+	validate(v, s, "$", &errs)
+	if len(errs) > 0 {
+		return &ValidationError{Event: EventType(eventType), Errors: errs}
+	}
+	return nil
+}
+
+func loadSchemas() map[EventType]*jsonSchema {
+	schemasOnce.Do(func() {
+		schemas = make(map[EventType]*jsonSchema)
+		for _, t := range SchemaTypes() {
+			st := eventStructs[t]
+			s := schemaOf(st)
+			s.Schema = "https://json-schema.org/draft/2020-12/schema"
+			s.ID = fmt.Sprintf("%s/%s.json", SchemaBaseURL, t)
+			s.Title = st.Name()
+			s.Type = []string{"object"}
+			s.Properties["eventType"] = &jsonSchema{Type: []string{"string"}, Const: string(t)}
+			s.Required = []string{"eventType"}
+			schemas[t] = s
+		}
+	})
+	return schemas
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaOf generate the schema of t, every value is nullable since null is accepted
+// by encoding/json for any type.
+func schemaOf(t reflect.Type) *jsonSchema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return &jsonSchema{Type: []string{"string", "null"}, Format: "date-time"}
+	}
+	// types with their own decoding, e.g. EpisodeFile, provide their schema.
+	if p, ok := reflect.Zero(t).Interface().(interface{ jsonSchema() *jsonSchema }); ok {
+		return p.jsonSchema()
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &jsonSchema{Type: []string{"boolean", "null"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &jsonSchema{Type: []string{"integer", "null"}}
+	case reflect.Float32, reflect.Float64:
+		return &jsonSchema{Type: []string{"number", "null"}}
+	case reflect.String:
+		return &jsonSchema{Type: []string{"string", "null"}}
+	case reflect.Slice, reflect.Array:
+		return &jsonSchema{Type: []string{"array", "null"}, Items: schemaOf(t.Elem())}
+	case reflect.Map:
+		return &jsonSchema{Type: []string{"object", "null"}, AdditionalProperties: schemaOf(t.Elem())}
+	case reflect.Struct:
+		s := &jsonSchema{Type: []string{"object", "null"}, Properties: make(map[string]*jsonSchema)}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if !f.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			s.Properties[name] = schemaOf(f.Type)
+		}
+		return s
+	}
+	// interface{} accept any value.
+	return &jsonSchema{}
+}
+
+// validate check v decoded with UseNumber against s, failures are appended to errs.
+func validate(v interface{}, s *jsonSchema, path string, errs *[]SchemaError) {
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, SchemaError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	got := jsonType(v)
+	if len(s.Type) > 0 && !allowed(s.Type, got) {
+		fail("expected %s, got %s", strings.Join(s.Type, " or "), got)
+		return
+	}
+	if s.Const != nil && v != s.Const {
+		fail("expected %q, got %v", s.Const, v)
+	}
+
+	switch v := v.(type) {
+	case string:
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, v); err != nil {
+				fail("invalid date-time %q", v)
+			}
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range v {
+				validate(item, s.Items, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				*errs = append(*errs, SchemaError{Path: path + "." + name, Message: "required"})
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if ps, ok := s.Properties[k]; ok {
+				validate(v[k], ps, path+"."+k, errs)
+			} else if s.AdditionalProperties != nil {
+				validate(v[k], s.AdditionalProperties, path+"."+k, errs)
+			}
+		}
+	}
+}
+
+// jsonType JSON Schema type of v, numbers without fraction are integer.
+func jsonType(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func allowed(types []string, got string) bool {
+	for _, t := range types {
+		if t == got || (t == "number" && got == "integer") {
+			return true
+		}
+	}
+	return false
+}
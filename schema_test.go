@@ -0,0 +1,111 @@
+package eventt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidatePayload(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    []SchemaError
+	}{
+		{"valid", `{"eventType":"Grab","series":{"id":7,"title":"Mob Psycho 100"},"episodes":[{"airDateUtc":"2022-10-05T14:00:00Z"}]}`, nil},
+		{"null and unknown fields", `{"eventType":"Grab","series":null,"release":{"size":null},"newField":[1]}`, nil},
+		{"v3 episode file", `{"eventType":"Download","episodeFile":{"quality":{"quality":{"name":"HDTV-720p"}},"mediaInfo":{"width":1280}}}`, nil},
+		{"v4 episode file", `{"eventType":"Download","episodeFile":{"quality":"HDTV-720p","mediaInfo":{"width":1280}}}`, nil},
+		{"unknown event type", `{"eventType":"ManualInteractionRequired","series":"not an object"}`, nil},
+		{"missing event type", `{"series":{"id":7}}`, []SchemaError{{"$.eventType", "required"}}},
+		{"null event type", `{"eventType":null}`, []SchemaError{{"$.eventType", "required"}}},
+		{"wrong type", `{"eventType":"Grab","series":{"id":"7"}}`, []SchemaError{{"$.series.id", "expected integer or null, got string"}}},
+		{"float for integer", `{"eventType":"Grab","release":{"size":1.5}}`, []SchemaError{{"$.release.size", "expected integer or null, got number"}}},
+		{"object for array", `{"eventType":"Download","episodes":{}}`, []SchemaError{{"$.episodes", "expected array or null, got object"}}},
+		{"array item", `{"eventType":"Rename","renamedEpisodeFiles":[{"id":1},{"id":true}]}`, []SchemaError{{"$.renamedEpisodeFiles[1].id", "expected integer or null, got boolean"}}},
+		{"date-time", `{"eventType":"Grab","episodes":[{"airDateUtc":"2022-10-05"}]}`, []SchemaError{{"$.episodes[0].airDateUtc", `invalid date-time "2022-10-05"`}}},
+		{"episode file quality", `{"eventType":"Download","episodeFile":{"quality":1}}`, []SchemaError{{"$.episodeFile.quality", "expected string or object or null, got integer"}}},
+		{"all failures in path order", `{"eventType":"Health","wikiUrl":1,"level":[],"message":false}`, []SchemaError{
+			{"$.level", "expected string or null, got array"},
+			{"$.message", "expected string or null, got boolean"},
+			{"$.wikiUrl", "expected string or null, got integer"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePayload([]byte(tt.payload))
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			var verr *ValidationError
+			if !errors.As(err, &verr) || !errors.Is(err, ErrInvalidPayload) {
+				t.Fatalf("got %v, want *ValidationError", err)
+			}
+			if !reflect.DeepEqual(verr.Errors, tt.want) {
+				t.Errorf("got  %v\nwant %v", verr.Errors, tt.want)
+			}
+		})
+	}
+
+	if err := ValidatePayload([]byte(`not json`)); err == nil || errors.Is(err, ErrInvalidPayload) {
+		t.Errorf("got %v, want a parsing error", err)
+	}
+}
+
+func TestValidationErrorMessage(t *testing.T) {
+	err := ValidatePayload([]byte(`{"eventType":"Grab","series":{"id":"7","tvdbId":"1"}}`))
+	want := `invalid payload: payload does not match 'Grab' schema: $.series.id: expected integer or null, got string; $.series.tvdbId: expected integer or null, got string`
+	if err == nil || err.Error() != want {
+		t.Errorf("got  %v\nwant %s", err, want)
+	}
+	err = ValidatePayload([]byte(`{}`))
+	if want := "invalid payload: $.eventType: required"; err == nil || err.Error() != want {
+		t.Errorf("got %v, want %s", err, want)
+	}
+}
+
+func TestDispatchValidateSchema(t *testing.T) {
+	var reported error
+	called := false
+	s := &SonarrTriggers{
+		ValidateSchema: true,
+		OnGrab:         func(e GrabEvent) { called = true },
+		OnError: func(payload []byte, err error) int {
+			reported = err
+			return 0
+		},
+	}
+	err := s.Dispatch(context.Background(), []byte(`{"eventType":"Grab","episodes":[{"title":7}]}`))
+	var verr *ValidationError
+	if !errors.As(err, &verr) || verr.Event != Grab || verr.Errors[0].Path != "$.episodes[0].title" || reported != err || called {
+		t.Errorf("got %v, reported %v, called %v", err, reported, called)
+	}
+}
+
+func TestSchema(t *testing.T) {
+	for _, et := range SchemaTypes() {
+		b, err := Schema(et)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var s struct {
+			ID       string                     `json:"$id"`
+			Required []string                   `json:"required"`
+			Props    map[string]json.RawMessage `json:"properties"`
+		}
+		if err := json.Unmarshal(b, &s); err != nil {
+			t.Fatal(err)
+		}
+		if s.ID != SchemaBaseURL+"/"+string(et)+".json" || len(s.Required) != 1 || s.Required[0] != "eventType" || s.Props["eventType"] == nil {
+			t.Errorf("%s schema: %s", et, b)
+		}
+	}
+	if _, err := Schema(Unknown); err == nil {
+		t.Error("schema for Unknown events")
+	}
+}
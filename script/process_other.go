@@ -0,0 +1,16 @@
+//go:build !unix
+
+package script
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup process groups are only used on unix.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kill p, the processes it started are left running.
+func killProcessGroup(p *os.Process) {
+	_ = p.Kill()
+}
@@ -0,0 +1,22 @@
+//go:build unix
+
+package script
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup run cmd in its own process group, so the processes it starts can
+// be killed with it.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessGroup kill p and the processes of its group.
+func killProcessGroup(p *os.Process) {
+	if err := syscall.Kill(-p.Pid, syscall.SIGKILL); err != nil {
+		_ = p.Kill()
+	}
+}
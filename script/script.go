@@ -0,0 +1,215 @@
+// Package script eventt sink which run commands for each event with the same environment
+// variables Sonarr Custom Script connection sets, so existing custom scripts can be
+// moved to webhooks without being rewritten.
+package script
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/k-x7/eventt"
+)
+
+// Default settings used when Exec fields are not set.
+const (
+	DefaultTimeout       = time.Minute
+	DefaultMaxConcurrent = 4
+	DefaultMaxOutput     = 64 << 10
+)
+
+// Result outcome of a single command run.
+type Result struct {
+	// Event type of the event which triggered the command.
+	Event eventt.EventType
+	// ExitCode of the command, -1 if it did not start or was killed.
+	ExitCode int
+	// Stdout and Stderr captured output, truncated to MaxOutput bytes.
+	Stdout []byte
+	Stderr []byte
+	// Duration of the run.
+	Duration time.Duration
+	// Err nil if the command exited with 0.
+	Err error
+}
+
+// ExitError returned when the command exit with non zero code.
+type ExitError struct {
+	Code   int
+	Stderr []byte
+}
+
+func (e *ExitError) Error() string {
+	if len(e.Stderr) == 0 {
+		return fmt.Sprintf("exit status %d", e.Code)
+	}
+	return fmt.Sprintf("exit status %d: %s", e.Code, bytes.TrimSpace(e.Stderr))
+}
+
+// Exec eventt.Sink which run Command for each event, the command environment contains
+// the current process environment, eventt.Env of the event and Env.
+// the command run before the response is sent to Sonarr, failures and non zero exit
+// codes are returned to SonarrTriggers.OnError.
+type Exec struct {
+	// Command path of the executable or script.
+	Command string
+	// Args optional arguments passed to Command.
+	Args []string
+	// Dir working directory, empty means the current directory.
+	Dir string
+	// Env extra environment variables in "key=value" form.
+	Env []string
+	// Types events which trigger the command, empty means all events.
+	Types eventt.TypeFilter
+	// Stdin pass the original webhook payload to the command standard input.
+	Stdin bool
+	// Timeout maximum duration of a run, the command is killed after it.
+	// default DefaultTimeout, negative value disable it.
+	Timeout time.Duration
+	// MaxConcurrent maximum number of commands running at the same time, other
+	// events wait for a free slot. default DefaultMaxConcurrent.
+	MaxConcurrent int
+	// MaxOutput maximum captured bytes of stdout and stderr each, default DefaultMaxOutput.
+	MaxOutput int
+	// OnResult optional callback invoked after each run with the captured output.
+	OnResult func(r Result)
+
+	once sync.Once
+	sem  chan struct{}
+}
+
+// Send implements eventt.Sink.
+func (x *Exec) Send(ctx context.Context, event eventt.Event, payload []byte) error {
+	if !x.Types.Match(eventt.TypeOf(event)) {
+		return nil
+	}
+
+	if err := x.acquire(ctx); err != nil {
+		return err
+	}
+	defer x.release()
+
+	res := x.run(ctx, event, payload)
+	if x.OnResult != nil {
+		x.OnResult(res)
+	}
+	if res.Err != nil {
+		return fmt.Errorf("script '%s': %w", x.Command, res.Err)
+	}
+	return nil
+}
+
+func (x *Exec) run(ctx context.Context, event eventt.Event, payload []byte) Result {
+	timeout := x.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	limit := x.MaxOutput
+	if limit <= 0 {
+		limit = DefaultMaxOutput
+	}
+	stdout := &limitedBuffer{limit: limit}
+	stderr := &limitedBuffer{limit: limit}
+
+	// the command is not started with exec.CommandContext as it kill only the command,
+	// children still holding the output pipes would keep the run going past the timeout.
+	cmd := exec.Command(x.Command, x.Args...)
+	cmd.Dir = x.Dir
+	cmd.Env = append(append(os.Environ(), eventt.Env(event)...), x.Env...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if x.Stdin {
+		cmd.Stdin = bytes.NewReader(payload)
+	}
+
+	setProcessGroup(cmd)
+
+	start := time.Now()
+	err := cmd.Start()
+	if err == nil {
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				killProcessGroup(cmd.Process)
+			case <-done:
+			}
+		}()
+		err = cmd.Wait()
+		close(done)
+	}
+	res := Result{
+		Event:    eventt.TypeOf(event),
+		ExitCode: -1,
+		Stdout:   stdout.Bytes(),
+		Stderr:   stderr.Bytes(),
+		Duration: time.Since(start),
+	}
+	if cmd.ProcessState != nil {
+		res.ExitCode = cmd.ProcessState.ExitCode()
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() != nil:
+		res.Err = fmt.Errorf("killed after %s: %w", res.Duration.Round(time.Millisecond), ctx.Err())
+	case errors.As(err, &exitErr):
+		res.Err = &ExitError{Code: res.ExitCode, Stderr: res.Stderr}
+	default:
+		res.Err = err
+	}
+	return res
+}
+
+func (x *Exec) acquire(ctx context.Context) error {
+	x.once.Do(func() {
+		n := x.MaxConcurrent
+		if n <= 0 {
+			n = DefaultMaxConcurrent
+		}
+		x.sem = make(chan struct{}, n)
+	})
+	select {
+	case x.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("script '%s': waiting for a free slot: %w", x.Command, ctx.Err())
+	}
+}
+
+func (x *Exec) release() {
+	<-x.sem
+}
+
+// limitedBuffer keep the first limit bytes written to it and discard the rest, the
+// buffer is not embedded so io.Copy can't bypass the limit with Buffer.ReadFrom.
+type limitedBuffer struct {
+	buf   bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.buf.Len(); room > 0 {
+		if len(p) > room {
+			b.buf.Write(p[:room])
+		} else {
+			b.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+func (b *limitedBuffer) Bytes() []byte {
+	return b.buf.Bytes()
+}
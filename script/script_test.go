@@ -0,0 +1,107 @@
+package script
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/k-x7/eventt"
+)
+
+func grabEvent() eventt.GrabEvent {
+	return eventt.GrabEvent{Series: eventt.Series{Title: "Mob Psycho 100"}}
+}
+
+func TestExecEnv(t *testing.T) {
+	var res Result
+	x := &Exec{
+		Command:  "sh",
+		Args:     []string{"-c", `echo "$sonarr_eventtype $sonarr_series_title $EXTRA"; cat`},
+		Env:      []string{"EXTRA=extra"},
+		Stdin:    true,
+		OnResult: func(r Result) { res = r },
+	}
+	if err := x.Send(context.Background(), grabEvent(), []byte(`{"eventType":"Grab"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(res.Stdout); got != "Grab Mob Psycho 100 extra\n{\"eventType\":\"Grab\"}" {
+		t.Errorf("stdout = %q", got)
+	}
+	if res.Event != eventt.Grab || res.ExitCode != 0 || res.Err != nil {
+		t.Errorf("result = %+v", res)
+	}
+}
+
+func TestExecExitCode(t *testing.T) {
+	x := &Exec{Command: "sh", Args: []string{"-c", "echo failed >&2; exit 3"}}
+	err := x.Send(context.Background(), grabEvent(), nil)
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 3 || string(exitErr.Stderr) != "failed\n" {
+		t.Fatalf("got %v", err)
+	}
+	if err.Error() != "script 'sh': exit status 3: failed" {
+		t.Errorf("error = %q", err)
+	}
+
+	if err := (&Exec{Command: "/nonexistent/script"}).Send(context.Background(), grabEvent(), nil); err == nil {
+		t.Error("missing command reported no error")
+	}
+}
+
+func TestExecTypes(t *testing.T) {
+	x := &Exec{Command: "sh", Args: []string{"-c", "exit 1"}, Types: eventt.Types(eventt.Download)}
+	if err := x.Send(context.Background(), grabEvent(), nil); err != nil {
+		t.Errorf("filtered event ran the command: %v", err)
+	}
+}
+
+func TestExecMaxOutput(t *testing.T) {
+	var res Result
+	x := &Exec{
+		Command:   "sh",
+		Args:      []string{"-c", "echo 0123456789"},
+		MaxOutput: 4,
+		OnResult:  func(r Result) { res = r },
+	}
+	if err := x.Send(context.Background(), grabEvent(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if string(res.Stdout) != "0123" {
+		t.Errorf("stdout = %q", res.Stdout)
+	}
+}
+
+func TestExecTimeout(t *testing.T) {
+	// the background sleep keep the output pipes open after sh is killed.
+	x := &Exec{Command: "sh", Args: []string{"-c", "sleep 10 & sleep 10"}, Timeout: 100 * time.Millisecond}
+	start := time.Now()
+	err := x.Send(context.Background(), grabEvent(), nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want deadline exceeded", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("run took %v, children not killed", d)
+	}
+}
+
+func TestExecMaxConcurrent(t *testing.T) {
+	x := &Exec{Command: "sh", Args: []string{"-c", "sleep 1"}, MaxConcurrent: 1}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_ = x.Send(context.Background(), grabEvent(), nil)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := x.Send(ctx, grabEvent(), nil)
+	if err == nil || !strings.Contains(err.Error(), "waiting for a free slot") {
+		t.Errorf("got %v, want no free slot", err)
+	}
+	wg.Wait()
+}
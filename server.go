@@ -0,0 +1,340 @@
+package eventt
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slog"
+
+	"github.com/k-x7/eventt/systemd"
+)
+
+// Default settings used when Server fields are not set.
+const (
+	DefaultAddr              = ":8281"
+	DefaultPath              = "/events"
+	DefaultReadHeaderTimeout = 10 * time.Second
+	DefaultReadTimeout       = time.Minute
+	DefaultWriteTimeout      = time.Minute
+	DefaultIdleTimeout       = 2 * time.Minute
+	DefaultSocketMode        = 0o660
+)
+
+// certCheckInterval how often certificate files are checked for changes.
+const certCheckInterval = 10 * time.Second
+
+// Server http server for SonarrTriggers with timeouts, TLS and graceful shutdown,
+// prefer it to http.ListenAndServe which has no timeouts:
+//
+//	srv := &eventt.Server{Triggers: &events, CertFile: "cert.pem", KeyFile: "key.pem"}
+//	go srv.ListenAndServe()
+//	...
+//	srv.Shutdown(ctx)
+//
+// the certificate is reloaded when CertFile or KeyFile change, so rotated certificates
+// are used without restart.
+//
+// when run as systemd service with Type=notify, Server send READY=1 once it is
+// listening, STOPPING=1 on Shutdown and WATCHDOG=1 pings if WatchdogSec= is set.
+type Server struct {
+	// Addr listen address, default DefaultAddr. it can be:
+	//
+	//	host:port        TCP address
+	//	unix:/path       Unix domain socket, a stale socket file is removed
+	//	systemd          first socket passed by systemd socket activation
+	//	systemd:name     socket with FileDescriptorName=name
+	Addr string
+	// SocketMode permissions of Unix domain socket, default DefaultSocketMode which allow
+	// the owner and group, e.g. a reverse proxy user in the same group.
+	SocketMode os.FileMode
+	// Triggers served at Path, ignored if Handler is set.
+	Triggers *SonarrTriggers
+	// Path where Sonarr sends the events, default DefaultPath.
+	Path string
+	// Handler optional, serve it instead of Triggers, e.g. Mux or http.ServeMux with
+	// metrics and other endpoints.
+	Handler http.Handler
+
+	// timeouts of http.Server, zero use the Default... values, negative disable them.
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+
+	// CertFile and KeyFile PEM certificate and key, TLS is enabled when both are set.
+	CertFile string
+	KeyFile  string
+	// ClientCAFile optional PEM CA certificates, if set clients must present a
+	// certificate signed by them (mTLS), e.g. a reverse proxy or Sonarr behind one.
+	ClientCAFile string
+
+	once     sync.Once
+	srv      *http.Server
+	err      error
+	watchdog sync.Once
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// ListenAndServe listen on Addr and serve until Shutdown, it returns http.ErrServerClosed
+// after Shutdown.
+func (s *Server) ListenAndServe() error {
+	if err := s.init(); err != nil {
+		return err
+	}
+	l, err := s.listen()
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+func (s *Server) listen() (net.Listener, error) {
+	addr := s.Addr
+	if addr == "" {
+		addr = DefaultAddr
+	}
+
+	switch {
+	case strings.HasPrefix(addr, "unix:"):
+		return listenUnix(strings.TrimPrefix(addr, "unix:"), s.SocketMode)
+	case addr == "systemd" || strings.HasPrefix(addr, "systemd:"):
+		name := strings.TrimPrefix(strings.TrimPrefix(addr, "systemd"), ":")
+		sockets, err := systemd.Listeners()
+		if err != nil {
+			return nil, err
+		}
+		var found net.Listener
+		for _, socket := range sockets {
+			if found == nil && (name == "" || socket.Name == name) {
+				found = socket.Listener
+				continue
+			}
+			socket.Listener.Close()
+		}
+		if found == nil {
+			return nil, fmt.Errorf("eventt server: no systemd socket '%s', is the service socket activated?", name)
+		}
+		return found, nil
+	}
+	return net.Listen("tcp", addr)
+}
+
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if mode == 0 {
+		mode = DefaultSocketMode
+	}
+	// remove the socket left by an unclean exit, other files are kept and Listen fails.
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("eventt server: %w", err)
+		}
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		l.Close()
+		return nil, fmt.Errorf("eventt server: %w", err)
+	}
+	return l, nil
+}
+
+// Serve accept connections on l until Shutdown, it returns http.ErrServerClosed
+// after Shutdown.
+func (s *Server) Serve(l net.Listener) error {
+	if err := s.init(); err != nil {
+		l.Close()
+		return err
+	}
+	s.notifyReady()
+	if s.srv.TLSConfig != nil {
+		return s.srv.ServeTLS(l, "", "")
+	}
+	return s.srv.Serve(l)
+}
+
+// Shutdown stop accepting new webhooks and wait for in-flight handlers until ctx is done.
+// If the server could not be set up it returns the setup error, as it was never started.
+func (s *Server) Shutdown(ctx context.Context) error {
+	if err := s.init(); err != nil {
+		return err
+	}
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		if err := systemd.Notify(systemd.Stopping); err != nil {
+			slog.Warn("error notifying systemd", "error", err)
+		}
+	})
+	return s.srv.Shutdown(ctx)
+}
+
+// notifyReady tell systemd the server is ready and start the watchdog pings.
+func (s *Server) notifyReady() {
+	s.watchdog.Do(func() {
+		if err := systemd.Notify(systemd.Ready); err != nil {
+			slog.Warn("error notifying systemd", "error", err)
+		}
+		interval := systemd.WatchdogInterval() / 2
+		if interval <= 0 {
+			return
+		}
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					if err := systemd.Notify(systemd.Watchdog); err != nil {
+						slog.Warn("error notifying systemd", "error", err)
+					}
+				case <-s.stop:
+					return
+				}
+			}
+		}()
+	})
+}
+
+func (s *Server) init() error {
+	s.once.Do(func() {
+		s.stop = make(chan struct{})
+		handler := s.Handler
+		if handler == nil {
+			if s.Triggers == nil {
+				s.err = errors.New("eventt server: Triggers or Handler is required")
+				return
+			}
+			path := s.Path
+			if path == "" {
+				path = DefaultPath
+			}
+			mux := http.NewServeMux()
+			mux.HandleFunc(path, s.Triggers.Monitor)
+			handler = mux
+		}
+
+		s.srv = &http.Server{
+			Handler:           handler,
+			ReadHeaderTimeout: timeout(s.ReadHeaderTimeout, DefaultReadHeaderTimeout),
+			ReadTimeout:       timeout(s.ReadTimeout, DefaultReadTimeout),
+			WriteTimeout:      timeout(s.WriteTimeout, DefaultWriteTimeout),
+			IdleTimeout:       timeout(s.IdleTimeout, DefaultIdleTimeout),
+		}
+		s.srv.TLSConfig, s.err = s.tlsConfig()
+	})
+	return s.err
+}
+
+func (s *Server) tlsConfig() (*tls.Config, error) {
+	if s.CertFile == "" && s.KeyFile == "" {
+		if s.ClientCAFile != "" {
+			return nil, errors.New("eventt server: ClientCAFile requires CertFile and KeyFile")
+		}
+		return nil, nil
+	}
+	if s.CertFile == "" || s.KeyFile == "" {
+		return nil, errors.New("eventt server: both CertFile and KeyFile are required")
+	}
+
+	certs := &certReloader{certFile: s.CertFile, keyFile: s.KeyFile}
+	if err := certs.load(); err != nil {
+		return nil, fmt.Errorf("eventt server: %w", err)
+	}
+	cfg := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: certs.get,
+	}
+	if s.ClientCAFile != "" {
+		pem, err := os.ReadFile(s.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("eventt server: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("eventt server: no certificates found in '%s'", s.ClientCAFile)
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg, nil
+}
+
+func timeout(d, def time.Duration) time.Duration {
+	switch {
+	case d == 0:
+		return def
+	case d < 0:
+		return 0
+	}
+	return d
+}
+
+// certReloader serve the certificate and reload it when the files change, if the
+// new files are invalid, e.g. during rotation, the current certificate is kept.
+type certReloader struct {
+	certFile, keyFile string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+	checked time.Time
+}
+
+func (c *certReloader) get(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if time.Since(c.checked) >= certCheckInterval {
+		c.checked = time.Now()
+		if c.changed() {
+			if err := c.loadLocked(); err != nil {
+				slog.Error("error reloading certificate, keeping the current one", err,
+					"cert", c.certFile, "key", c.keyFile)
+			}
+		}
+	}
+	return c.cert, nil
+}
+
+func (c *certReloader) load() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checked = time.Now()
+	return c.loadLocked()
+}
+
+func (c *certReloader) loadLocked() error {
+	modTime := c.lastModified()
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return err
+	}
+	c.cert = &cert
+	c.modTime = modTime
+	return nil
+}
+
+func (c *certReloader) changed() bool {
+	return !c.lastModified().Equal(c.modTime)
+}
+
+// lastModified latest modification time of the certificate and key files.
+func (c *certReloader) lastModified() time.Time {
+	var latest time.Time
+	for _, f := range []string{c.certFile, c.keyFile} {
+		if info, err := os.Stat(f); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
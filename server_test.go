@@ -0,0 +1,324 @@
+package eventt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeCert write a self-signed certificate for 127.0.0.1 with its key to dir, it
+// can be used as server and client certificate and as CA.
+func writeCert(t *testing.T, dir, name string) (certFile, keyFile string, cert *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile = filepath.Join(dir, name+".pem")
+	keyFile = filepath.Join(dir, name+".key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cert, _ = x509.ParseCertificate(der)
+	return certFile, keyFile, cert
+}
+
+// serve start s on a local TCP port, it returns the base url and a function which
+// shut the server down and return the Serve error.
+func serve(t *testing.T, s *Server) (string, func() error) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(l) }()
+	scheme := "http"
+	if s.CertFile != "" {
+		scheme = "https"
+	}
+	return scheme + "://" + l.Addr().String(), func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.Shutdown(ctx); err != nil {
+			return err
+		}
+		return <-done
+	}
+}
+
+func postTest(client *http.Client, url string) (int, error) {
+	resp, err := client.Post(url, "application/json", strings.NewReader(`{"eventType":"Test"}`))
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+func TestServer(t *testing.T) {
+	received := make(chan TestEvent, 1)
+	s := &Server{Triggers: &SonarrTriggers{OnTest: func(e TestEvent) { received <- e }}, ReadTimeout: -1}
+	url, shutdown := serve(t, s)
+
+	if status, err := postTest(http.DefaultClient, url+DefaultPath); err != nil || status != http.StatusOK {
+		t.Fatalf("got %d, %v", status, err)
+	}
+	select {
+	case <-received:
+	case <-time.After(time.Second):
+		t.Error("event not dispatched")
+	}
+	if status, err := postTest(http.DefaultClient, url+"/other"); err != nil || status != http.StatusNotFound {
+		t.Errorf("other path got %d, %v", status, err)
+	}
+	if s.srv.ReadHeaderTimeout != DefaultReadHeaderTimeout || s.srv.ReadTimeout != 0 || s.srv.IdleTimeout != DefaultIdleTimeout {
+		t.Errorf("timeouts %v %v %v", s.srv.ReadHeaderTimeout, s.srv.ReadTimeout, s.srv.IdleTimeout)
+	}
+
+	if err := shutdown(); !errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("Serve returned %v, want http.ErrServerClosed", err)
+	}
+	if _, err := postTest(http.DefaultClient, url+DefaultPath); err == nil {
+		t.Error("server accepted requests after Shutdown")
+	}
+}
+
+func TestServerHandler(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hooks/sonarr", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusAccepted) })
+	url, shutdown := serve(t, &Server{Handler: mux, Triggers: &SonarrTriggers{}})
+	defer shutdown()
+
+	if status, err := postTest(http.DefaultClient, url+"/hooks/sonarr"); err != nil || status != http.StatusAccepted {
+		t.Errorf("got %d, %v", status, err)
+	}
+	if status, err := postTest(http.DefaultClient, url+DefaultPath); err != nil || status != http.StatusNotFound {
+		t.Errorf("Triggers served with Handler set: %d, %v", status, err)
+	}
+}
+
+// tlsClient trust ca and present certs as client certificates.
+func tlsClient(ca *x509.Certificate, certs ...tls.Certificate) *http.Client {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, Certificates: certs}}}
+}
+
+func TestServerTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, cert := writeCert(t, dir, "server")
+	url, shutdown := serve(t, &Server{Triggers: &SonarrTriggers{}, CertFile: certFile, KeyFile: keyFile})
+	defer shutdown()
+
+	if status, err := postTest(tlsClient(cert), url+DefaultPath); err != nil || status != http.StatusOK {
+		t.Fatalf("got %d, %v", status, err)
+	}
+	if _, err := postTest(http.DefaultClient, url+DefaultPath); err == nil {
+		t.Error("untrusted certificate accepted")
+	}
+}
+
+func TestServerClientCA(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, cert := writeCert(t, dir, "server")
+	clientCert, clientKey, _ := writeCert(t, dir, "client")
+	writeCert(t, dir, "other")
+	url, shutdown := serve(t, &Server{Triggers: &SonarrTriggers{}, CertFile: certFile, KeyFile: keyFile, ClientCAFile: clientCert})
+	defer shutdown()
+
+	trusted, err := tls.LoadX509KeyPair(clientCert, clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	untrusted, err := tls.LoadX509KeyPair(filepath.Join(dir, "other.pem"), filepath.Join(dir, "other.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status, err := postTest(tlsClient(cert, trusted), url+DefaultPath); err != nil || status != http.StatusOK {
+		t.Errorf("trusted client got %d, %v", status, err)
+	}
+	if _, err := postTest(tlsClient(cert), url+DefaultPath); err == nil {
+		t.Error("client without certificate accepted")
+	}
+	if _, err := postTest(tlsClient(cert, untrusted), url+DefaultPath); err == nil {
+		t.Error("client with untrusted certificate accepted")
+	}
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, first := writeCert(t, dir, "server")
+	c := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := c.load(); err != nil {
+		t.Fatal(err)
+	}
+	serial := func() *big.Int {
+		t.Helper()
+		cert, err := c.get(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return leaf.SerialNumber
+	}
+
+	// rotate the files, the new certificate is used once the files are checked again.
+	_, _, second := writeCert(t, dir, "rotated")
+	for _, f := range [][2]string{{"rotated.pem", certFile}, {"rotated.key", keyFile}} {
+		if err := os.Rename(filepath.Join(dir, f[0]), f[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(certFile, future, future); err != nil {
+		t.Fatal(err)
+	}
+	if got := serial(); got.Cmp(first.SerialNumber) != 0 {
+		t.Error("certificate reloaded before the check interval")
+	}
+	c.checked = time.Time{}
+	if got := serial(); got.Cmp(second.SerialNumber) != 0 {
+		t.Error("rotated certificate not reloaded")
+	}
+
+	// an invalid key during rotation keep the current certificate.
+	if err := os.WriteFile(keyFile, []byte("rotating"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	future = future.Add(time.Minute)
+	if err := os.Chtimes(keyFile, future, future); err != nil {
+		t.Fatal(err)
+	}
+	c.checked = time.Time{}
+	if got := serial(); got.Cmp(second.SerialNumber) != 0 {
+		t.Error("invalid files replaced the current certificate")
+	}
+}
+
+func TestServerSetupErrors(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, _ := writeCert(t, dir, "server")
+	tests := []struct {
+		name string
+		srv  *Server
+		err  string
+	}{
+		{"no handler", &Server{}, "Triggers or Handler is required"},
+		{"cert without key", &Server{Triggers: &SonarrTriggers{}, CertFile: certFile}, "both CertFile and KeyFile are required"},
+		{"client ca without cert", &Server{Triggers: &SonarrTriggers{}, ClientCAFile: certFile}, "ClientCAFile requires CertFile and KeyFile"},
+		{"missing cert", &Server{Triggers: &SonarrTriggers{}, CertFile: filepath.Join(dir, "missing.pem"), KeyFile: keyFile}, "missing.pem"},
+		{"client ca without certificates", &Server{Triggers: &SonarrTriggers{}, CertFile: certFile, KeyFile: keyFile, ClientCAFile: keyFile}, "no certificates found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.srv.ListenAndServe()
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("got %v, want error containing %q", err, tt.err)
+			}
+			// the server never started, Shutdown report why.
+			if shutdownErr := tt.srv.Shutdown(context.Background()); shutdownErr != err {
+				t.Errorf("Shutdown returned %v, want %v", shutdownErr, err)
+			}
+		})
+	}
+}
+
+func TestServerUnixSocket(t *testing.T) {
+	// socket paths are limited to about 100 bytes, t.TempDir can be longer.
+	dir, err := os.MkdirTemp("", "eventt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "eventt.sock")
+
+	// a stale socket left by an unclean exit is replaced.
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	s := &Server{Addr: "unix:" + path, SocketMode: 0o600, Triggers: &SonarrTriggers{}}
+	done := make(chan error, 1)
+	go func() { done <- s.ListenAndServe() }()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status, err := postTest(client, "http://eventt"+DefaultPath)
+		if err == nil {
+			if status != http.StatusOK {
+				t.Errorf("got %d", status)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("socket mode %v, %v", info.Mode().Perm(), err)
+	}
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; !errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("ListenAndServe returned %v", err)
+	}
+
+	// other files are not removed.
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := listenUnix(file, 0); err == nil {
+		t.Error("listening replaced a regular file")
+	}
+}
@@ -0,0 +1,58 @@
+package eventt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Sink receives every decoded event after the On... callbacks, e.g. to forward
+// events to other services, see SonarrTriggers.Sinks. payload is the original
+// webhook payload as received from Sonarr.
+type Sink interface {
+	Send(ctx context.Context, event Event, payload []byte) error
+}
+
+// SinkFunc adapter to use ordinary functions as Sink.
+type SinkFunc func(ctx context.Context, event Event, payload []byte) error
+
+// Send call f(ctx, event, payload).
+func (f SinkFunc) Send(ctx context.Context, event Event, payload []byte) error {
+	return f(ctx, event, payload)
+}
+
+// SinkErrors errors returned by one or more sinks for the same event.
+type SinkErrors []error
+
+func (e SinkErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Is report whether any of the sinks errors matches target.
+func (e SinkErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// send deliver e to all sinks, all sinks are called even if some of them fail.
+func (s *SonarrTriggers) send(ctx context.Context, e Event, payload []byte) error {
+	var errs SinkErrors
+	for i, sink := range s.Sinks {
+		if err := sink.Send(ctx, e, payload); err != nil {
+			errs = append(errs, fmt.Errorf("sink %d (%T): %w", i, sink, err))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
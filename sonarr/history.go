@@ -0,0 +1,65 @@
+package sonarr
+
+import (
+	"context"
+	"net/url"
+	"time"
+)
+
+// History event types of HistoryRecord.EventType.
+const (
+	HistoryGrabbed                = "grabbed"
+	HistorySeriesFolderImported   = "seriesFolderImported"
+	HistoryDownloadFolderImported = "downloadFolderImported"
+	HistoryDownloadFailed         = "downloadFailed"
+	HistoryEpisodeFileDeleted     = "episodeFileDeleted"
+	HistoryEpisodeFileRenamed     = "episodeFileRenamed"
+	HistoryDownloadIgnored        = "downloadIgnored"
+)
+
+// HistoryRecord single entry of Sonarr activity history, multi episodes grabs and
+// imports have a record per episode with the same DownloadID.
+type HistoryRecord struct {
+	ID          int          `json:"id"`
+	EpisodeID   int          `json:"episodeId"`
+	SeriesID    int          `json:"seriesId"`
+	SourceTitle string       `json:"sourceTitle"`
+	Quality     QualityModel `json:"quality"`
+	Date        time.Time    `json:"date"`
+	DownloadID  string       `json:"downloadId"`
+	EventType   string       `json:"eventType"`
+	// Data extra information depending on EventType, e.g. indexer for grabbed,
+	// importedPath for downloadFolderImported and reason for episodeFileDeleted.
+	Data    map[string]string `json:"data"`
+	Series  *Series           `json:"series,omitempty"`
+	Episode *Episode          `json:"episode,omitempty"`
+}
+
+// QualityModel quality and revision of a release or episode file.
+type QualityModel struct {
+	Quality struct {
+		ID         int    `json:"id"`
+		Name       string `json:"name"`
+		Source     string `json:"source"`
+		Resolution int    `json:"resolution"`
+	} `json:"quality"`
+	Revision struct {
+		Version  int  `json:"version"`
+		Real     int  `json:"real"`
+		IsRepack bool `json:"isRepack"`
+	} `json:"revision"`
+}
+
+// HistorySince return the history records after since, with their series and episode.
+func (c *Client) HistorySince(ctx context.Context, since time.Time) ([]HistoryRecord, error) {
+	query := url.Values{
+		"date":           {since.UTC().Format(time.RFC3339)},
+		"includeSeries":  {"true"},
+		"includeEpisode": {"true"},
+	}
+	var records []HistoryRecord
+	if err := c.get(ctx, "/api/v3/history/since", query, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
@@ -0,0 +1,185 @@
+package sonarr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Webhook connection settings, see Notification.
+const (
+	WebhookImplementation = "Webhook"
+	WebhookConfigContract = "WebhookSettings"
+	// WebhookMethodPost and WebhookMethodPut values of the method field.
+	WebhookMethodPost = 1
+	WebhookMethodPut  = 2
+)
+
+// Notification connection resource from Settings > Connect, e.g. webhook.
+type Notification struct {
+	ID                            int     `json:"id,omitempty"`
+	Name                          string  `json:"name"`
+	Implementation                string  `json:"implementation"`
+	ConfigContract                string  `json:"configContract"`
+	Fields                        []Field `json:"fields"`
+	Tags                          []int   `json:"tags"`
+	OnGrab                        bool    `json:"onGrab"`
+	OnDownload                    bool    `json:"onDownload"`
+	OnUpgrade                     bool    `json:"onUpgrade"`
+	OnRename                      bool    `json:"onRename"`
+	OnSeriesDelete                bool    `json:"onSeriesDelete"`
+	OnEpisodeFileDelete           bool    `json:"onEpisodeFileDelete"`
+	OnEpisodeFileDeleteForUpgrade bool    `json:"onEpisodeFileDeleteForUpgrade"`
+	OnHealthIssue                 bool    `json:"onHealthIssue"`
+	IncludeHealthWarnings         bool    `json:"includeHealthWarnings"`
+	OnApplicationUpdate           bool    `json:"onApplicationUpdate"`
+	// Raw resource as received from Sonarr, its properties which are not modelled,
+	// e.g. onManualInteractionRequired of Sonarr v4, are sent back unchanged by
+	// MarshalJSON, so updates only change the modelled fields.
+	Raw json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON decode the notification and keep the received resource in Raw.
+func (n *Notification) UnmarshalJSON(b []byte) error {
+	type notification Notification
+	if err := json.Unmarshal(b, (*notification)(n)); err != nil {
+		return err
+	}
+	n.Raw = append(json.RawMessage(nil), b...)
+	return nil
+}
+
+// MarshalJSON encode the notification over Raw, the modelled properties replace the
+// received ones and the values of Fields replace the values of the received fields
+// with the same name.
+func (n Notification) MarshalJSON() ([]byte, error) {
+	type notification Notification
+	b, err := json.Marshal(notification(n))
+	if err != nil || len(n.Raw) == 0 {
+		return b, err
+	}
+
+	var raw, modelled map[string]json.RawMessage
+	if err := json.Unmarshal(n.Raw, &raw); err != nil {
+		return nil, fmt.Errorf("error decoding raw notification: %w", err)
+	}
+	if err := json.Unmarshal(b, &modelled); err != nil {
+		return nil, err
+	}
+	fields, err := mergeFields(raw["fields"], n.Fields)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range modelled {
+		raw[k] = v
+	}
+	raw["fields"] = fields
+	return json.Marshal(raw)
+}
+
+// mergeFields set the values of fields on the received fields, which also have their
+// label, type and help text, missing fields are added.
+func mergeFields(received json.RawMessage, fields []Field) (json.RawMessage, error) {
+	var out []map[string]interface{}
+	if len(received) > 0 {
+		if err := json.Unmarshal(received, &out); err != nil {
+			return nil, fmt.Errorf("error decoding raw notification fields: %w", err)
+		}
+	}
+	for _, f := range fields {
+		found := false
+		for _, rf := range out {
+			if rf["name"] == f.Name {
+				rf["value"] = f.Value
+				found = true
+			}
+		}
+		if !found {
+			out = append(out, map[string]interface{}{"name": f.Name, "value": f.Value})
+		}
+	}
+	return json.Marshal(out)
+}
+
+// Field setting of a Notification, e.g. url of a webhook.
+type Field struct {
+	Name  string      `json:"name"`
+	Value interface{} `json:"value"`
+}
+
+// Field return the value of the field name, nil if not set.
+func (n *Notification) Field(name string) interface{} {
+	for _, f := range n.Fields {
+		if f.Name == name {
+			return f.Value
+		}
+	}
+	return nil
+}
+
+// SetField set the value of the field name, the field is added if missing.
+func (n *Notification) SetField(name string, value interface{}) {
+	for i := range n.Fields {
+		if n.Fields[i].Name == name {
+			n.Fields[i].Value = value
+			return
+		}
+	}
+	n.Fields = append(n.Fields, Field{Name: name, Value: value})
+}
+
+// Notifications return all the connections.
+func (c *Client) Notifications(ctx context.Context) ([]Notification, error) {
+	var ns []Notification
+	if err := c.get(ctx, "/api/v3/notification", nil, &ns); err != nil {
+		return nil, err
+	}
+	return ns, nil
+}
+
+// Notification return the connection with the given id.
+func (c *Client) Notification(ctx context.Context, id int) (*Notification, error) {
+	n := &Notification{}
+	if err := c.get(ctx, "/api/v3/notification/"+strconv.Itoa(id), nil, n); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// CreateNotification add a new connection and return it with its id.
+func (c *Client) CreateNotification(ctx context.Context, n *Notification) (*Notification, error) {
+	created := &Notification{}
+	if err := c.do(ctx, http.MethodPost, "/api/v3/notification", nil, n, created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// UpdateNotification replace the connection with n.ID, properties of n.Raw which are
+// not modelled are kept, see Notification.MarshalJSON.
+func (c *Client) UpdateNotification(ctx context.Context, n *Notification) (*Notification, error) {
+	updated := &Notification{}
+	path := "/api/v3/notification/" + strconv.Itoa(n.ID)
+	if err := c.do(ctx, http.MethodPut, path, nil, n, updated); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// TestNotification ask Sonarr to send a test notification using n settings, n does not
+// need to be saved. for webhooks Sonarr post a Test event and returns an APIError if
+// the endpoint did not reply with 2xx.
+func (c *Client) TestNotification(ctx context.Context, n *Notification) error {
+	return c.do(ctx, http.MethodPost, "/api/v3/notification/test", nil, n, nil)
+}
+
+// CreateTag add a new tag with label.
+func (c *Client) CreateTag(ctx context.Context, label string) (*Tag, error) {
+	t := &Tag{}
+	if err := c.do(ctx, http.MethodPost, "/api/v3/tag", nil, map[string]string{"label": label}, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
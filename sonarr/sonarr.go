@@ -0,0 +1,170 @@
+// Package sonarr minimal client of Sonarr v3 API, it covers the endpoints eventt needs
+// to enrich webhook events: series, episodes and tags.
+// see: https://sonarr.tv/docs/api/
+package sonarr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout used when Client.HTTPClient is not set.
+const DefaultTimeout = 30 * time.Second
+
+// ErrNotFound matched by errors.Is when the API returns 404, e.g. deleted series.
+var ErrNotFound = errors.New("not found")
+
+// APIError returned when Sonarr reply with non 2xx status.
+type APIError struct {
+	// StatusCode http status returned by Sonarr.
+	StatusCode int
+	// Message error message from the response body, if any.
+	Message string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("sonarr api: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("sonarr api: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Is report whether target is ErrNotFound and the status is 404.
+func (e *APIError) Is(target error) bool {
+	return target == ErrNotFound && e.StatusCode == http.StatusNotFound
+}
+
+// Client Sonarr API client, it is safe for concurrent use.
+type Client struct {
+	// BaseURL of Sonarr including the URL base if set, e.g. http://localhost:8989/sonarr.
+	BaseURL string
+	// APIKey from Sonarr Settings > General.
+	APIKey string
+	// HTTPClient used to call the API, if nil a client with DefaultTimeout is used.
+	HTTPClient *http.Client
+}
+
+// Series return the series with the given id.
+func (c *Client) Series(ctx context.Context, id int) (*Series, error) {
+	s := &Series{}
+	if err := c.get(ctx, "/api/v3/series/"+strconv.Itoa(id), nil, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Episode return the episode with the given id.
+func (c *Client) Episode(ctx context.Context, id int) (*Episode, error) {
+	e := &Episode{}
+	if err := c.get(ctx, "/api/v3/episode/"+strconv.Itoa(id), nil, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Episodes return all the episodes of the series with the given id.
+func (c *Client) Episodes(ctx context.Context, seriesID int) ([]Episode, error) {
+	var episodes []Episode
+	query := url.Values{"seriesId": {strconv.Itoa(seriesID)}}
+	if err := c.get(ctx, "/api/v3/episode", query, &episodes); err != nil {
+		return nil, err
+	}
+	return episodes, nil
+}
+
+// Tags return all the tags.
+func (c *Client) Tags(ctx context.Context) ([]Tag, error) {
+	var tags []Tag
+	if err := c.get(ctx, "/api/v3/tag", nil, &tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (c *Client) get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	return c.do(ctx, http.MethodGet, path, query, nil, out)
+}
+
+// do call the API and decode the response into out, in is encoded as JSON body if not nil.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	u := strings.TrimSuffix(c.BaseURL, "/") + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("sonarr api: error encoding request: %w", err)
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return fmt.Errorf("sonarr api: %w", err)
+	}
+	req.Header.Set("X-Api-Key", c.APIKey)
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client().Do(req)
+	if err != nil {
+		return fmt.Errorf("sonarr api: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return &APIError{StatusCode: resp.StatusCode, Message: errorMessage(b)}
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("sonarr api: error decoding %s response: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Client) client() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return &http.Client{Timeout: DefaultTimeout}
+}
+
+// errorMessage extract the message of Sonarr error responses, which are either an
+// object with message field or a list of validation failures.
+func errorMessage(b []byte) string {
+	var single struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(b, &single) == nil && single.Message != "" {
+		return single.Message
+	}
+	var failures []struct {
+		PropertyName string `json:"propertyName"`
+		ErrorMessage string `json:"errorMessage"`
+	}
+	if json.Unmarshal(b, &failures) == nil && len(failures) > 0 {
+		msgs := make([]string, 0, len(failures))
+		for _, f := range failures {
+			msgs = append(msgs, f.PropertyName+": "+f.ErrorMessage)
+		}
+		return strings.Join(msgs, ", ")
+	}
+	return strings.TrimSpace(string(b))
+}
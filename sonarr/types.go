@@ -0,0 +1,92 @@
+package sonarr
+
+import "time"
+
+// Series full series resource.
+type Series struct {
+	ID                int         `json:"id"`
+	Title             string      `json:"title"`
+	SortTitle         string      `json:"sortTitle"`
+	Status            string      `json:"status"`
+	Overview          string      `json:"overview"`
+	Network           string      `json:"network"`
+	AirTime           string      `json:"airTime"`
+	Images            []Image     `json:"images"`
+	Seasons           []Season    `json:"seasons"`
+	Year              int         `json:"year"`
+	Path              string      `json:"path"`
+	QualityProfileID  int         `json:"qualityProfileId"`
+	LanguageProfileID int         `json:"languageProfileId"`
+	SeasonFolder      bool        `json:"seasonFolder"`
+	Monitored         bool        `json:"monitored"`
+	UseSceneNumbering bool        `json:"useSceneNumbering"`
+	Runtime           int         `json:"runtime"`
+	TvdbID            int         `json:"tvdbId"`
+	TvRageID          int         `json:"tvRageId"`
+	TvMazeID          int         `json:"tvMazeId"`
+	ImdbID            string      `json:"imdbId"`
+	FirstAired        time.Time   `json:"firstAired"`
+	SeriesType        string      `json:"seriesType"`
+	CleanTitle        string      `json:"cleanTitle"`
+	TitleSlug         string      `json:"titleSlug"`
+	Certification     string      `json:"certification"`
+	Genres            []string    `json:"genres"`
+	Tags              []int       `json:"tags"`
+	Added             time.Time   `json:"added"`
+	Ratings           Ratings     `json:"ratings"`
+	Statistics        *Statistics `json:"statistics,omitempty"`
+}
+
+// Season of a series.
+type Season struct {
+	SeasonNumber int         `json:"seasonNumber"`
+	Monitored    bool        `json:"monitored"`
+	Statistics   *Statistics `json:"statistics,omitempty"`
+}
+
+// Statistics episode and size counters of a series or season.
+type Statistics struct {
+	EpisodeFileCount  int     `json:"episodeFileCount"`
+	EpisodeCount      int     `json:"episodeCount"`
+	TotalEpisodeCount int     `json:"totalEpisodeCount"`
+	SizeOnDisk        int64   `json:"sizeOnDisk"`
+	PercentOfEpisodes float64 `json:"percentOfEpisodes"`
+}
+
+// Image poster, banner or fanart of a series or episode.
+type Image struct {
+	CoverType string `json:"coverType"`
+	URL       string `json:"url"`
+	RemoteURL string `json:"remoteUrl"`
+}
+
+// Ratings of a series.
+type Ratings struct {
+	Votes int     `json:"votes"`
+	Value float64 `json:"value"`
+}
+
+// Episode full episode resource.
+type Episode struct {
+	ID                       int       `json:"id"`
+	SeriesID                 int       `json:"seriesId"`
+	TvdbID                   int       `json:"tvdbId"`
+	EpisodeFileID            int       `json:"episodeFileId"`
+	SeasonNumber             int       `json:"seasonNumber"`
+	EpisodeNumber            int       `json:"episodeNumber"`
+	Title                    string    `json:"title"`
+	AirDate                  string    `json:"airDate"`
+	AirDateUtc               time.Time `json:"airDateUtc"`
+	Overview                 string    `json:"overview"`
+	HasFile                  bool      `json:"hasFile"`
+	Monitored                bool      `json:"monitored"`
+	AbsoluteEpisodeNumber    int       `json:"absoluteEpisodeNumber"`
+	UnverifiedSceneNumbering bool      `json:"unverifiedSceneNumbering"`
+	Images                   []Image   `json:"images"`
+}
+
+// Tag label which can be assigned to series, indexers and connections.
+type Tag struct {
+	ID    int    `json:"id"`
+	Label string `json:"label"`
+}
@@ -0,0 +1,203 @@
+package eventt
+
+import (
+	"context"
+	"sync"
+)
+
+// TypeFilter set of event types, an empty filter matches all event types.
+type TypeFilter map[EventType]bool
+
+// Types return a TypeFilter matching the given event types, without arguments
+// it matches all event types.
+func Types(types ...EventType) TypeFilter {
+	f := make(TypeFilter, len(types))
+	for _, t := range types {
+		f[t] = true
+	}
+	return f
+}
+
+// Match report whether t is part of the filter.
+func (f TypeFilter) Match(t EventType) bool {
+	return len(f) == 0 || f[t]
+}
+
+// Overflow policy used when a subscription buffer is full.
+type Overflow int
+
+const (
+	// OverflowBlock wait until the subscriber receive the event, this will delay the
+	// response to Sonarr until the subscriber catch up or the request is canceled.
+	OverflowBlock Overflow = iota
+	// OverflowDropOldest discard the oldest buffered event to make room for the new one.
+	OverflowDropOldest
+	// OverflowDropNewest discard the new event and keep the buffered events.
+	OverflowDropNewest
+)
+
+// SubscribeOption optional settings for SonarrTriggers.Subscribe.
+type SubscribeOption func(sub *subscription)
+
+// WithOverflow set the subscription overflow policy, default is OverflowBlock.
+func WithOverflow(policy Overflow) SubscribeOption {
+	return func(sub *subscription) {
+		sub.overflow = policy
+	}
+}
+
+type subscription struct {
+	ch       chan Event
+	filter   TypeFilter
+	overflow Overflow
+
+	once sync.Once
+	// done closed before acquiring mu, so blocked senders can release it.
+	done   chan struct{}
+	mu     sync.Mutex
+	closed bool
+}
+
+// Subscribe return a channel receiving all events matching filter, events are
+// delivered in addition to the On... callbacks. buffer is the channel capacity,
+// when it is full the overflow policy decide what happen, see WithOverflow.
+// the channel is closed when ctx is done or SonarrTriggers.Close is called.
+//
+//	sub := triggers.Subscribe(ctx, eventt.Types(eventt.Grab, eventt.Download), 16)
+//	for event := range sub {
+//		switch e := event.(type) {
+//		case eventt.GrabEvent:
+//			...
+//		}
+//	}
+func (s *SonarrTriggers) Subscribe(ctx context.Context, filter TypeFilter, buffer int, opts ...SubscribeOption) <-chan Event {
+	if buffer < 0 {
+		buffer = 0
+	}
+	sub := &subscription{
+		ch:     make(chan Event, buffer),
+		filter: filter,
+		done:   make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(sub)
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		sub.close()
+		return sub.ch
+	}
+	if s.subs == nil {
+		s.subs = make(map[*subscription]struct{})
+	}
+	s.subs[sub] = struct{}{}
+	s.mu.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-sub.done:
+		}
+		s.mu.Lock()
+		delete(s.subs, sub)
+		s.mu.Unlock()
+		sub.close()
+	}()
+
+	return sub.ch
+}
+
+// Close close all subscriptions channels, any later call to Subscribe will
+// return a closed channel. callbacks are not affected.
+func (s *SonarrTriggers) Close() {
+	s.mu.Lock()
+	s.closed = true
+	subs := s.subs
+	s.subs = nil
+	s.mu.Unlock()
+
+	for sub := range subs {
+		sub.close()
+	}
+}
+
+// subscribed report whether any subscription is interested in t, it used to
+// skip decoding events when there is no callback or subscriber for them.
+func (s *SonarrTriggers) subscribed(t EventType) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for sub := range s.subs {
+		if sub.filter.Match(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// publish deliver e to all matching subscriptions, ctx limits how long
+// OverflowBlock subscriptions can delay the delivery.
+func (s *SonarrTriggers) publish(ctx context.Context, e Event) {
+	t := TypeOf(e)
+
+	s.mu.RLock()
+	subs := make([]*subscription, 0, len(s.subs))
+	for sub := range s.subs {
+		if sub.filter.Match(t) {
+			subs = append(subs, sub)
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, sub := range subs {
+		sub.send(ctx, e)
+	}
+}
+
+func (sub *subscription) send(ctx context.Context, e Event) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if sub.closed {
+		return
+	}
+
+	switch sub.overflow {
+	case OverflowDropNewest:
+		select {
+		case sub.ch <- e:
+		default:
+		}
+	case OverflowDropOldest:
+		for {
+			select {
+			case sub.ch <- e:
+				return
+			default:
+			}
+			if cap(sub.ch) == 0 {
+				return
+			}
+			select {
+			case <-sub.ch:
+			default:
+			}
+		}
+	default:
+		select {
+		case sub.ch <- e:
+		case <-sub.done:
+		case <-ctx.Done():
+		}
+	}
+}
+
+func (sub *subscription) close() {
+	sub.once.Do(func() {
+		close(sub.done)
+		sub.mu.Lock()
+		sub.closed = true
+		close(sub.ch)
+		sub.mu.Unlock()
+	})
+}
@@ -0,0 +1,136 @@
+package eventt
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func dispatchAll(t *testing.T, s *SonarrTriggers, payloads ...string) {
+	t.Helper()
+	for _, p := range payloads {
+		if err := s.Dispatch(context.Background(), []byte(p)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func receiveAll(ch <-chan Event) []Event {
+	var events []Event
+	for {
+		select {
+		case e, ok := <-ch:
+			if !ok {
+				return events
+			}
+			events = append(events, e)
+		default:
+			return events
+		}
+	}
+}
+
+func TestSubscribeFilter(t *testing.T) {
+	s := &SonarrTriggers{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	grabs := s.Subscribe(ctx, Types(Grab), 4)
+	all := s.Subscribe(ctx, Types(), 4)
+
+	dispatchAll(t, s,
+		`{"eventType":"Grab","release":{"releaseTitle":"one"}}`,
+		`{"eventType":"Health","message":"down"}`,
+		`{"eventType":"ManualInteractionRequired"}`,
+	)
+
+	if got := receiveAll(grabs); len(got) != 1 || got[0].(GrabEvent).Release.ReleaseTitle != "one" {
+		t.Errorf("grab subscription got %v", got)
+	}
+	got := receiveAll(all)
+	if len(got) != 3 || TypeOf(got[1]) != Health || TypeOf(got[2]) != Unknown {
+		t.Errorf("all subscription got %v", got)
+	}
+}
+
+func TestSubscribeOverflow(t *testing.T) {
+	payloads := []string{
+		`{"eventType":"Grab","release":{"releaseTitle":"1"}}`,
+		`{"eventType":"Grab","release":{"releaseTitle":"2"}}`,
+		`{"eventType":"Grab","release":{"releaseTitle":"3"}}`,
+	}
+	titles := func(events []Event) (s string) {
+		for _, e := range events {
+			s += e.(GrabEvent).Release.ReleaseTitle
+		}
+		return s
+	}
+
+	s := &SonarrTriggers{}
+	oldest := s.Subscribe(context.Background(), nil, 2, WithOverflow(OverflowDropOldest))
+	newest := s.Subscribe(context.Background(), nil, 2, WithOverflow(OverflowDropNewest))
+	dispatchAll(t, s, payloads...)
+	if got := titles(receiveAll(oldest)); got != "23" {
+		t.Errorf("drop oldest kept %q, want 23", got)
+	}
+	if got := titles(receiveAll(newest)); got != "12" {
+		t.Errorf("drop newest kept %q, want 12", got)
+	}
+	s.Close()
+}
+
+func TestSubscribeBlock(t *testing.T) {
+	s := &SonarrTriggers{}
+	ch := s.Subscribe(context.Background(), nil, 0)
+	defer s.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := s.Dispatch(ctx, []byte(`{"eventType":"Test"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) < 50*time.Millisecond {
+		t.Error("blocking subscription did not wait for the receiver")
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- s.Dispatch(context.Background(), []byte(`{"eventType":"Test"}`)) }()
+	select {
+	case e := <-ch:
+		if TypeOf(e) != Test {
+			t.Errorf("got %T", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("event not delivered")
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSubscribeClose(t *testing.T) {
+	s := &SonarrTriggers{}
+	ctx, cancel := context.WithCancel(context.Background())
+	canceled := s.Subscribe(ctx, nil, 1)
+	closed := s.Subscribe(context.Background(), nil, 1)
+
+	cancel()
+	select {
+	case _, ok := <-canceled:
+		if ok {
+			t.Error("unexpected event")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("subscription not closed when ctx is done")
+	}
+
+	s.Close()
+	if _, ok := <-closed; ok {
+		t.Error("subscription not closed by Close")
+	}
+	if _, ok := <-s.Subscribe(context.Background(), nil, 1); ok {
+		t.Error("Subscribe after Close returned an open channel")
+	}
+	// dispatching after Close does not panic on the closed channels.
+	dispatchAll(t, s, `{"eventType":"Test"}`)
+}
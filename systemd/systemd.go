@@ -0,0 +1,106 @@
+// Package systemd minimal support of systemd socket activation and sd_notify protocol,
+// without linking libsystemd.
+// see: https://www.freedesktop.org/software/systemd/man/sd_listen_fds.html
+// and https://www.freedesktop.org/software/systemd/man/sd_notify.html
+package systemd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// listenFdsStart first file descriptor passed by systemd.
+const listenFdsStart = 3
+
+// States sent with Notify.
+const (
+	Ready     = "READY=1"
+	Reloading = "RELOADING=1"
+	Stopping  = "STOPPING=1"
+	Watchdog  = "WATCHDOG=1"
+)
+
+// Socket listener passed by systemd socket activation.
+type Socket struct {
+	// Name from FileDescriptorName= in the socket unit, default the socket unit name.
+	Name     string
+	Listener net.Listener
+}
+
+// Listeners return the sockets passed by systemd, nil if the process was not socket
+// activated. the environment variables are unset so child processes do not use them.
+func Listeners() ([]Socket, error) {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return nil, nil
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+	sockets := make([]Socket, 0, n)
+	for i := 0; i < n; i++ {
+		name := "unknown"
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+		f := os.NewFile(uintptr(listenFdsStart+i), name)
+		l, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			for _, s := range sockets {
+				s.Listener.Close()
+			}
+			return nil, fmt.Errorf("systemd: socket %d (%s): %w", listenFdsStart+i, name, err)
+		}
+		sockets = append(sockets, Socket{Name: name, Listener: l})
+	}
+	return sockets, nil
+}
+
+// Notify send state to systemd, e.g. Ready, it does nothing if the service is not
+// of Type=notify, i.e. NOTIFY_SOCKET is not set.
+func Notify(state string) error {
+	addr := os.Getenv("NOTIFY_SOCKET")
+	if addr == "" {
+		return nil
+	}
+	// abstract sockets start with '@'.
+	if addr[0] == '@' {
+		addr = "\x00" + addr[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf("systemd: notify: %w", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return fmt.Errorf("systemd: notify: %w", err)
+	}
+	return nil
+}
+
+// WatchdogInterval return WatchdogSec= of the service, zero if the watchdog is
+// disabled. Watchdog must be sent more often, usually every half of it.
+func WatchdogInterval() time.Duration {
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
@@ -0,0 +1,459 @@
+package eventt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// TemplateFuncs helper functions available in Templates:
+//
+//	episodeCode  episode code of an Episode or Episodes, e.g. S01E02 or S01E02-E04, an
+//	             optional series type selects the numbering, e.g. {{episodeCode .Episodes .Series.Type}}
+//	humanSize    size in bytes as human readable text, e.g. 1.4 GiB
+//	join         join a list with a separator, e.g. {{join .Enrichment.Series.Genres ", "}}
+//	quality      quality name of an event, e.g. WEBDL-1080p
+var TemplateFuncs = template.FuncMap{
+	"episodeCode": templateEpisodeCode,
+	"humanSize":   templateHumanSize,
+	"join":        templateJoin,
+	"quality":     templateQuality,
+}
+
+// Templates user defined text/template templates for events, defined per event type
+// and per sink, e.g. a different Grab message for Discord and Telegram.
+// templates are checked against the event struct when they are parsed, so
+// a typo like {{.Series.Titel}} fails when loading the templates instead of
+// when the event is received. the zero value is ready to use.
+type Templates struct {
+	set map[templateKey]*template.Template
+}
+
+type templateKey struct {
+	sink  string
+	event EventType
+}
+
+// eventStructs concrete type of each event, used to check templates and generate schemas.
+var eventStructs = map[EventType]reflect.Type{
+	Grab:              reflect.TypeOf(GrabEvent{}),
+	Download:          reflect.TypeOf(DownloadEvent{}),
+	Rename:            reflect.TypeOf(RenameEvent{}),
+	EpisodeFileDelete: reflect.TypeOf(EpisodeFileDeleteEvent{}),
+	SeriesDelete:      reflect.TypeOf(SeriesDeleteEvent{}),
+	Health:            reflect.TypeOf(HealthEvent{}),
+	ApplicationUpdate: reflect.TypeOf(ApplicationUpdateEvent{}),
+	Test:              reflect.TypeOf(TestEvent{}),
+	Unknown:           reflect.TypeOf(UnknownEvent{}),
+}
+
+// Parse define the template of event for sink, an empty sink define the default
+// template used by all sinks without their own template.
+func (t *Templates) Parse(sink string, event EventType, text string) error {
+	st, ok := eventStructs[event]
+	if !ok {
+		return fmt.Errorf("unknown event type '%s'", event)
+	}
+
+	name := event.templateName(sink)
+	tmpl, err := template.New(name).Funcs(TemplateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return err
+	}
+	if err := checkTemplate(tmpl, st); err != nil {
+		return err
+	}
+
+	if t.set == nil {
+		t.set = make(map[templateKey]*template.Template)
+	}
+	t.set[templateKey{sink, event}] = tmpl
+	return nil
+}
+
+// ParseFile same as Parse but read the template from a file.
+func (t *Templates) ParseFile(sink string, event EventType, path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return t.Parse(sink, event, string(b))
+}
+
+// ParseDir load all templates from dir, default templates are named after the
+// event type and sink templates are inside a directory named after the sink:
+//
+//	dir/Grab.tmpl          default Grab template
+//	dir/discord/Grab.tmpl  Grab template for discord sink
+func (t *Templates) ParseDir(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+	if err != nil {
+		return err
+	}
+	sinkFiles, err := filepath.Glob(filepath.Join(dir, "*", "*.tmpl"))
+	if err != nil {
+		return err
+	}
+
+	for _, path := range append(files, sinkFiles...) {
+		sink, _ := filepath.Rel(dir, filepath.Dir(path))
+		if sink == "." {
+			sink = ""
+		}
+		event := EventType(strings.TrimSuffix(filepath.Base(path), ".tmpl"))
+		if err := t.ParseFile(sink, event, path); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// Has report whether there is a template for event and sink, including the default template.
+func (t *Templates) Has(sink string, event EventType) bool {
+	return t.lookup(sink, event) != nil
+}
+
+// Render execute the template of the event for sink, falling back to the default
+// template, it returns an error if there is no template for the event.
+func (t *Templates) Render(sink string, e Event) (string, error) {
+	tmpl := t.lookup(sink, TypeOf(e))
+	if tmpl == nil {
+		return "", fmt.Errorf("no template for '%s' event", TypeOf(e).templateName(sink))
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, e); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func (t *Templates) lookup(sink string, event EventType) *template.Template {
+	if t == nil {
+		return nil
+	}
+	if tmpl, ok := t.set[templateKey{sink, event}]; ok {
+		return tmpl
+	}
+	return t.set[templateKey{"", event}]
+}
+
+func (e EventType) templateName(sink string) string {
+	if sink == "" {
+		return string(e)
+	}
+	return sink + "/" + string(e)
+}
+
+// checkTemplate walk the template tree and check all fields exist in the data type,
+// templates defined with {{define}} are checked with the data of their {{template}} calls.
+func checkTemplate(tmpl *template.Template, data reflect.Type) error {
+	return checkNamedTemplate(tmpl, tmpl.Name(), data, make(map[templateCall]bool))
+}
+
+// templateCall a defined template called with a data type.
+type templateCall struct {
+	name string
+	data reflect.Type
+}
+
+func checkNamedTemplate(tmpl *template.Template, name string, data reflect.Type, checked map[templateCall]bool) error {
+	call := templateCall{name, data}
+	t := tmpl.Lookup(name)
+	if checked[call] || t == nil || t.Tree == nil || t.Tree.Root == nil {
+		return nil
+	}
+	// mark before walking the tree so recursive templates are checked once.
+	checked[call] = true
+	c := &templateChecker{tmpl: tmpl, tree: t.Tree, vars: map[string]reflect.Type{"$": data}, checked: checked}
+	return c.list(data, t.Tree.Root)
+}
+
+// templateChecker static type checker for templates fields, a nil type means
+// the type is not known (e.g. interface{}) and anything is accepted.
+type templateChecker struct {
+	tmpl    *template.Template
+	tree    *parse.Tree
+	vars    map[string]reflect.Type
+	checked map[templateCall]bool
+}
+
+func (c *templateChecker) list(dot reflect.Type, l *parse.ListNode) error {
+	if l == nil {
+		return nil
+	}
+	for _, n := range l.Nodes {
+		if err := c.node(dot, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *templateChecker) node(dot reflect.Type, n parse.Node) error {
+	switch n := n.(type) {
+	case *parse.ActionNode:
+		_, err := c.pipe(dot, n.Pipe)
+		return err
+	case *parse.IfNode:
+		return c.branch(dot, &n.BranchNode, func(reflect.Type) reflect.Type { return dot })
+	case *parse.WithNode:
+		return c.branch(dot, &n.BranchNode, func(t reflect.Type) reflect.Type { return t })
+	case *parse.RangeNode:
+		return c.branch(dot, &n.BranchNode, elemType)
+	case *parse.TemplateNode:
+		t, err := c.pipe(dot, n.Pipe)
+		if err != nil {
+			return err
+		}
+		return checkNamedTemplate(c.tmpl, n.Name, t, c.checked)
+	case *parse.ListNode:
+		return c.list(dot, n)
+	}
+	return nil
+}
+
+func (c *templateChecker) branch(dot reflect.Type, b *parse.BranchNode, inner func(reflect.Type) reflect.Type) error {
+	t, err := c.pipe(dot, b.Pipe)
+	if err != nil {
+		return err
+	}
+	if b.NodeType == parse.NodeRange && len(b.Pipe.Decl) == 2 {
+		// {{range $i, $e := .List}}
+		c.vars[b.Pipe.Decl[0].Ident[0]] = keyType(t)
+		c.vars[b.Pipe.Decl[1].Ident[0]] = elemType(t)
+	} else if b.NodeType == parse.NodeRange && len(b.Pipe.Decl) == 1 {
+		c.vars[b.Pipe.Decl[0].Ident[0]] = elemType(t)
+	}
+	if err := c.list(inner(t), b.List); err != nil {
+		return err
+	}
+	return c.list(dot, b.ElseList)
+}
+
+func (c *templateChecker) pipe(dot reflect.Type, p *parse.PipeNode) (reflect.Type, error) {
+	if p == nil {
+		return nil, nil
+	}
+	var t reflect.Type
+	for _, cmd := range p.Cmds {
+		var err error
+		if t, err = c.command(dot, cmd); err != nil {
+			return nil, err
+		}
+	}
+	if len(p.Decl) == 1 {
+		c.vars[p.Decl[0].Ident[0]] = t
+	}
+	return t, nil
+}
+
+func (c *templateChecker) command(dot reflect.Type, cmd *parse.CommandNode) (reflect.Type, error) {
+	if len(cmd.Args) == 0 {
+		return nil, nil
+	}
+	for _, arg := range cmd.Args[1:] {
+		if _, err := c.arg(dot, arg); err != nil {
+			return nil, err
+		}
+	}
+	if ident, ok := cmd.Args[0].(*parse.IdentifierNode); ok {
+		fn, ok := TemplateFuncs[ident.Ident]
+		if !ok {
+			// builtin functions like len, index and printf.
+			return nil, nil
+		}
+		ft := reflect.TypeOf(fn)
+		if ft.NumOut() == 0 {
+			return nil, nil
+		}
+		return ft.Out(0), nil
+	}
+	return c.arg(dot, cmd.Args[0])
+}
+
+func (c *templateChecker) arg(dot reflect.Type, n parse.Node) (reflect.Type, error) {
+	switch n := n.(type) {
+	case *parse.DotNode:
+		return dot, nil
+	case *parse.FieldNode:
+		return c.fields(n, dot, n.Ident)
+	case *parse.VariableNode:
+		t, ok := c.vars[n.Ident[0]]
+		if !ok {
+			return nil, nil
+		}
+		return c.fields(n, t, n.Ident[1:])
+	case *parse.ChainNode:
+		t, err := c.arg(dot, n.Node)
+		if err != nil {
+			return nil, err
+		}
+		return c.fields(n, t, n.Field)
+	case *parse.PipeNode:
+		return c.pipe(dot, n)
+	case *parse.StringNode:
+		return reflect.TypeOf(""), nil
+	case *parse.BoolNode:
+		return reflect.TypeOf(true), nil
+	}
+	return nil, nil
+}
+
+// fields resolve a chain of fields or methods starting from t.
+func (c *templateChecker) fields(n parse.Node, t reflect.Type, names []string) (reflect.Type, error) {
+	for _, name := range names {
+		if t == nil {
+			return nil, nil
+		}
+		next, ok := fieldType(t, name)
+		if !ok {
+			location, context := c.tree.ErrorContext(n)
+			return nil, fmt.Errorf("template: %s: executing %q: can't evaluate field %s in type %s",
+				location, context, name, t)
+		}
+		t = next
+	}
+	return t, nil
+}
+
+func fieldType(t reflect.Type, name string) (reflect.Type, bool) {
+	if m, ok := t.MethodByName(name); ok {
+		return methodResult(m.Type), true
+	}
+	if t.Kind() != reflect.Pointer && t.Kind() != reflect.Interface {
+		if m, ok := reflect.PointerTo(t).MethodByName(name); ok {
+			return methodResult(m.Type), true
+		}
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Interface:
+		return nil, true
+	case reflect.Map:
+		if t.Key().Kind() == reflect.String {
+			return unknownIfInterface(t.Elem()), true
+		}
+	case reflect.Struct:
+		if f, ok := t.FieldByName(name); ok && f.IsExported() {
+			return unknownIfInterface(f.Type), true
+		}
+	}
+	return nil, false
+}
+
+func methodResult(t reflect.Type) reflect.Type {
+	if t.NumOut() == 0 {
+		return nil
+	}
+	return unknownIfInterface(t.Out(0))
+}
+
+func unknownIfInterface(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Interface {
+		return nil
+	}
+	return t
+}
+
+func elemType(t reflect.Type) reflect.Type {
+	if t == nil {
+		return nil
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Chan:
+		return unknownIfInterface(t.Elem())
+	}
+	return nil
+}
+
+func keyType(t reflect.Type) reflect.Type {
+	if t == nil {
+		return nil
+	}
+	if t.Kind() == reflect.Map {
+		return unknownIfInterface(t.Key())
+	}
+	return reflect.TypeOf(0)
+}
+
+func templateEpisodeCode(v interface{}, seriesType ...string) (string, error) {
+	t := SeriesStandard
+	if len(seriesType) > 0 {
+		t = seriesType[0]
+	}
+	switch v := v.(type) {
+	case Episode:
+		return v.CodeFor(t), nil
+	case Episodes:
+		return v.CodeFor(t), nil
+	case []Episode:
+		return Episodes(v).CodeFor(t), nil
+	}
+	return "", fmt.Errorf("episodeCode: unsupported type %T", v)
+}
+
+func templateHumanSize(v interface{}) (string, error) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return HumanSize(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return HumanSize(int64(rv.Uint())), nil
+	case reflect.Float32, reflect.Float64:
+		return HumanSize(int64(rv.Float())), nil
+	}
+	return "", fmt.Errorf("humanSize: unsupported type %T", v)
+}
+
+func templateJoin(list interface{}, sep string) (string, error) {
+	rv := reflect.ValueOf(list)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return "", fmt.Errorf("join: unsupported type %T", list)
+	}
+	items := make([]string, rv.Len())
+	for i := range items {
+		items[i] = fmt.Sprint(rv.Index(i).Interface())
+	}
+	return strings.Join(items, sep), nil
+}
+
+func templateQuality(v interface{}) (string, error) {
+	switch e := v.(type) {
+	case string:
+		return e, nil
+	case GrabEvent:
+		return e.Release.Quality, nil
+	case DownloadEvent:
+		return e.EpisodeFile.Quality, nil
+	case EpisodeFileDeleteEvent:
+		return e.EpisodeFile.Quality, nil
+	}
+	return "", fmt.Errorf("quality: unsupported type %T", v)
+}
+
+// HumanSize format size in bytes using binary units, e.g. 1.4 GiB, it returns
+// an empty string for zero or negative sizes.
+func HumanSize(size int64) string {
+	if size <= 0 {
+		return ""
+	}
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
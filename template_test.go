@@ -0,0 +1,67 @@
+package eventt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTemplatesDefinedTemplates(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		err  string
+	}{
+		{
+			name: "defined template typed from call site",
+			text: `{{define "ep"}}{{.Title}}{{end}}{{range .Episodes}}{{template "ep" .}}{{end}}`,
+		},
+		{
+			name: "defined template with error",
+			text: `{{define "ep"}}{{.Titel}}{{end}}{{range .Episodes}}{{template "ep" .}}{{end}}`,
+			err:  "can't evaluate field Titel in type eventt.Episode",
+		},
+		{
+			name: "defined template called with the event",
+			text: `{{define "series"}}{{.Series.Title}}{{end}}{{template "series" .}}`,
+		},
+		{
+			name: "defined template without call",
+			text: `{{define "unused"}}{{.Anything}}{{end}}{{.Series.Title}}`,
+		},
+		{
+			name: "recursive template",
+			text: `{{define "r"}}{{if .Series.Title}}{{template "r" .}}{{end}}{{end}}{{template "r" .}}`,
+		},
+		{
+			name: "join example",
+			text: `{{join .Enrichment.Series.Genres ", "}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&Templates{}).Parse("", Grab, tt.text)
+			switch {
+			case tt.err == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Fatalf("got error %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestTemplatesRenderDefined(t *testing.T) {
+	templates := &Templates{}
+	err := templates.Parse("", Grab, `{{define "ep"}}{{.Title}};{{end}}{{range .Episodes}}{{template "ep" .}}{{end}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := GrabEvent{Episodes: Episodes{{Title: "One"}, {Title: "Two"}}}
+	got, err := templates.Render("", e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "One;Two;" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,149 @@
+package eventt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version of Sonarr which sent a webhook payload, e.g. 3.0.9.1549. only Major is set
+// when the version is detected from the payload shape.
+type Version struct {
+	Major, Minor, Patch, Build int
+}
+
+// String return the version as dotted numbers, e.g. "4.0.0.748", or "v3" when only
+// the major version is known and "unknown" for the zero Version.
+func (v Version) String() string {
+	switch {
+	case v == Version{}:
+		return "unknown"
+	case v == Version{Major: v.Major}:
+		return fmt.Sprintf("v%d", v.Major)
+	}
+	return fmt.Sprintf("%d.%d.%d.%d", v.Major, v.Minor, v.Patch, v.Build)
+}
+
+// ParseVersion parse dotted version numbers like "3.0.9.1549", missing parts are zero.
+func ParseVersion(s string) (Version, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) > 4 {
+		return Version{}, fmt.Errorf("invalid version '%s'", s)
+	}
+	var n [4]int
+	for i, p := range parts {
+		v, err := strconv.Atoi(p)
+		if err != nil || v < 0 {
+			return Version{}, fmt.Errorf("invalid version '%s'", s)
+		}
+		n[i] = v
+	}
+	return Version{Major: n[0], Minor: n[1], Patch: n[2], Build: n[3]}, nil
+}
+
+// DetectVersion detect the Sonarr version from the User-Agent header sent by Sonarr,
+// e.g. "Sonarr/3.0.9.1549 (ubuntu 20.04)", when the header is missing or sent by
+// something else the major version is guessed from the fields only Sonarr v4 sends:
+// instanceName, applicationUrl, customFormatInfo, languages as objects and the media
+// info or source path of the episode file.
+func DetectVersion(userAgent string, payload []byte) Version {
+	for _, product := range strings.Fields(userAgent) {
+		if !strings.HasPrefix(product, "Sonarr/") {
+			continue
+		}
+		if v, err := ParseVersion(strings.TrimPrefix(product, "Sonarr/")); err == nil {
+			return v
+		}
+	}
+
+	var probe struct {
+		InstanceName     *string         `json:"instanceName"`
+		ApplicationURL   *string         `json:"applicationUrl"`
+		CustomFormatInfo json.RawMessage `json:"customFormatInfo"`
+		Release          struct {
+			Languages json.RawMessage `json:"languages"`
+		} `json:"release"`
+		EpisodeFile struct {
+			Quality    json.RawMessage `json:"quality"`
+			Languages  json.RawMessage `json:"languages"`
+			MediaInfo  json.RawMessage `json:"mediaInfo"`
+			SourcePath *string         `json:"sourcePath"`
+		} `json:"episodeFile"`
+	}
+	if err := json.Unmarshal(payload, &probe); err != nil {
+		return Version{}
+	}
+	ef := probe.EpisodeFile
+	switch {
+	case probe.InstanceName != nil, probe.ApplicationURL != nil, len(probe.CustomFormatInfo) > 0:
+		return Version{Major: 4}
+	case objectList(probe.Release.Languages), objectList(ef.Languages):
+		return Version{Major: 4}
+	case isString(ef.Quality) && (len(ef.MediaInfo) > 0 || ef.SourcePath != nil):
+		// the v3 full episode file has a media info too, but with a nested quality.
+		return Version{Major: 4}
+	}
+	return Version{Major: 3}
+}
+
+// objectList report whether raw is a JSON array of objects, e.g. the v4 languages.
+func objectList(raw json.RawMessage) bool {
+	var list []json.RawMessage
+	if err := json.Unmarshal(raw, &list); err != nil || len(list) == 0 {
+		return false
+	}
+	return len(list[0]) > 0 && list[0][0] == '{'
+}
+
+// isString report whether raw is a JSON string.
+func isString(raw json.RawMessage) bool {
+	return len(raw) > 0 && raw[0] == '"'
+}
+
+// Source where a typed event come from, set on every typed event decoded by
+// SonarrTriggers or Parse.
+type Source struct {
+	// Version of Sonarr which sent the event, see DetectVersion.
+	Version Version
+	// Payload version specific struct the event was normalized from, it is only set
+	// when the payload differ from the event struct, e.g. *V3EpisodeFileDeleteEvent
+	// for EpisodeFileDelete events sent by Sonarr v3.
+	Payload interface{}
+
+	// instance name set on the event by withInstance.
+	instance string
+}
+
+type userAgentKey struct{}
+
+// WithUserAgent return a copy of ctx with the User-Agent header of the request which
+// delivered the payload, it is set by Monitor and Mux and it can be used with
+// SonarrTriggers.Dispatch to detect the Sonarr version of forwarded payloads.
+func WithUserAgent(ctx context.Context, userAgent string) context.Context {
+	return context.WithValue(ctx, userAgentKey{}, userAgent)
+}
+
+func userAgentFromContext(ctx context.Context) string {
+	ua, _ := ctx.Value(userAgentKey{}).(string)
+	return ua
+}
+
+// sourceOf return the Source of payload, the version specific payload is decoded for
+// the events which have one.
+func sourceOf(eventType EventType, userAgent string, payload []byte) Source {
+	src := Source{Version: DetectVersion(userAgent, payload)}
+	if eventType == EpisodeFileDelete && isV3EpisodeFile(payload) {
+		v3 := &V3EpisodeFileDeleteEvent{}
+		if err := json.Unmarshal(payload, v3); err == nil {
+			src.Payload = v3
+		}
+	}
+	return src
+}
+
+// withSource set the Source field of the typed event e.
+func withSource(e Event, src Source) Event {
+	return updateEvent(e, func(f eventFields) { *f.source = src })
+}
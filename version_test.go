@@ -0,0 +1,65 @@
+package eventt
+
+import "testing"
+
+func TestDetectVersion(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		payload   string
+		want      Version
+	}{
+		{
+			name:      "user agent",
+			userAgent: "Sonarr/3.0.9.1549 (ubuntu 20.04)",
+			payload:   `{"eventType":"Grab","instanceName":"Sonarr"}`,
+			want:      Version{Major: 3, Minor: 0, Patch: 9, Build: 1549},
+		},
+		{
+			name:    "v3 download with quality name",
+			payload: `{"eventType":"Download","episodeFile":{"id":1,"relativePath":"S01E01.mkv","quality":"WEBDL-1080p","qualityVersion":1,"sceneName":"Show.S01E01"},"isUpgrade":false}`,
+			want:    Version{Major: 3},
+		},
+		{
+			name:    "v3 episode file delete",
+			payload: `{"eventType":"EpisodeFileDelete","episodeFile":{"quality":{"quality":{"name":"HDTV-720p"}},"mediaInfo":{"videoFormat":"AVC"}}}`,
+			want:    Version{Major: 3},
+		},
+		{
+			name:    "v3 grab",
+			payload: `{"eventType":"Grab","release":{"quality":"WEBDL-1080p","releaseTitle":"Show.S01E01"}}`,
+			want:    Version{Major: 3},
+		},
+		{
+			name:    "v4 instance name",
+			payload: `{"eventType":"Test","instanceName":"Sonarr"}`,
+			want:    Version{Major: 4},
+		},
+		{
+			name:    "v4 release languages",
+			payload: `{"eventType":"Grab","release":{"quality":"WEBDL-1080p","languages":[{"id":1,"name":"English"}]}}`,
+			want:    Version{Major: 4},
+		},
+		{
+			name:    "v4 custom formats",
+			payload: `{"eventType":"Grab","customFormatInfo":{"customFormats":[],"customFormatScore":0}}`,
+			want:    Version{Major: 4},
+		},
+		{
+			name:    "v4 episode file media info",
+			payload: `{"eventType":"Download","episodeFile":{"quality":"WEBDL-1080p","mediaInfo":{"videoCodec":"x264"}}}`,
+			want:    Version{Major: 4},
+		},
+		{
+			name:    "invalid payload",
+			payload: `{`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectVersion(tt.userAgent, []byte(tt.payload)); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
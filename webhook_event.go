@@ -1,64 +1,171 @@
 package eventt
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type WebhookEvent struct {
 	EventType string `json:"eventType"`
 }
 
-type eventType interface {
-	eventName() string
+// Event implemented by all webhook events: GrabEvent, DownloadEvent, RenameEvent,
+// EpisodeFileDeleteEvent, SeriesDeleteEvent, HealthEvent, ApplicationUpdateEvent,
+// TestEvent and UnknownEvent. use a type switch to get the concrete event. typed events
+// keep the received payload in Raw and the fields they don't model in Extra, see
+// GrabEvent.MarshalJSON.
+type Event interface {
+	eventName() EventType
+}
+
+// eventFields pointers to the fields shared by the typed events, the fields an event
+// does not have are nil, e.g. enrichment of HealthEvent.
+type eventFields struct {
+	source       *Source
+	raw          *json.RawMessage
+	extra        *map[string]json.RawMessage
+	instanceName *string
+	enrichment   **Enrichment
+	episodes     *Episodes
+}
+
+// updateEvent call update with the fields of the typed event e and return the updated
+// event, other events are returned unchanged.
+func updateEvent(e Event, update func(f eventFields)) Event {
+	switch e := e.(type) {
+	case GrabEvent:
+		update(eventFields{&e.Source, &e.Raw, &e.Extra, &e.InstanceName, &e.Enrichment, &e.Episodes})
+		return e
+	case DownloadEvent:
+		update(eventFields{&e.Source, &e.Raw, &e.Extra, &e.InstanceName, &e.Enrichment, &e.Episodes})
+		return e
+	case RenameEvent:
+		update(eventFields{&e.Source, &e.Raw, &e.Extra, &e.InstanceName, &e.Enrichment, nil})
+		return e
+	case EpisodeFileDeleteEvent:
+		update(eventFields{&e.Source, &e.Raw, &e.Extra, &e.InstanceName, &e.Enrichment, &e.Episodes})
+		return e
+	case SeriesDeleteEvent:
+		update(eventFields{&e.Source, &e.Raw, &e.Extra, &e.InstanceName, nil, nil})
+		return e
+	case HealthEvent:
+		update(eventFields{&e.Source, &e.Raw, &e.Extra, &e.InstanceName, nil, nil})
+		return e
+	case ApplicationUpdateEvent:
+		update(eventFields{&e.Source, &e.Raw, &e.Extra, &e.InstanceName, nil, nil})
+		return e
+	case TestEvent:
+		update(eventFields{&e.Source, &e.Raw, &e.Extra, &e.InstanceName, nil, &e.Episodes})
+		return e
+	}
+	return e
+}
+
+// TypeOf return the event type of e.
+func TypeOf(e Event) EventType {
+	return e.eventName()
 }
 
+// EventType webhook event type as sent by Sonarr in the eventType field.
+type EventType string
+
 // WebhookEventTypes
 // see: https://github.com/Sonarr/Sonarr/blob/v3.0.9.1549/src/NzbDrone.Core/Notifications/Webhook/WebhookEventType.cs#L9
 const (
 	// see GrabEvent
-	grab = "Grab"
+	Grab EventType = "Grab"
 
 	// see DownloadEvent
-	download = "Download"
+	Download EventType = "Download"
 
 	// see RenameEvent
-	rename = "Rename"
+	Rename EventType = "Rename"
 
 	// see EpisodeFileDeleteEvent
-	episodeFileDelete = "EpisodeFileDelete"
+	EpisodeFileDelete EventType = "EpisodeFileDelete"
 
 	// see SeriesDeleteEvent
-	seriesDelete = "SeriesDelete"
+	SeriesDelete EventType = "SeriesDelete"
 
 	// see HealthIssueEvent
-	health = "Health"
+	Health EventType = "Health"
 
 	// see ApplicationUpdateEvent
-	applicationUpdate = "ApplicationUpdate"
+	ApplicationUpdate EventType = "ApplicationUpdate"
 
 	// see TestEvent
-	test = "Test"
+	Test EventType = "Test"
+
+	// see UnknownEvent
+	Unknown EventType = "Unknown"
 )
 
+// Series webhook series information included in most of the events.
+type Series struct {
+	ID       int    `json:"id"`
+	Title    string `json:"title"`
+	Path     string `json:"path"`
+	TvdbID   int    `json:"tvdbId"`
+	TvMazeID int    `json:"tvMazeId"`
+	ImdbID   string `json:"imdbId"`
+	Type     string `json:"type"`
+}
+
+// Episode webhook episode information included in Grab, Download, EpisodeFileDelete and Test events.
+type Episode struct {
+	ID            int       `json:"id"`
+	EpisodeNumber int       `json:"episodeNumber"`
+	SeasonNumber  int       `json:"seasonNumber"`
+	Title         string    `json:"title"`
+	AirDate       string    `json:"airDate"`
+	AirDateUtc    time.Time `json:"airDateUtc"`
+	// AbsoluteEpisodeNumber used by anime series, it is not sent in webhook payloads
+	// and it is set from the Enrichment when SonarrTriggers.Enricher is set.
+	AbsoluteEpisodeNumber int `json:"absoluteEpisodeNumber,omitempty"`
+}
+
+// EpisodeFile webhook episode file included in Download and EpisodeFileDelete events,
+// the full episode file sent by Sonarr v3 in EpisodeFileDelete events is normalized
+// into it, see V3EpisodeFile.
+type EpisodeFile struct {
+	ID             int       `json:"id"`
+	RelativePath   string    `json:"relativePath"`
+	Path           string    `json:"path"`
+	Quality        string    `json:"quality"`
+	QualityVersion int       `json:"qualityVersion"`
+	ReleaseGroup   string    `json:"releaseGroup"`
+	SceneName      string    `json:"sceneName"`
+	Size           int       `json:"size"`
+	DateAdded      time.Time `json:"dateAdded"`
+	// SourcePath path the file was imported from, sent by Sonarr v4 only.
+	SourcePath string    `json:"sourcePath"`
+	MediaInfo  MediaInfo `json:"mediaInfo"`
+}
+
+// MediaInfo webhook media information of an episode file.
+type MediaInfo struct {
+	AudioChannels         float64  `json:"audioChannels"`
+	AudioCodec            string   `json:"audioCodec"`
+	AudioLanguages        []string `json:"audioLanguages"`
+	Height                int      `json:"height"`
+	Width                 int      `json:"width"`
+	Subtitles             []string `json:"subtitles"`
+	VideoCodec            string   `json:"videoCodec"`
+	VideoDynamicRange     string   `json:"videoDynamicRange"`
+	VideoDynamicRangeType string   `json:"videoDynamicRangeType"`
+	// RunTime, VideoBitrate and AudioBitrate are only sent by Sonarr v3, the run time
+	// is formatted as "00:42:30.1230000" and the bitrates are in bits per second.
+	RunTime      string `json:"runTime,omitempty"`
+	VideoBitrate int    `json:"videoBitrate,omitempty"`
+	AudioBitrate int    `json:"audioBitrate,omitempty"`
+}
+
 // GrabEvent webhook grab payload
 // see: https://github.com/Sonarr/Sonarr/blob/v3.0.9.1549/src/NzbDrone.Core/Notifications/Webhook/Webhook.cs#L23
 type GrabEvent struct {
-	Series struct {
-		ID       int    `json:"id"`
-		Title    string `json:"title"`
-		Path     string `json:"path"`
-		TvdbID   int    `json:"tvdbId"`
-		TvMazeID int    `json:"tvMazeId"`
-		ImdbID   string `json:"imdbId"`
-		Type     string `json:"type"`
-	} `json:"series"`
-	Episodes []struct {
-		ID            int       `json:"id"`
-		EpisodeNumber int       `json:"episodeNumber"`
-		SeasonNumber  int       `json:"seasonNumber"`
-		Title         string    `json:"title"`
-		AirDate       string    `json:"airDate"`
-		AirDateUtc    time.Time `json:"airDateUtc"`
-	} `json:"episodes"`
-	Release struct {
+	Series   Series   `json:"series"`
+	Episodes Episodes `json:"episodes"`
+	Release  struct {
 		Quality        string `json:"quality"`
 		QualityVersion int    `json:"qualityVersion"`
 		ReleaseGroup   string `json:"releaseGroup"`
@@ -69,371 +176,176 @@ type GrabEvent struct {
 	DownloadClient     string `json:"downloadClient"`
 	DownloadClientType string `json:"downloadClientType"`
 	DownloadID         string `json:"downloadId"`
-	EventType          string `json:"eventType"`
+	// Enrichment set when SonarrTriggers.Enricher is set, see Enricher.
+	Enrichment     *Enrichment                `json:"-"`
+	Source         Source                     `json:"-"`
+	Raw            json.RawMessage            `json:"-"`
+	Extra          map[string]json.RawMessage `json:"-"`
+	InstanceName   string                     `json:"instanceName,omitempty"`
+	ApplicationURL string                     `json:"applicationUrl,omitempty"`
+	EventType      string                     `json:"eventType"`
 }
 
-func (e GrabEvent) eventName() string {
-	return grab
+func (e GrabEvent) eventName() EventType {
+	return Grab
 }
 
 // DownloadEvent webhook download payload
 // see: https://github.com/Sonarr/Sonarr/blob/v3.0.9.1549/src/NzbDrone.Core/Notifications/Webhook/Webhook.cs#L42
 type DownloadEvent struct {
-	Series struct {
-		ID       int    `json:"id"`
-		Title    string `json:"title"`
-		Path     string `json:"path"`
-		TvdbID   int    `json:"tvdbId"`
-		TvMazeID int    `json:"tvMazeId"`
-		ImdbID   string `json:"imdbId"`
-		Type     string `json:"type"`
-	} `json:"series"`
-	Episodes []struct {
-		ID            int       `json:"id"`
-		EpisodeNumber int       `json:"episodeNumber"`
-		SeasonNumber  int       `json:"seasonNumber"`
-		Title         string    `json:"title"`
-		AirDate       string    `json:"airDate"`
-		AirDateUtc    time.Time `json:"airDateUtc"`
-	} `json:"episodes"`
-	EpisodeFile struct {
-		ID             int    `json:"id"`
-		RelativePath   string `json:"relativePath"`
-		Path           string `json:"path"`
-		Quality        string `json:"quality"`
-		QualityVersion int    `json:"qualityVersion"`
-		ReleaseGroup   string `json:"releaseGroup"`
-		Size           int    `json:"size"`
-	} `json:"episodeFile"`
-	IsUpgrade bool   `json:"isUpgrade"`
-	EventType string `json:"eventType"`
+	Series      Series      `json:"series"`
+	Episodes    Episodes    `json:"episodes"`
+	EpisodeFile EpisodeFile `json:"episodeFile"`
+	IsUpgrade   bool        `json:"isUpgrade"`
+	// DeletedFiles files replaced by the upgrade, sent by Sonarr v4 only.
+	DeletedFiles       []EpisodeFile `json:"deletedFiles"`
+	DownloadClient     string        `json:"downloadClient"`
+	DownloadClientType string        `json:"downloadClientType"`
+	DownloadID         string        `json:"downloadId"`
+	// Enrichment set when SonarrTriggers.Enricher is set, see Enricher.
+	Enrichment     *Enrichment                `json:"-"`
+	Source         Source                     `json:"-"`
+	Raw            json.RawMessage            `json:"-"`
+	Extra          map[string]json.RawMessage `json:"-"`
+	InstanceName   string                     `json:"instanceName,omitempty"`
+	ApplicationURL string                     `json:"applicationUrl,omitempty"`
+	EventType      string                     `json:"eventType"`
 }
 
-func (e DownloadEvent) eventName() string {
-	return download
+func (e DownloadEvent) eventName() EventType {
+	return Download
 }
 
 // RenameEvent webhook rename payload
 // see: https://github.com/Sonarr/Sonarr/blob/v3.0.9.1549/src/NzbDrone.Core/Notifications/Webhook/Webhook.cs#L71
 type RenameEvent struct {
-	Series struct {
-		ID       int    `json:"id"`
-		Title    string `json:"title"`
-		Path     string `json:"path"`
-		TvdbID   int    `json:"tvdbId"`
-		TvMazeID int    `json:"tvMazeId"`
-		ImdbID   string `json:"imdbId"`
-		Type     string `json:"type"`
-	} `json:"series"`
-	RenamedEpisodeFiles []struct {
-		PreviousRelativePath string `json:"previousRelativePath"`
-		PreviousPath         string `json:"previousPath"`
-		ID                   int    `json:"id"`
-		RelativePath         string `json:"relativePath"`
-		Quality              string `json:"quality"`
-		QualityVersion       int    `json:"qualityVersion"`
-		ReleaseGroup         string `json:"releaseGroup"`
-		SceneName            string `json:"sceneName"`
-		Size                 int    `json:"size"`
-	} `json:"renamedEpisodeFiles"`
-	EventType string `json:"eventType"`
+	Series              Series               `json:"series"`
+	RenamedEpisodeFiles []RenamedEpisodeFile `json:"renamedEpisodeFiles"`
+	// Enrichment set when SonarrTriggers.Enricher is set, see Enricher.
+	Enrichment     *Enrichment                `json:"-"`
+	Source         Source                     `json:"-"`
+	Raw            json.RawMessage            `json:"-"`
+	Extra          map[string]json.RawMessage `json:"-"`
+	InstanceName   string                     `json:"instanceName,omitempty"`
+	ApplicationURL string                     `json:"applicationUrl,omitempty"`
+	EventType      string                     `json:"eventType"`
+}
+
+func (e RenameEvent) eventName() EventType {
+	return Rename
 }
 
-func (e RenameEvent) eventName() string {
-	return rename
+// RenamedEpisodeFile episode file renamed by Sonarr, see RenameEvent.
+type RenamedEpisodeFile struct {
+	PreviousRelativePath string `json:"previousRelativePath"`
+	PreviousPath         string `json:"previousPath"`
+	ID                   int    `json:"id"`
+	RelativePath         string `json:"relativePath"`
+	Quality              string `json:"quality"`
+	QualityVersion       int    `json:"qualityVersion"`
+	ReleaseGroup         string `json:"releaseGroup"`
+	SceneName            string `json:"sceneName"`
+	Size                 int    `json:"size"`
 }
 
-// EpisodeFileDeleteEvent webhook episode file delete payload
+// EpisodeFileDeleteEvent webhook episode file delete payload, Sonarr v3 sends the full
+// episode file which is normalized into EpisodeFile, the original payload is kept in
+// Source.Payload as *V3EpisodeFileDeleteEvent.
 // see: https://github.com/Sonarr/Sonarr/blob/v3.0.9.1549/src/NzbDrone.Core/Notifications/Webhook/Webhook.cs#L83
 type EpisodeFileDeleteEvent struct {
-	Series struct {
-		ID       int    `json:"id"`
-		Title    string `json:"title"`
-		Path     string `json:"path"`
-		TvdbID   int    `json:"tvdbId"`
-		TvMazeID int    `json:"tvMazeId"`
-		ImdbID   string `json:"imdbId"`
-		Type     string `json:"type"`
-	} `json:"series"`
-	Episodes []struct {
-		ID            int       `json:"id"`
-		EpisodeNumber int       `json:"episodeNumber"`
-		SeasonNumber  int       `json:"seasonNumber"`
-		Title         string    `json:"title"`
-		AirDate       string    `json:"airDate"`
-		AirDateUtc    time.Time `json:"airDateUtc"`
-	} `json:"episodes"`
-	EpisodeFile struct {
-		SeriesID     int       `json:"seriesId"`
-		SeasonNumber int       `json:"seasonNumber"`
-		RelativePath string    `json:"relativePath"`
-		Path         string    `json:"path"`
-		Size         int       `json:"size"`
-		DateAdded    time.Time `json:"dateAdded"`
-		ReleaseGroup string    `json:"releaseGroup"`
-		Quality      struct {
-			Quality struct {
-				ID         int    `json:"id"`
-				Name       string `json:"name"`
-				Source     string `json:"source"`
-				Resolution int    `json:"resolution"`
-			} `json:"quality"`
-			Revision struct {
-				Version  int  `json:"version"`
-				Real     int  `json:"real"`
-				IsRepack bool `json:"isRepack"`
-			} `json:"revision"`
-		} `json:"quality"`
-		MediaInfo struct {
-			ContainerFormat                    string  `json:"containerFormat"`
-			VideoFormat                        string  `json:"videoFormat"`
-			VideoCodecID                       string  `json:"videoCodecID"`
-			VideoProfile                       string  `json:"videoProfile"`
-			VideoCodecLibrary                  string  `json:"videoCodecLibrary"`
-			VideoBitrate                       int     `json:"videoBitrate"`
-			VideoBitDepth                      int     `json:"videoBitDepth"`
-			VideoMultiViewCount                int     `json:"videoMultiViewCount"`
-			VideoColourPrimaries               string  `json:"videoColourPrimaries"`
-			VideoTransferCharacteristics       string  `json:"videoTransferCharacteristics"`
-			VideoHdrFormat                     string  `json:"videoHdrFormat"`
-			VideoHdrFormatCompatibility        string  `json:"videoHdrFormatCompatibility"`
-			Width                              int     `json:"width"`
-			Height                             int     `json:"height"`
-			AudioFormat                        string  `json:"audioFormat"`
-			AudioCodecID                       string  `json:"audioCodecID"`
-			AudioCodecLibrary                  string  `json:"audioCodecLibrary"`
-			AudioAdditionalFeatures            string  `json:"audioAdditionalFeatures"`
-			AudioBitrate                       int     `json:"audioBitrate"`
-			RunTime                            string  `json:"runTime"`
-			AudioStreamCount                   int     `json:"audioStreamCount"`
-			AudioChannelsContainer             int     `json:"audioChannelsContainer"`
-			AudioChannelsStream                int     `json:"audioChannelsStream"`
-			AudioChannelPositions              string  `json:"audioChannelPositions"`
-			AudioChannelPositionsTextContainer string  `json:"audioChannelPositionsTextContainer"`
-			AudioChannelPositionsTextStream    string  `json:"audioChannelPositionsTextStream"`
-			AudioProfile                       string  `json:"audioProfile"`
-			VideoFps                           float64 `json:"videoFps"`
-			AudioLanguages                     string  `json:"audioLanguages"`
-			Subtitles                          string  `json:"subtitles"`
-			ScanType                           string  `json:"scanType"`
-			SchemaRevision                     int     `json:"schemaRevision"`
-		} `json:"mediaInfo"`
-		Episodes struct {
-			Value []struct {
-				SeriesID                   int       `json:"seriesId"`
-				TvdbID                     int       `json:"tvdbId"`
-				EpisodeFileID              int       `json:"episodeFileId"`
-				SeasonNumber               int       `json:"seasonNumber"`
-				EpisodeNumber              int       `json:"episodeNumber"`
-				Title                      string    `json:"title"`
-				AirDate                    string    `json:"airDate"`
-				AirDateUtc                 time.Time `json:"airDateUtc"`
-				Overview                   string    `json:"overview"`
-				Monitored                  bool      `json:"monitored"`
-				AbsoluteEpisodeNumber      int       `json:"absoluteEpisodeNumber"`
-				SceneAbsoluteEpisodeNumber int       `json:"sceneAbsoluteEpisodeNumber"`
-				SceneSeasonNumber          int       `json:"sceneSeasonNumber"`
-				SceneEpisodeNumber         int       `json:"sceneEpisodeNumber"`
-				UnverifiedSceneNumbering   bool      `json:"unverifiedSceneNumbering"`
-				Ratings                    struct {
-					Votes int     `json:"votes"`
-					Value float64 `json:"value"`
-				} `json:"ratings"`
-				Images []struct {
-					CoverType string `json:"coverType"`
-					URL       string `json:"url"`
-				} `json:"images"`
-				EpisodeFile struct {
-					IsLoaded bool `json:"isLoaded"`
-				} `json:"episodeFile"`
-				HasFile bool `json:"hasFile"`
-				ID      int  `json:"id"`
-			} `json:"value"`
-			IsLoaded bool `json:"isLoaded"`
-		} `json:"episodes"`
-		Series struct {
-			Value struct {
-				TvdbID            int       `json:"tvdbId"`
-				TvRageID          int       `json:"tvRageId"`
-				TvMazeID          int       `json:"tvMazeId"`
-				ImdbID            string    `json:"imdbId"`
-				Title             string    `json:"title"`
-				CleanTitle        string    `json:"cleanTitle"`
-				SortTitle         string    `json:"sortTitle"`
-				Status            string    `json:"status"`
-				Overview          string    `json:"overview"`
-				AirTime           string    `json:"airTime"`
-				Monitored         bool      `json:"monitored"`
-				QualityProfileID  int       `json:"qualityProfileId"`
-				LanguageProfileID int       `json:"languageProfileId"`
-				SeasonFolder      bool      `json:"seasonFolder"`
-				LastInfoSync      time.Time `json:"lastInfoSync"`
-				Runtime           int       `json:"runtime"`
-				Images            []struct {
-					CoverType string `json:"coverType"`
-					URL       string `json:"url"`
-				} `json:"images"`
-				SeriesType        string `json:"seriesType"`
-				Network           string `json:"network"`
-				UseSceneNumbering bool   `json:"useSceneNumbering"`
-				TitleSlug         string `json:"titleSlug"`
-				Path              string `json:"path"`
-				Year              int    `json:"year"`
-				Ratings           struct {
-					Votes int     `json:"votes"`
-					Value float64 `json:"value"`
-				} `json:"ratings"`
-				Genres []string `json:"genres"`
-				Actors []struct {
-					Name      string        `json:"name"`
-					Character string        `json:"character"`
-					Images    []interface{} `json:"images"`
-				} `json:"actors"`
-				Certification  string    `json:"certification"`
-				Added          time.Time `json:"added"`
-				FirstAired     time.Time `json:"firstAired"`
-				QualityProfile struct {
-					Value struct {
-						Name           string `json:"name"`
-						UpgradeAllowed bool   `json:"upgradeAllowed"`
-						Cutoff         int    `json:"cutoff"`
-						Items          []struct {
-							Quality struct {
-								ID         int    `json:"id"`
-								Name       string `json:"name"`
-								Source     string `json:"source"`
-								Resolution int    `json:"resolution"`
-							} `json:"quality,omitempty"`
-							Items   []interface{} `json:"items"`
-							Allowed bool          `json:"allowed"`
-							ID      int           `json:"id,omitempty"`
-							Name    string        `json:"name,omitempty"`
-						} `json:"items"`
-						ID int `json:"id"`
-					} `json:"value"`
-					IsLoaded bool `json:"isLoaded"`
-				} `json:"qualityProfile"`
-				LanguageProfile struct {
-					Value struct {
-						Name      string `json:"name"`
-						Languages []struct {
-							Language struct {
-								ID   int    `json:"id"`
-								Name string `json:"name"`
-							} `json:"language"`
-							Allowed bool `json:"allowed"`
-						} `json:"languages"`
-						UpgradeAllowed bool `json:"upgradeAllowed"`
-						Cutoff         struct {
-							ID   int    `json:"id"`
-							Name string `json:"name"`
-						} `json:"cutoff"`
-						ID int `json:"id"`
-					} `json:"value"`
-					IsLoaded bool `json:"isLoaded"`
-				} `json:"languageProfile"`
-				Seasons []struct {
-					SeasonNumber int  `json:"seasonNumber"`
-					Monitored    bool `json:"monitored"`
-					Images       []struct {
-						CoverType string `json:"coverType"`
-						URL       string `json:"url"`
-					} `json:"images"`
-				} `json:"seasons"`
-				Tags []int `json:"tags"`
-				ID   int   `json:"id"`
-			} `json:"value"`
-			IsLoaded bool `json:"isLoaded"`
-		} `json:"series"`
-		Language struct {
-			ID   int    `json:"id"`
-			Name string `json:"name"`
-		} `json:"language"`
-		ID int `json:"id"`
-	} `json:"episodeFile"`
-	DeleteReason string `json:"deleteReason"`
-	EventType    string `json:"eventType"`
+	Series       Series      `json:"series"`
+	Episodes     Episodes    `json:"episodes"`
+	EpisodeFile  EpisodeFile `json:"episodeFile"`
+	DeleteReason string      `json:"deleteReason"`
+	// Enrichment set when SonarrTriggers.Enricher is set, see Enricher.
+	Enrichment     *Enrichment                `json:"-"`
+	Source         Source                     `json:"-"`
+	Raw            json.RawMessage            `json:"-"`
+	Extra          map[string]json.RawMessage `json:"-"`
+	InstanceName   string                     `json:"instanceName,omitempty"`
+	ApplicationURL string                     `json:"applicationUrl,omitempty"`
+	EventType      string                     `json:"eventType"`
 }
 
-func (e EpisodeFileDeleteEvent) eventName() string {
-	return episodeFileDelete
+func (e EpisodeFileDeleteEvent) eventName() EventType {
+	return EpisodeFileDelete
 }
 
 // SeriesDeleteEvent webhook series delete payload
 // see: https://github.com/Sonarr/Sonarr/blob/v3.0.9.1549/src/NzbDrone.Core/Notifications/Webhook/Webhook.cs#L97
 type SeriesDeleteEvent struct {
-	Series struct {
-		ID       int    `json:"id"`
-		Title    string `json:"title"`
-		Path     string `json:"path"`
-		TvdbID   int    `json:"tvdbId"`
-		TvMazeID int    `json:"tvMazeId"`
-		ImdbID   string `json:"imdbId"`
-		Type     string `json:"type"`
-	} `json:"series"`
-	DeletedFiles bool   `json:"deletedFiles"`
-	EventType    string `json:"eventType"`
+	Series         Series                     `json:"series"`
+	DeletedFiles   bool                       `json:"deletedFiles"`
+	Source         Source                     `json:"-"`
+	Raw            json.RawMessage            `json:"-"`
+	Extra          map[string]json.RawMessage `json:"-"`
+	InstanceName   string                     `json:"instanceName,omitempty"`
+	ApplicationURL string                     `json:"applicationUrl,omitempty"`
+	EventType      string                     `json:"eventType"`
 }
 
-func (e SeriesDeleteEvent) eventName() string {
-	return seriesDelete
+func (e SeriesDeleteEvent) eventName() EventType {
+	return SeriesDelete
 }
 
 // Health webhook health payload
 // see: https://github.com/Sonarr/Sonarr/blob/v3.0.9.1549/src/NzbDrone.Core/Notifications/Webhook/Webhook.cs#L109
 type HealthEvent struct {
-	Level     string `json:"level"`
-	Message   string `json:"message"`
-	Type      string `json:"type"`
-	WikiURL   string `json:"wikiUrl"`
-	EventType string `json:"eventType"`
+	Level          string                     `json:"level"`
+	Message        string                     `json:"message"`
+	Type           string                     `json:"type"`
+	WikiURL        string                     `json:"wikiUrl"`
+	Source         Source                     `json:"-"`
+	Raw            json.RawMessage            `json:"-"`
+	Extra          map[string]json.RawMessage `json:"-"`
+	InstanceName   string                     `json:"instanceName,omitempty"`
+	ApplicationURL string                     `json:"applicationUrl,omitempty"`
+	EventType      string                     `json:"eventType"`
 }
 
-func (e HealthEvent) eventName() string {
-	return health
+func (e HealthEvent) eventName() EventType {
+	return Health
 }
 
 // ApplicationUpdateEvent webhook application update payload
 // see: https://github.com/Sonarr/Sonarr/blob/v3.0.9.1549/src/NzbDrone.Core/Notifications/Webhook/Webhook.cs#L123
 type ApplicationUpdateEvent struct {
-	Message         string `json:"message"`
-	PreviousVersion string `json:"previousVersion"`
-	NewVersion      string `json:"newVersion"`
-	EventType       string `json:"eventType"`
+	Message         string                     `json:"message"`
+	PreviousVersion string                     `json:"previousVersion"`
+	NewVersion      string                     `json:"newVersion"`
+	Source          Source                     `json:"-"`
+	Raw             json.RawMessage            `json:"-"`
+	Extra           map[string]json.RawMessage `json:"-"`
+	InstanceName    string                     `json:"instanceName,omitempty"`
+	ApplicationURL  string                     `json:"applicationUrl,omitempty"`
+	EventType       string                     `json:"eventType"`
 }
 
-func (e ApplicationUpdateEvent) eventName() string {
-	return applicationUpdate
+func (e ApplicationUpdateEvent) eventName() EventType {
+	return ApplicationUpdate
 }
 
 // TestEvent webhook test payload
 // see: https://github.com/Sonarr/Sonarr/blob/v3.0.9.1549/src/NzbDrone.Core/Notifications/Webhook/Webhook.cs#L153
 type TestEvent struct {
-	Series struct {
-		ID       int    `json:"id"`
-		Title    string `json:"title"`
-		Path     string `json:"path"`
-		TvdbID   int    `json:"tvdbId"`
-		TvMazeID int    `json:"tvMazeId"`
-		Type     string `json:"type"`
-	} `json:"series"`
-	Episodes []struct {
-		ID            int    `json:"id"`
-		EpisodeNumber int    `json:"episodeNumber"`
-		SeasonNumber  int    `json:"seasonNumber"`
-		Title         string `json:"title"`
-	} `json:"episodes"`
-	EventType string `json:"eventType"`
+	Series         Series                     `json:"series"`
+	Episodes       Episodes                   `json:"episodes"`
+	Source         Source                     `json:"-"`
+	Raw            json.RawMessage            `json:"-"`
+	Extra          map[string]json.RawMessage `json:"-"`
+	InstanceName   string                     `json:"instanceName,omitempty"`
+	ApplicationURL string                     `json:"applicationUrl,omitempty"`
+	EventType      string                     `json:"eventType"`
 }
 
-func (e TestEvent) eventName() string {
-	return test
+func (e TestEvent) eventName() EventType {
+	return Test
 }
 
 // UnknownEvent parse any unknown events, this could happened if Sonarr update or add
 // new webhook events or change them, like what happened when OnImport and OnDownload.
 type UnknownEvent map[string]interface{}
 
-func (e UnknownEvent) eventName() string {
-	return "Unknown"
+func (e UnknownEvent) eventName() EventType {
+	return Unknown
 }
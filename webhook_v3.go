@@ -0,0 +1,345 @@
+package eventt
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// V3EpisodeFileDeleteEvent webhook episode file delete payload as sent by Sonarr v3,
+// it is available in EpisodeFileDeleteEvent.Source.Payload.
+// see: https://github.com/Sonarr/Sonarr/blob/v3.0.9.1549/src/NzbDrone.Core/Notifications/Webhook/Webhook.cs#L83
+type V3EpisodeFileDeleteEvent struct {
+	Series       Series        `json:"series"`
+	Episodes     []Episode     `json:"episodes"`
+	EpisodeFile  V3EpisodeFile `json:"episodeFile"`
+	DeleteReason string        `json:"deleteReason"`
+	EventType    string        `json:"eventType"`
+}
+
+// V3EpisodeFile full episode file model sent by Sonarr v3 in EpisodeFileDelete events.
+type V3EpisodeFile struct {
+	SeriesID     int       `json:"seriesId"`
+	SeasonNumber int       `json:"seasonNumber"`
+	RelativePath string    `json:"relativePath"`
+	Path         string    `json:"path"`
+	Size         int       `json:"size"`
+	DateAdded    time.Time `json:"dateAdded"`
+	ReleaseGroup string    `json:"releaseGroup"`
+	Quality      struct {
+		Quality struct {
+			ID         int    `json:"id"`
+			Name       string `json:"name"`
+			Source     string `json:"source"`
+			Resolution int    `json:"resolution"`
+		} `json:"quality"`
+		Revision struct {
+			Version  int  `json:"version"`
+			Real     int  `json:"real"`
+			IsRepack bool `json:"isRepack"`
+		} `json:"revision"`
+	} `json:"quality"`
+	MediaInfo struct {
+		ContainerFormat                    string  `json:"containerFormat"`
+		VideoFormat                        string  `json:"videoFormat"`
+		VideoCodecID                       string  `json:"videoCodecID"`
+		VideoProfile                       string  `json:"videoProfile"`
+		VideoCodecLibrary                  string  `json:"videoCodecLibrary"`
+		VideoBitrate                       int     `json:"videoBitrate"`
+		VideoBitDepth                      int     `json:"videoBitDepth"`
+		VideoMultiViewCount                int     `json:"videoMultiViewCount"`
+		VideoColourPrimaries               string  `json:"videoColourPrimaries"`
+		VideoTransferCharacteristics       string  `json:"videoTransferCharacteristics"`
+		VideoHdrFormat                     string  `json:"videoHdrFormat"`
+		VideoHdrFormatCompatibility        string  `json:"videoHdrFormatCompatibility"`
+		Width                              int     `json:"width"`
+		Height                             int     `json:"height"`
+		AudioFormat                        string  `json:"audioFormat"`
+		AudioCodecID                       string  `json:"audioCodecID"`
+		AudioCodecLibrary                  string  `json:"audioCodecLibrary"`
+		AudioAdditionalFeatures            string  `json:"audioAdditionalFeatures"`
+		AudioBitrate                       int     `json:"audioBitrate"`
+		RunTime                            string  `json:"runTime"`
+		AudioStreamCount                   int     `json:"audioStreamCount"`
+		AudioChannelsContainer             int     `json:"audioChannelsContainer"`
+		AudioChannelsStream                int     `json:"audioChannelsStream"`
+		AudioChannelPositions              string  `json:"audioChannelPositions"`
+		AudioChannelPositionsTextContainer string  `json:"audioChannelPositionsTextContainer"`
+		AudioChannelPositionsTextStream    string  `json:"audioChannelPositionsTextStream"`
+		AudioProfile                       string  `json:"audioProfile"`
+		VideoFps                           float64 `json:"videoFps"`
+		AudioLanguages                     string  `json:"audioLanguages"`
+		Subtitles                          string  `json:"subtitles"`
+		ScanType                           string  `json:"scanType"`
+		SchemaRevision                     int     `json:"schemaRevision"`
+	} `json:"mediaInfo"`
+	Episodes struct {
+		Value []struct {
+			SeriesID                   int       `json:"seriesId"`
+			TvdbID                     int       `json:"tvdbId"`
+			EpisodeFileID              int       `json:"episodeFileId"`
+			SeasonNumber               int       `json:"seasonNumber"`
+			EpisodeNumber              int       `json:"episodeNumber"`
+			Title                      string    `json:"title"`
+			AirDate                    string    `json:"airDate"`
+			AirDateUtc                 time.Time `json:"airDateUtc"`
+			Overview                   string    `json:"overview"`
+			Monitored                  bool      `json:"monitored"`
+			AbsoluteEpisodeNumber      int       `json:"absoluteEpisodeNumber"`
+			SceneAbsoluteEpisodeNumber int       `json:"sceneAbsoluteEpisodeNumber"`
+			SceneSeasonNumber          int       `json:"sceneSeasonNumber"`
+			SceneEpisodeNumber         int       `json:"sceneEpisodeNumber"`
+			UnverifiedSceneNumbering   bool      `json:"unverifiedSceneNumbering"`
+			Ratings                    struct {
+				Votes int     `json:"votes"`
+				Value float64 `json:"value"`
+			} `json:"ratings"`
+			Images []struct {
+				CoverType string `json:"coverType"`
+				URL       string `json:"url"`
+			} `json:"images"`
+			EpisodeFile struct {
+				IsLoaded bool `json:"isLoaded"`
+			} `json:"episodeFile"`
+			HasFile bool `json:"hasFile"`
+			ID      int  `json:"id"`
+		} `json:"value"`
+		IsLoaded bool `json:"isLoaded"`
+	} `json:"episodes"`
+	Series struct {
+		Value struct {
+			TvdbID            int       `json:"tvdbId"`
+			TvRageID          int       `json:"tvRageId"`
+			TvMazeID          int       `json:"tvMazeId"`
+			ImdbID            string    `json:"imdbId"`
+			Title             string    `json:"title"`
+			CleanTitle        string    `json:"cleanTitle"`
+			SortTitle         string    `json:"sortTitle"`
+			Status            string    `json:"status"`
+			Overview          string    `json:"overview"`
+			AirTime           string    `json:"airTime"`
+			Monitored         bool      `json:"monitored"`
+			QualityProfileID  int       `json:"qualityProfileId"`
+			LanguageProfileID int       `json:"languageProfileId"`
+			SeasonFolder      bool      `json:"seasonFolder"`
+			LastInfoSync      time.Time `json:"lastInfoSync"`
+			Runtime           int       `json:"runtime"`
+			Images            []struct {
+				CoverType string `json:"coverType"`
+				URL       string `json:"url"`
+			} `json:"images"`
+			SeriesType        string `json:"seriesType"`
+			Network           string `json:"network"`
+			UseSceneNumbering bool   `json:"useSceneNumbering"`
+			TitleSlug         string `json:"titleSlug"`
+			Path              string `json:"path"`
+			Year              int    `json:"year"`
+			Ratings           struct {
+				Votes int     `json:"votes"`
+				Value float64 `json:"value"`
+			} `json:"ratings"`
+			Genres []string `json:"genres"`
+			Actors []struct {
+				Name      string        `json:"name"`
+				Character string        `json:"character"`
+				Images    []interface{} `json:"images"`
+			} `json:"actors"`
+			Certification  string    `json:"certification"`
+			Added          time.Time `json:"added"`
+			FirstAired     time.Time `json:"firstAired"`
+			QualityProfile struct {
+				Value struct {
+					Name           string `json:"name"`
+					UpgradeAllowed bool   `json:"upgradeAllowed"`
+					Cutoff         int    `json:"cutoff"`
+					Items          []struct {
+						Quality struct {
+							ID         int    `json:"id"`
+							Name       string `json:"name"`
+							Source     string `json:"source"`
+							Resolution int    `json:"resolution"`
+						} `json:"quality,omitempty"`
+						Items   []interface{} `json:"items"`
+						Allowed bool          `json:"allowed"`
+						ID      int           `json:"id,omitempty"`
+						Name    string        `json:"name,omitempty"`
+					} `json:"items"`
+					ID int `json:"id"`
+				} `json:"value"`
+				IsLoaded bool `json:"isLoaded"`
+			} `json:"qualityProfile"`
+			LanguageProfile struct {
+				Value struct {
+					Name      string `json:"name"`
+					Languages []struct {
+						Language struct {
+							ID   int    `json:"id"`
+							Name string `json:"name"`
+						} `json:"language"`
+						Allowed bool `json:"allowed"`
+					} `json:"languages"`
+					UpgradeAllowed bool `json:"upgradeAllowed"`
+					Cutoff         struct {
+						ID   int    `json:"id"`
+						Name string `json:"name"`
+					} `json:"cutoff"`
+					ID int `json:"id"`
+				} `json:"value"`
+				IsLoaded bool `json:"isLoaded"`
+			} `json:"languageProfile"`
+			Seasons []struct {
+				SeasonNumber int  `json:"seasonNumber"`
+				Monitored    bool `json:"monitored"`
+				Images       []struct {
+					CoverType string `json:"coverType"`
+					URL       string `json:"url"`
+				} `json:"images"`
+			} `json:"seasons"`
+			Tags []int `json:"tags"`
+			ID   int   `json:"id"`
+		} `json:"value"`
+		IsLoaded bool `json:"isLoaded"`
+	} `json:"series"`
+	Language struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	} `json:"language"`
+	ID int `json:"id"`
+}
+
+// Normalize convert f into the version independent EpisodeFile.
+func (f V3EpisodeFile) Normalize() EpisodeFile {
+	mi := f.MediaInfo
+	ef := EpisodeFile{
+		ID:             f.ID,
+		RelativePath:   f.RelativePath,
+		Path:           f.Path,
+		Quality:        f.Quality.Quality.Name,
+		QualityVersion: f.Quality.Revision.Version,
+		ReleaseGroup:   f.ReleaseGroup,
+		Size:           f.Size,
+		DateAdded:      f.DateAdded,
+		MediaInfo: MediaInfo{
+			AudioChannels:         v3AudioChannels(mi.AudioChannelPositions, mi.AudioChannelsContainer),
+			AudioCodec:            mi.AudioFormat,
+			AudioLanguages:        splitLanguages(mi.AudioLanguages),
+			Height:                mi.Height,
+			Width:                 mi.Width,
+			Subtitles:             splitLanguages(mi.Subtitles),
+			VideoCodec:            mi.VideoFormat,
+			VideoDynamicRangeType: v3DynamicRangeType(mi.VideoHdrFormat, mi.VideoHdrFormatCompatibility, mi.VideoTransferCharacteristics),
+			RunTime:               mi.RunTime,
+			VideoBitrate:          mi.VideoBitrate,
+			AudioBitrate:          mi.AudioBitrate,
+		},
+	}
+	if ef.MediaInfo.VideoDynamicRangeType != "" {
+		ef.MediaInfo.VideoDynamicRange = "HDR"
+	}
+	return ef
+}
+
+// v3AudioChannels number of channels as formatted by Sonarr v4, e.g. 5.1, from the
+// v3 channel positions, e.g. "3/2/0.1", or the number of channels in the container.
+func v3AudioChannels(positions string, container int) float64 {
+	var sum float64
+	if pos, _, _ := strings.Cut(positions, ","); pos != "" {
+		for _, p := range strings.Split(pos, "/") {
+			n, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+			if err != nil {
+				sum = 0
+				break
+			}
+			sum += n
+		}
+	}
+	switch {
+	case sum > 0:
+		return math.Round(sum*10) / 10
+	case container >= 6:
+		// the LFE channel is counted as .1, e.g. 6 channels are 5.1.
+		return float64(container-1) + 0.1
+	}
+	return float64(container)
+}
+
+// v3DynamicRangeType the video dynamic range type as sent by Sonarr v4, e.g. "DV HDR10",
+// from the v3 HDR format, its compatibility and the transfer characteristics.
+func v3DynamicRangeType(format, compatibility, transfer string) string {
+	var types []string
+	if strings.Contains(format, "Dolby Vision") {
+		types = append(types, "DV")
+		format = compatibility
+	}
+	switch {
+	case strings.Contains(format, "HDR10+"), strings.Contains(format, "SMPTE ST 2094"):
+		types = append(types, "HDR10Plus")
+	case strings.Contains(format, "HDR10"), strings.Contains(format, "SMPTE ST 2086"):
+		types = append(types, "HDR10")
+	case transfer == "HLG", transfer == "arib-std-b67":
+		types = append(types, "HLG")
+	case len(types) == 0 && (transfer == "PQ" || transfer == "smpte2084"):
+		types = append(types, "PQ")
+	}
+	return strings.Join(types, " ")
+}
+
+// splitLanguages split Sonarr v3 media info languages, e.g. "English/Japanese".
+func splitLanguages(s string) []string {
+	var langs []string
+	for _, l := range strings.Split(s, "/") {
+		if l = strings.TrimSpace(l); l != "" {
+			langs = append(langs, l)
+		}
+	}
+	return langs
+}
+
+// UnmarshalJSON decode both the Sonarr v4 episode file and the full episode file
+// sent by Sonarr v3 in EpisodeFileDelete events, see V3EpisodeFile.
+func (f *EpisodeFile) UnmarshalJSON(b []byte) error {
+	var probe struct {
+		Quality json.RawMessage `json:"quality"`
+	}
+	if err := json.Unmarshal(b, &probe); err != nil {
+		return err
+	}
+	if len(probe.Quality) > 0 && probe.Quality[0] == '{' {
+		var v3 V3EpisodeFile
+		if err := json.Unmarshal(b, &v3); err != nil {
+			return err
+		}
+		*f = v3.Normalize()
+		return nil
+	}
+
+	// episodeFile has the same fields without the UnmarshalJSON method.
+	type episodeFile EpisodeFile
+	return json.Unmarshal(b, (*episodeFile)(f))
+}
+
+// isV3EpisodeFile report whether the payload episodeFile is the Sonarr v3 full episode file.
+func isV3EpisodeFile(payload []byte) bool {
+	var probe struct {
+		EpisodeFile struct {
+			Quality json.RawMessage `json:"quality"`
+		} `json:"episodeFile"`
+	}
+	if err := json.Unmarshal(payload, &probe); err != nil {
+		return false
+	}
+	q := probe.EpisodeFile.Quality
+	return len(q) > 0 && q[0] == '{'
+}
+
+// jsonSchema accept the quality and media info of both Sonarr v3 and v4 episode files.
+func (EpisodeFile) jsonSchema() *jsonSchema {
+	type episodeFile EpisodeFile
+	s := schemaOf(reflect.TypeOf(episodeFile{}))
+	s.Properties["quality"] = &jsonSchema{Type: []string{"string", "object", "null"}}
+	s.Properties["mediaInfo"] = &jsonSchema{Type: []string{"object", "null"}}
+	return s
+}